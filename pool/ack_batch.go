@@ -0,0 +1,134 @@
+package pool
+
+import (
+	"errors"
+	"fmt"
+)
+
+// AckBatch acknowledges a batch of deliveries that may have been received by different sessions.
+// The deliveries are grouped by their session and every session acknowledges its part of the batch
+// with a single multiple-ack of its highest delivery tag, which also acknowledges all other outstanding
+// deliveries of that session with a lower delivery tag.
+// No acknowledgement is sent in case any delivery was not received by a session.
+func AckBatch(batch []Delivery) error {
+	groups, err := groupBySession(batch)
+	if err != nil {
+		return fmt.Errorf("failed to ack batch: %w", err)
+	}
+
+	var errs error
+	for _, g := range groups {
+		errs = errors.Join(errs, g.session.AckBatch(g.deliveries))
+	}
+	return errs
+}
+
+// NackBatch negatively acknowledges a batch of deliveries that may have been received by different sessions.
+// See AckBatch for how the deliveries are grouped.
+func NackBatch(batch []Delivery, requeue bool) error {
+	groups, err := groupBySession(batch)
+	if err != nil {
+		return fmt.Errorf("failed to nack batch: %w", err)
+	}
+
+	var errs error
+	for _, g := range groups {
+		errs = errors.Join(errs, g.session.NackBatch(g.deliveries, requeue))
+	}
+	return errs
+}
+
+// AckBatch acknowledges all deliveries of the batch with a single multiple-ack of the highest delivery tag.
+// All deliveries must have been received by this session, otherwise ErrForeignDelivery is returned
+// and no acknowledgement is sent, as delivery tags are scoped to a channel.
+func (s *Session) AckBatch(batch []Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.borrowed()
+	if err != nil {
+		return fmt.Errorf("failed to ack batch: %w", err)
+	}
+
+	tag, err := s.lastChannelDeliveryTag(batch)
+	if err != nil {
+		return fmt.Errorf("failed to ack batch: %w", err)
+	}
+	return s.channel.Ack(tag, true)
+}
+
+// NackBatch negatively acknowledges all deliveries of the batch with a single multiple-nack of the highest delivery tag.
+// All deliveries must have been received by this session, otherwise ErrForeignDelivery is returned
+// and no negative acknowledgement is sent, as delivery tags are scoped to a channel.
+func (s *Session) NackBatch(batch []Delivery, requeue bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.borrowed()
+	if err != nil {
+		return fmt.Errorf("failed to nack batch: %w", err)
+	}
+
+	tag, err := s.lastChannelDeliveryTag(batch)
+	if err != nil {
+		return fmt.Errorf("failed to nack batch: %w", err)
+	}
+	return s.channel.Nack(tag, true, requeue)
+}
+
+// lastChannelDeliveryTag returns the highest channel delivery tag of the batch.
+// not threadsafe
+func (s *Session) lastChannelDeliveryTag(batch []Delivery) (uint64, error) {
+	if len(batch) == 0 {
+		return 0, errors.New("empty batch")
+	}
+
+	var last uint64
+	for _, d := range batch {
+		if deliverySession(d) != s {
+			return 0, fmt.Errorf("%w: delivery tag %d of consumer %q", ErrForeignDelivery, d.DeliveryTag, d.ConsumerTag)
+		}
+		if d.DeliveryTag > last {
+			last = d.DeliveryTag
+		}
+	}
+	return s.channelDeliveryTag(last)
+}
+
+// deliverySession returns the session that received the delivery or nil
+// in case the delivery was not received via a session.
+func deliverySession(d Delivery) *Session {
+	a, ok := d.Acknowledger.(sessionAcknowledger)
+	if !ok {
+		return nil
+	}
+	return a.s
+}
+
+type sessionBatch struct {
+	session    *Session
+	deliveries []Delivery
+}
+
+// groupBySession splits the batch into one batch per session, preserving the order of the deliveries.
+func groupBySession(batch []Delivery) ([]sessionBatch, error) {
+	var (
+		groups []sessionBatch
+		index  = make(map[*Session]int)
+	)
+	for _, d := range batch {
+		s := deliverySession(d)
+		if s == nil {
+			return nil, fmt.Errorf("%w: delivery tag %d of consumer %q was not received by a session", ErrForeignDelivery, d.DeliveryTag, d.ConsumerTag)
+		}
+
+		i, ok := index[s]
+		if !ok {
+			i = len(groups)
+			index[s] = i
+			groups = append(groups, sessionBatch{session: s})
+		}
+		groups[i].deliveries = append(groups[i].deliveries, d)
+	}
+	return groups, nil
+}
@@ -0,0 +1,50 @@
+package pool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupBySession(t *testing.T) {
+	t.Parallel()
+
+	var (
+		s1 = &Session{channels: 1}
+		s2 = &Session{channels: 2}
+	)
+
+	batch := []Delivery{
+		{Acknowledger: sessionAcknowledger{s1}, DeliveryTag: 1},
+		{Acknowledger: sessionAcknowledger{s2}, DeliveryTag: toSessionDeliveryTag(1, 1)},
+		{Acknowledger: sessionAcknowledger{s1}, DeliveryTag: 3},
+		{Acknowledger: sessionAcknowledger{s2}, DeliveryTag: toSessionDeliveryTag(1, 2)},
+		{Acknowledger: sessionAcknowledger{s1}, DeliveryTag: 2},
+	}
+
+	groups, err := groupBySession(batch)
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	assert.Equal(t, s1, groups[0].session)
+	assert.Equal(t, []Delivery{batch[0], batch[2], batch[4]}, groups[0].deliveries)
+	assert.Equal(t, s2, groups[1].session)
+	assert.Equal(t, []Delivery{batch[1], batch[3]}, groups[1].deliveries)
+
+	// the highest tag is (n)acked on the channel of each session
+	tag, err := s1.lastChannelDeliveryTag(groups[0].deliveries)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), tag)
+
+	tag, err = s2.lastChannelDeliveryTag(groups[1].deliveries)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), tag)
+
+	// delivery tags of other sessions are never (n)acked on the wrong channel
+	_, err = s1.lastChannelDeliveryTag(batch)
+	assert.ErrorIs(t, err, ErrForeignDelivery)
+
+	// deliveries that were not received by a session cannot be grouped
+	_, err = groupBySession(append(batch, Delivery{DeliveryTag: 1}))
+	assert.ErrorIs(t, err, ErrForeignDelivery)
+}
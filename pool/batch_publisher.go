@@ -0,0 +1,275 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jxsl13/amqpx/logging"
+)
+
+// BatchPublishing is a message that is buffered by the BatchPublisher until its batch is flushed.
+type BatchPublishing struct {
+	Exchange   string
+	RoutingKey string
+	Publishing
+}
+
+// BatchPublisher accumulates messages and publishes them in batches in order to amortize
+// the round trips that are needed to await publisher confirms.
+// A batch is flushed as soon as it reaches the maximum number of messages, the maximum number of bytes
+// or when the flush interval elapses.
+// Messages are published in the order in which they were added.
+// In case only a part of a batch could be published, the remaining messages are retried, which
+// may lead to duplicate messages (at least once delivery).
+type BatchPublisher struct {
+	sp *SessionPool
+
+	maxMessages   int
+	maxBytes      int
+	flushInterval time.Duration
+	flushTimeout  time.Duration
+
+	errorCB BatchPublisherErrorCallback
+
+	queue chan BatchPublishing
+
+	// mu protects closed and guarantees that no message is added after the last flush
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	log logging.Logger
+}
+
+// NewBatchPublisher creates a new publisher which publishes the added messages in batches
+// using sessions of the passed session pool.
+// The session pool should be created with confirms enabled, otherwise the messages of a batch are
+// published without awaiting any broker confirmation.
+func NewBatchPublisher(sp *SessionPool, options ...BatchPublisherOption) *BatchPublisher {
+	if sp == nil {
+		panic("nil session pool passed")
+	}
+
+	// sane defaults
+	option := batchPublisherOption{
+		Ctx: sp.ctx,
+
+		MaxMessages:   100,
+		MaxBytes:      1024 * 1024,
+		FlushInterval: 100 * time.Millisecond,
+		FlushTimeout:  30 * time.Second,
+
+		Logger: sp.log, // derive logger from session pool
+	}
+
+	for _, o := range options {
+		o(&option)
+	}
+
+	ctx, cc := context.WithCancelCause(option.Ctx)
+	cancel := toCancelFunc(fmt.Errorf("batch publisher %w", ErrClosed), cc)
+
+	bp := &BatchPublisher{
+		sp: sp,
+
+		maxMessages:   option.MaxMessages,
+		maxBytes:      option.MaxBytes,
+		flushInterval: option.FlushInterval,
+		flushTimeout:  option.FlushTimeout,
+
+		errorCB: option.ErrorCallback,
+
+		queue: make(chan BatchPublishing, option.MaxMessages),
+
+		ctx:    ctx,
+		cancel: cancel,
+
+		log: option.Logger,
+	}
+
+	bp.wg.Add(1)
+	go bp.run()
+
+	bp.info("batch publisher initialized")
+	return bp
+}
+
+// Add adds a message to the current batch.
+// Add blocks in case the publisher cannot keep up with flushing batches.
+// You may set exchange to "" and routingKey to your queue name in order to publish directly to a queue.
+// Errors that occur while flushing are passed to the error callback.
+func (bp *BatchPublisher) Add(ctx context.Context, exchange string, routingKey string, msg Publishing) error {
+	bp.mu.RLock()
+	defer bp.mu.RUnlock()
+
+	if bp.closed {
+		return fmt.Errorf("failed to add message: batch publisher %w", ErrClosed)
+	}
+
+	select {
+	case bp.queue <- BatchPublishing{Exchange: exchange, RoutingKey: routingKey, Publishing: msg}:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to add message: %w", ctx.Err())
+	case <-bp.ctx.Done():
+		return fmt.Errorf("failed to add message: %w", context.Cause(bp.ctx))
+	}
+}
+
+// Close flushes all buffered messages and stops the publisher.
+// Close blocks until the last batch has been flushed or the flush timeout is exceeded.
+func (bp *BatchPublisher) Close() {
+	bp.debug("closing batch publisher...")
+	defer bp.info("closed")
+
+	// wait for concurrent Add calls to finish
+	bp.mu.Lock()
+	bp.closed = true
+	bp.mu.Unlock()
+
+	bp.cancel()
+	bp.wg.Wait()
+}
+
+func (bp *BatchPublisher) run() {
+	defer bp.wg.Done()
+
+	var (
+		batch = make([]BatchPublishing, 0, bp.maxMessages)
+		size  = 0
+	)
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		bp.flush(batch)
+		batch = make([]BatchPublishing, 0, bp.maxMessages)
+		size = 0
+	}
+
+	ticker := time.NewTicker(bp.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case msg := <-bp.queue:
+			batch = append(batch, msg)
+			size += len(msg.Body)
+			if len(batch) >= bp.maxMessages || size >= bp.maxBytes {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-bp.ctx.Done():
+			// no new messages can be added after closing
+			for {
+				select {
+				case msg := <-bp.queue:
+					batch = append(batch, msg)
+					size += len(msg.Body)
+					if len(batch) >= bp.maxMessages || size >= bp.maxBytes {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush publishes the batch and retries the remaining messages in case of recoverable errors.
+func (bp *BatchPublisher) flush(batch []BatchPublishing) {
+	// the publisher context might already be closed, we still want to publish the last batch.
+	ctx, cancel := context.WithTimeout(context.Background(), bp.flushTimeout)
+	defer cancel()
+
+	for {
+		n, err := bp.publishBatch(ctx, batch)
+		batch = batch[n:]
+		if err == nil {
+			bp.debug(fmt.Sprintf("flushed batch of %d messages", n))
+			return
+		}
+
+		switch {
+		case errors.Is(err, ErrNack), errors.Is(err, ErrReturned), !recoverable(err):
+			bp.failed(batch, err)
+			return
+		case ctx.Err() != nil:
+			bp.failed(batch, fmt.Errorf("%w: %v", ctx.Err(), err))
+			return
+		default:
+			// confirms of messages that were published before a channel recovery are lost,
+			// which leads to delivery tag mismatches, so we publish those messages again.
+			bp.warn(err, fmt.Sprintf("flushing batch failed due to recoverable error, retrying %d messages", len(batch)))
+		}
+	}
+}
+
+// publishBatch publishes all messages of the batch and awaits their confirmations.
+// It returns the number of messages that were confirmed before the first error occurred.
+func (bp *BatchPublisher) publishBatch(ctx context.Context, batch []BatchPublishing) (confirmed int, err error) {
+	s, err := bp.sp.GetSession(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		bp.sp.ReturnSession(s, err)
+	}()
+
+	tags := make([]uint64, 0, len(batch))
+	for _, msg := range batch {
+		tag, err := s.Publish(ctx, msg.Exchange, msg.RoutingKey, msg.Publishing)
+		if err != nil {
+			return 0, err
+		}
+		tags = append(tags, tag)
+	}
+
+	if !s.IsConfirmable() {
+		return len(batch), nil
+	}
+
+	for i, tag := range tags {
+		err = s.AwaitConfirm(ctx, tag)
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(batch), nil
+}
+
+func (bp *BatchPublisher) failed(msgs []BatchPublishing, err error) {
+	bp.error(err, fmt.Sprintf("failed to publish %d messages", len(msgs)))
+	if bp.errorCB != nil {
+		_ = callSafe(bp.log, "batch publisher error callback", ErrCallbackPanic, func() error {
+			bp.errorCB(msgs, err)
+			return nil
+		})
+	}
+}
+
+func (bp *BatchPublisher) info(a ...any) {
+	bp.log.WithField("batchPublisher", bp.sp.pool.Name()).Info(a...)
+}
+
+func (bp *BatchPublisher) debug(a ...any) {
+	bp.log.WithField("batchPublisher", bp.sp.pool.Name()).Debug(a...)
+}
+
+func (bp *BatchPublisher) warn(err error, a ...any) {
+	bp.log.WithField("batchPublisher", bp.sp.pool.Name()).WithField("error", err.Error()).Warn(a...)
+}
+
+func (bp *BatchPublisher) error(err error, a ...any) {
+	bp.log.WithField("batchPublisher", bp.sp.pool.Name()).WithField("error", err.Error()).Error(a...)
+}
@@ -0,0 +1,91 @@
+package pool
+
+import (
+	"context"
+	"time"
+
+	"github.com/jxsl13/amqpx/logging"
+)
+
+// BatchPublisherErrorCallback is called with all messages of a batch that could not be published.
+// The messages are passed in the order in which they were added to the BatchPublisher.
+type BatchPublisherErrorCallback func(msgs []BatchPublishing, err error)
+
+type batchPublisherOption struct {
+	Ctx context.Context
+
+	MaxMessages   int
+	MaxBytes      int
+	FlushInterval time.Duration
+	FlushTimeout  time.Duration
+
+	ErrorCallback BatchPublisherErrorCallback
+
+	Logger logging.Logger
+}
+
+type BatchPublisherOption func(*batchPublisherOption)
+
+func BatchPublisherWithContext(ctx context.Context) BatchPublisherOption {
+	return func(bpo *batchPublisherOption) {
+		bpo.Ctx = ctx
+	}
+}
+
+func BatchPublisherWithLogger(logger logging.Logger) BatchPublisherOption {
+	return func(bpo *batchPublisherOption) {
+		bpo.Logger = logger
+	}
+}
+
+// BatchPublisherWithMaxMessages flushes a batch as soon as it contains the given number of messages.
+// Values smaller than 1 are set to 1.
+func BatchPublisherWithMaxMessages(maxMessages int) BatchPublisherOption {
+	if maxMessages < 1 {
+		maxMessages = 1
+	}
+	return func(bpo *batchPublisherOption) {
+		bpo.MaxMessages = maxMessages
+	}
+}
+
+// BatchPublisherWithMaxBytes flushes a batch as soon as the sum of its message bodies reaches the given number of bytes.
+// Values smaller than 1 are set to 1.
+func BatchPublisherWithMaxBytes(maxBytes int) BatchPublisherOption {
+	if maxBytes < 1 {
+		maxBytes = 1
+	}
+	return func(bpo *batchPublisherOption) {
+		bpo.MaxBytes = maxBytes
+	}
+}
+
+// BatchPublisherWithFlushInterval flushes a non-empty batch after the given interval at the latest.
+// The interval MUST be >= 1 * time.Millisecond.
+func BatchPublisherWithFlushInterval(interval time.Duration) BatchPublisherOption {
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	return func(bpo *batchPublisherOption) {
+		bpo.FlushInterval = interval
+	}
+}
+
+// BatchPublisherWithFlushTimeout limits the time that is spent on publishing a single batch including all retries.
+// The timeout MUST be >= 1 * time.Second.
+func BatchPublisherWithFlushTimeout(timeout time.Duration) BatchPublisherOption {
+	if timeout < time.Second {
+		timeout = time.Second
+	}
+	return func(bpo *batchPublisherOption) {
+		bpo.FlushTimeout = timeout
+	}
+}
+
+// BatchPublisherWithErrorCallback is called for every batch that could not be published.
+// Without a callback the failed messages are only logged.
+func BatchPublisherWithErrorCallback(callback BatchPublisherErrorCallback) BatchPublisherOption {
+	return func(bpo *batchPublisherOption) {
+		bpo.ErrorCallback = callback
+	}
+}
@@ -0,0 +1,164 @@
+package pool_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jxsl13/amqpx/internal/testutils"
+	"github.com/jxsl13/amqpx/logging"
+	"github.com/jxsl13/amqpx/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchPublisher(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx          = context.TODO()
+		log          = logging.NewTestLogger(t)
+		poolName     = testutils.FuncName()
+		nextConnName = testutils.ConnectionNameGenerator()
+		numMsgs      = 250
+	)
+
+	hs, hsclose := NewSession(t, ctx, testutils.HealthyConnectURL, nextConnName())
+	defer hsclose()
+
+	cp, err := pool.NewConnectionPool(ctx, testutils.HealthyConnectURL, 1,
+		pool.ConnectionPoolWithName(poolName),
+		pool.ConnectionPoolWithLogger(log),
+	)
+	require.NoError(t, err)
+	defer cp.Close()
+
+	sp, err := pool.NewSessionPool(cp, 1, pool.SessionPoolWithConfirms(true))
+	require.NoError(t, err)
+	defer sp.Close()
+
+	var (
+		nextExchangeName = testutils.ExchangeNameGenerator(hs.Name())
+		nextQueueName    = testutils.QueueNameGenerator(hs.Name())
+		exchangeName     = nextExchangeName()
+		queueName        = nextQueueName()
+	)
+	cleanup := DeclareExchangeQueue(t, ctx, hs, exchangeName, queueName)
+	defer cleanup()
+
+	var (
+		nextConsumerName = testutils.ConsumerNameGenerator(queueName)
+		publisherMsgGen  = testutils.MessageGenerator(queueName)
+		consumerMsgGen   = testutils.MessageGenerator(queueName)
+		wg               sync.WaitGroup
+	)
+
+	bp := pool.NewBatchPublisher(sp,
+		pool.BatchPublisherWithMaxMessages(20),
+		pool.BatchPublisherWithFlushInterval(50*time.Millisecond),
+		pool.BatchPublisherWithErrorCallback(func(msgs []pool.BatchPublishing, err error) {
+			assert.NoErrorf(t, err, "failed to publish %d messages", len(msgs))
+		}),
+	)
+
+	// messages must arrive in order without duplicates
+	ConsumeAsyncN(t, ctx, &wg, hs, queueName, nextConsumerName(), consumerMsgGen, numMsgs, false)
+
+	for i := 0; i < numMsgs; i++ {
+		err := bp.Add(ctx, exchangeName, "", pool.Publishing{
+			ContentType: "text/plain",
+			Body:        []byte(publisherMsgGen()),
+		})
+		assert.NoError(t, err)
+	}
+
+	// flushes the last partial batch
+	bp.Close()
+
+	err = bp.Add(ctx, exchangeName, "", pool.Publishing{Body: []byte("closed")})
+	assert.ErrorIs(t, err, pool.ErrClosed)
+
+	wg.Wait()
+}
+
+func BenchmarkSessionPublish(b *testing.B) {
+	benchmarkPublish(b, func(sp *pool.SessionPool, queueName string) (publish func(body []byte) error, close func()) {
+		publish = func(body []byte) (err error) {
+			ctx := context.Background()
+			s, err := sp.GetSession(ctx)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				sp.ReturnSession(s, err)
+			}()
+			tag, err := s.Publish(ctx, "", queueName, pool.Publishing{Body: body})
+			if err != nil {
+				return err
+			}
+			return s.AwaitConfirm(ctx, tag)
+		}
+		return publish, func() {}
+	})
+}
+
+func BenchmarkBatchPublisherAdd(b *testing.B) {
+	benchmarkPublish(b, func(sp *pool.SessionPool, queueName string) (publish func(body []byte) error, close func()) {
+		bp := pool.NewBatchPublisher(sp, pool.BatchPublisherWithErrorCallback(func(msgs []pool.BatchPublishing, err error) {
+			b.Errorf("failed to publish %d messages: %v", len(msgs), err)
+		}))
+		return func(body []byte) error {
+			return bp.Add(context.Background(), "", queueName, pool.Publishing{Body: body})
+		}, bp.Close
+	})
+}
+
+func benchmarkPublish(b *testing.B, newPublisher func(sp *pool.SessionPool, queueName string) (publish func(body []byte) error, close func())) {
+	ctx := context.Background()
+	cp, err := pool.NewConnectionPool(ctx, testutils.HealthyConnectURL, 1,
+		pool.ConnectionPoolWithName(testutils.FuncName()),
+	)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer cp.Close()
+
+	sp, err := pool.NewSessionPool(cp, 1, pool.SessionPoolWithConfirms(true))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer sp.Close()
+
+	s, err := sp.GetSession(ctx)
+	if err != nil {
+		b.Fatal(err)
+	}
+	queueName := testutils.QueueNameGenerator(s.Name())()
+	_, err = s.QueueDeclare(ctx, queueName)
+	sp.ReturnSession(s, err)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() {
+		s, err := sp.GetSession(ctx)
+		if err != nil {
+			b.Error(err)
+			return
+		}
+		_, err = s.QueueDelete(ctx, queueName)
+		sp.ReturnSession(s, err)
+	}()
+
+	publish, closePublisher := newPublisher(sp, queueName)
+	body := []byte("benchmark message")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := publish(body); err != nil {
+			b.Fatal(err)
+		}
+	}
+	closePublisher()
+	b.StopTimer()
+}
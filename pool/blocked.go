@@ -0,0 +1,53 @@
+package pool
+
+import "sync"
+
+// blockedAggregate rolls up the flow control state of all connections of a pool.
+// It notifies once when the first connection is blocked and once when the last blocked connection is unblocked,
+// which avoids one notification per connection during a broker resource alarm.
+type blockedAggregate struct {
+	mu sync.Mutex
+	// number of blocked connections, connections only notify about changes of their blocked state
+	blocked int
+
+	poolName    string
+	onBlocked   PoolBlockedCallback
+	onUnblocked PoolBlockedCallback
+}
+
+func newBlockedAggregate(poolName string, onBlocked, onUnblocked PoolBlockedCallback) *blockedAggregate {
+	return &blockedAggregate{
+		poolName:    poolName,
+		onBlocked:   onBlocked,
+		onUnblocked: onUnblocked,
+	}
+}
+
+// update is the ConnectionBlockedCallback of all connections of the pool.
+func (ba *blockedAggregate) update(_ string, blocked bool) {
+	ba.mu.Lock()
+	defer ba.mu.Unlock()
+
+	before := ba.blocked
+	if blocked {
+		ba.blocked++
+	} else if ba.blocked > 0 {
+		ba.blocked--
+	}
+	after := ba.blocked
+
+	// callbacks are called while holding the lock in order to preserve their order
+	switch {
+	case before == 0 && after > 0 && ba.onBlocked != nil:
+		ba.onBlocked(ba.poolName)
+	case before > 0 && after == 0 && ba.onUnblocked != nil:
+		ba.onUnblocked(ba.poolName)
+	}
+}
+
+// isBlocked returns true in case at least one connection of the pool is blocked.
+func (ba *blockedAggregate) isBlocked() bool {
+	ba.mu.Lock()
+	defer ba.mu.Unlock()
+	return ba.blocked > 0
+}
@@ -0,0 +1,45 @@
+package pool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockedAggregate(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx       = context.TODO()
+		events    []string
+		aggregate = newBlockedAggregate("pool",
+			func(poolName string) { events = append(events, poolName+" blocked") },
+			func(poolName string) { events = append(events, poolName+" unblocked") },
+		)
+	)
+
+	c1, err := newConnection(ctx, testConnectURL, "c1", ConnectionWithBlockedCallback(aggregate.update))
+	require.NoError(t, err)
+	defer c1.Close()
+	c2, err := newConnection(ctx, testConnectURL, "c2", ConnectionWithBlockedCallback(aggregate.update))
+	require.NoError(t, err)
+	defer c2.Close()
+
+	c1.setBlocked("c1", true)
+	c2.setBlocked("c2", true)
+	// repeated notifications do not change the state
+	c1.setBlocked("c1", true)
+	assert.True(t, aggregate.isBlocked())
+	assert.Equal(t, []string{"pool blocked"}, events)
+
+	c1.setBlocked("c1", false)
+	assert.True(t, aggregate.isBlocked())
+	c1.setBlocked("c1", false)
+	assert.Equal(t, []string{"pool blocked"}, events)
+
+	c2.setBlocked("c2", false)
+	assert.False(t, aggregate.isBlocked())
+	assert.Equal(t, []string{"pool blocked", "pool unblocked"}, events)
+}
@@ -0,0 +1,85 @@
+package pool
+
+import (
+	"fmt"
+	"sort"
+)
+
+// WeightedURL is a broker connection url with a weight that defines the share of the cached connections
+// of a connection pool that are connected to this broker.
+type WeightedURL struct {
+	URL    string
+	Weight int
+}
+
+// parseBrokerWeights validates the weighted broker urls and normalizes their urls.
+func parseBrokerWeights(brokers []WeightedURL, tlsEnabled bool) ([]WeightedURL, error) {
+	result := make([]WeightedURL, 0, len(brokers))
+	for _, b := range brokers {
+		u, err := parseURL(b.URL, tlsEnabled)
+		if err != nil {
+			return nil, err
+		}
+		if b.Weight < 1 {
+			return nil, fmt.Errorf("%w: broker %s has weight %d", ErrInvalidBrokerWeight, u.Redacted(), b.Weight)
+		}
+		result = append(result, WeightedURL{URL: u.String(), Weight: b.Weight})
+	}
+	return result, nil
+}
+
+// distributeByWeight assigns each of the n connections to a broker index proportionally to the broker weights.
+// The connections that remain after the proportional assignment are assigned to the brokers with the
+// largest remainders, ties are resolved in the order of the brokers.
+// Connection ids are assigned in contiguous blocks per broker.
+// No assignment is returned in case there are no brokers, in which case all connections use the connection url.
+func distributeByWeight(n int, brokers []WeightedURL) []int {
+	if len(brokers) == 0 {
+		return nil
+	}
+
+	total := 0
+	for _, b := range brokers {
+		total += b.Weight
+	}
+
+	counts := make([]int, len(brokers))
+	remainders := make([]int, len(brokers))
+	assigned := 0
+	for i, b := range brokers {
+		counts[i] = n * b.Weight / total
+		remainders[i] = n * b.Weight % total
+		assigned += counts[i]
+	}
+
+	order := make([]int, len(brokers))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return remainders[order[i]] > remainders[order[j]]
+	})
+	for i := 0; assigned < n; i++ {
+		counts[order[i]]++
+		assigned++
+	}
+
+	assignment := make([]int, 0, n)
+	for i, c := range counts {
+		for j := 0; j < c; j++ {
+			assignment = append(assignment, i)
+		}
+	}
+	return assignment
+}
+
+// failoverURLs returns the urls of all brokers except the one with the given index.
+func failoverURLs(brokers []WeightedURL, except int) []string {
+	urls := make([]string, 0, len(brokers))
+	for i, b := range brokers {
+		if i != except {
+			urls = append(urls, b.URL)
+		}
+	}
+	return urls
+}
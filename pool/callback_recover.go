@@ -0,0 +1,27 @@
+package pool
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/jxsl13/amqpx/logging"
+)
+
+// callSafe invokes a user supplied callback or handler and recovers from its panics, which would otherwise crash
+// the goroutine of the pool that invoked it. Panics are logged with their stack trace and converted into an error
+// that wraps the passed sentinel error, e.g. ErrHandlerPanic or ErrCallbackPanic.
+func callSafe(log logging.Logger, name string, sentinel error, f func() error) (err error) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		err = fmt.Errorf("%w: %s: %v", sentinel, name, r)
+		log.WithFields(map[string]any{
+			"callback": name,
+			"panic":    fmt.Sprint(r),
+			"stack":    string(debug.Stack()),
+		}).Error("recovered from panic")
+	}()
+	return f()
+}
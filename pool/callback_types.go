@@ -1,8 +1,37 @@
 package pool
 
+import (
+	"context"
+	"time"
+)
+
 // ConnectionRecoverCallback is a function that can be called after a connection failed to be established
-// and is about to be recovered.
+// and is about to be recovered. It is called without holding the lock of the connection.
+// Panics are recovered and logged.
 type ConnectionRecoverCallback func(name string, retry int, err error)
 
+// ConnectionBlockedCallback is called whenever the broker starts or stops blocking a connection due to
+// resource alarms (flow control). blocked is false after the connection was unblocked or lost.
+type ConnectionBlockedCallback func(name string, blocked bool)
+
+// ConnectionMaxBackoffCallback is called with maxed set to true the first time the recovery backoff of a connection
+// reaches its maximum, which indicates a sustained outage rather than a brief connection loss.
+// It is called with maxed set to false once such a connection was recovered.
+// It is called without holding the lock of the connection. Panics are recovered and logged.
+type ConnectionMaxBackoffCallback func(name string, maxed bool)
+
+// PoolBlockedCallback is called whenever the broker starts blocking the first connection of a pool
+// or stops blocking the last blocked connection of a pool.
+type PoolBlockedCallback func(poolName string)
+
 // RetryCallback is a function that is called when some operation fails.
+// It is called without holding the lock of the session. Panics are recovered and logged.
 type SessionRetryCallback func(operation, connName, sessionName string, retry int, err error)
+
+// TopologyFunc declares the topology of a queue, e.g. the queue itself and its bindings, see ConsumeOptions.Topology.
+// It must be idempotent, as it may be applied multiple times.
+type TopologyFunc func(ctx context.Context, s *Session) error
+
+// SlowHandlerCallback is called by the handler watchdog of a subscriber as soon as a handler has been processing
+// a message or batch for longer than the configured threshold. The handler is still running at that point.
+type SlowHandlerCallback func(consumer, queue string, elapsed time.Duration)
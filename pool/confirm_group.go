@@ -0,0 +1,150 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// ConfirmGroup is a confirmation barrier for messages that are published concurrently across multiple sessions.
+// Messages are published via ConfirmGroup.Publish, Wait awaits the broker confirmations of all of them.
+// The sessions must be in confirm mode and their confirmations must not be awaited by anyone else until Wait returns.
+// The zero value is ready to use.
+type ConfirmGroup struct {
+	mu      sync.Mutex
+	pending map[*Session]*groupConfirms
+}
+
+// groupConfirms are the outstanding confirmations of a single session of a group in publishing order.
+type groupConfirms struct {
+	epoch uint64
+	tags  []uint64
+	err   error
+}
+
+// NewConfirmGroup creates a new empty confirmation group.
+func NewConfirmGroup() *ConfirmGroup {
+	return &ConfirmGroup{}
+}
+
+// Publish publishes the message via the passed session and adds its confirmation to the group.
+// Publish may be called concurrently for different sessions.
+func (g *ConfirmGroup) Publish(ctx context.Context, s *Session, exchange string, routingKey string, msg Publishing) (deliveryTag uint64, err error) {
+	if !s.IsConfirmable() {
+		return 0, fmt.Errorf("failed to publish message of confirm group: %w", ErrNoConfirms)
+	}
+
+	deliveryTag, epoch, err := s.publish(ctx, exchange, routingKey, msg)
+	if err != nil {
+		return 0, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.add(s, epoch, deliveryTag)
+	return deliveryTag, nil
+}
+
+// not threadsafe
+func (g *ConfirmGroup) add(s *Session, epoch, deliveryTag uint64) {
+	if g.pending == nil {
+		g.pending = make(map[*Session]*groupConfirms)
+	}
+
+	c, ok := g.pending[s]
+	if !ok {
+		g.pending[s] = &groupConfirms{epoch: epoch, tags: []uint64{deliveryTag}}
+		return
+	}
+
+	if c.epoch != epoch && c.err == nil {
+		// the session was recovered between two publishings, the confirmations of the previous ones are lost
+		c.err = fmt.Errorf("%w: channel of session %s was recovered", ErrConfirmLost, s.Name())
+	}
+	c.tags = append(c.tags, deliveryTag)
+}
+
+// Len returns the number of published messages whose confirmations were not awaited, yet.
+func (g *ConfirmGroup) Len() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	n := 0
+	for _, c := range g.pending {
+		n += len(c.tags)
+	}
+	return n
+}
+
+// Wait blocks until the broker acknowledged all messages that were published via the group, or returns the first error,
+// e.g. ErrNack, ErrReturned or a context error. The confirmations of the sessions are awaited concurrently.
+// ErrConfirmLost is returned in case the channel of a session was recovered after a message was published on it.
+// The group is empty afterwards and can be reused, independent of the returned error.
+// In case of an error, the confirmations that the sessions of the group received but which were not awaited are discarded,
+// as they would otherwise be mistaken for the confirmations of subsequent publishings. Confirmations that are received
+// after Wait returned are not discarded, which is why the sessions should be returned to their pool with the error.
+func (g *ConfirmGroup) Wait(ctx context.Context) error {
+	g.mu.Lock()
+	pending := g.pending
+	g.pending = nil
+	g.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	for s, c := range pending {
+		wg.Add(1)
+		go func(s *Session, c *groupConfirms) {
+			defer wg.Done()
+			err := c.wait(ctx, s)
+			if err != nil {
+				c.discard(s)
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(s, c)
+	}
+	wg.Wait()
+
+	return firstErr
+}
+
+// wait awaits the confirmations of a single session in publishing order.
+func (c *groupConfirms) wait(ctx context.Context, s *Session) error {
+	if c.err != nil {
+		return c.err
+	}
+
+	for _, tag := range c.tags {
+		err := c.await(ctx, s, tag)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// await awaits a single confirmation, unless the channel on which the message was published was recovered.
+func (c *groupConfirms) await(ctx context.Context, s *Session, tag uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.awaitEpochConfirm(ctx, c.epoch, tag)
+}
+
+// discard drops the confirmations that the session received but which were not awaited.
+func (c *groupConfirms) discard(s *Session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.discardConfirms()
+}
@@ -0,0 +1,81 @@
+package pool
+
+import (
+	"context"
+	"testing"
+
+	amqp091 "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfirmGroupRecoveredSession(t *testing.T) {
+	t.Parallel()
+
+	// no channels, the confirmations are lost before they are awaited
+	var (
+		ctx       = context.Background()
+		g         = NewConfirmGroup()
+		recovered = &Session{name: "recovered", confirmable: true, channels: 2}
+		republish = &Session{name: "republish", confirmable: true, channels: 2}
+	)
+	assert.NoError(t, g.Wait(ctx), "empty group")
+
+	// published on the first channel, recovered afterwards
+	g.add(recovered, 0, 1)
+	assert.Equal(t, 1, g.Len())
+	assert.ErrorIs(t, g.Wait(ctx), ErrConfirmLost)
+	assert.Equal(t, 0, g.Len(), "group must be reusable after Wait")
+
+	// recovered between two publishings
+	g.add(republish, 0, 1)
+	g.add(republish, 1, 1)
+	assert.Equal(t, 2, g.Len())
+	assert.ErrorIs(t, g.Wait(ctx), ErrConfirmLost)
+
+	_, err := g.Publish(ctx, &Session{name: "unconfirmable"}, "", "queue", Publishing{})
+	assert.ErrorIs(t, err, ErrNoConfirms)
+	assert.Equal(t, 0, g.Len())
+}
+
+func TestConfirmGroupDiscardsConfirms(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx = context.Background()
+		g   = NewConfirmGroup()
+	)
+
+	conn, err := newConnection(ctx, testConnectURL, "confirm-group")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	newSession := func(name string) *Session {
+		return &Session{
+			name:        name,
+			confirmable: true,
+			channels:    1,
+			conn:        conn,
+			ctx:         ctx,
+			confirms:    make(chan amqp091.Confirmation, 2),
+		}
+	}
+
+	// the first message is not acknowledged, the confirmation of the second one is never awaited
+	nacked := newSession("nacked")
+	nacked.confirms <- amqp091.Confirmation{DeliveryTag: 1, Ack: false}
+	nacked.confirms <- amqp091.Confirmation{DeliveryTag: 2, Ack: true}
+	g.add(nacked, 0, 1)
+	g.add(nacked, 0, 2)
+
+	// the wait of the other session is canceled before any confirmation was received
+	canceled := newSession("canceled")
+	g.add(canceled, 0, 1)
+
+	assert.ErrorIs(t, g.Wait(ctx), ErrNack)
+	assert.Len(t, nacked.confirms, 0, "confirms that were not awaited must be discarded")
+
+	// the confirmations of subsequent publishings are not mistaken for the discarded ones
+	nacked.confirms <- amqp091.Confirmation{DeliveryTag: 3, Ack: true}
+	assert.NoError(t, nacked.AwaitConfirm(ctx, 3))
+}
@@ -6,10 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"net/url"
-
 	"github.com/jxsl13/amqpx/logging"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
@@ -20,21 +19,51 @@ type Connection struct {
 	url  string
 	addr string
 
-	name string
+	// urls of other brokers that are tried in case the broker of url is not reachable
+	failoverURLs []string
+
+	// name of the connection, accessible without locking, as the lock is held during recoveries
+	name atomic.Value // string
 
 	// indicates that the connection is part of a connection pool.
 	cached bool
 	// if set to true, the connection is marked as broken, indicating the connection must be recovered
 	flagged bool
-
-	tls *tls.Config
+	// the underlying connection of an idle cached connection was closed by the pool, it is reopened upon its next usage
+	released bool
+	// unix nano timestamp at which the cached connection was put back into its pool the last time
+	idleSince atomic.Int64
+
+	// lifecycle state, accessible without locking the connection mutex, which is held during recovery
+	state   atomic.Int32
+	blocked atomic.Bool
+	// identifies the latest underlying connection, so that stale watchers do not modify the state
+	generation atomic.Uint64
+
+	// parameters that were negotiated with the broker upon the latest connect, accessible without locking
+	negotiatedChannelMax atomic.Int64
+	negotiatedFrameSize  atomic.Int64
+	negotiatedHeartbeat  atomic.Int64
+
+	// settings that can be replaced at runtime, guarded by settingsMu, as mu is held during recovery
+	settingsMu sync.RWMutex
+	tls        *tls.Config
+	// backoff policy
+	errorBackoff BackoffFunc
+	log          logging.Logger
 
 	// underlying amqp connection
 	conn         *amqp.Connection
 	lastConnLoss time.Time
-
-	// backoff policy
-	errorBackoff BackoffFunc
+	// unix nano timestamp of the latest successful connect, accessible without locking
+	connectedAt atomic.Int64
+	// number of channels that were opened on this connection, including channels of previous underlying connections
+	channelsOpened atomic.Uint64
+	// diagnostics, accessible without locking
+	remoteAddr atomic.Value // string
+	// url of the broker that the connection was connected to most recently
+	brokerURL atomic.Value // string
+	lastErr   atomic.Value // string
 
 	heartbeat time.Duration
 
@@ -42,6 +71,22 @@ type Connection struct {
 	// recovering connections are recovered as long as the calling context
 	// is not canceled
 	connTimeout time.Duration
+	// bounds the TCP connect only, 0 falls back to the connection timeout
+	tcpConnectTimeout time.Duration
+	// deadline of every read and write on the socket, 0 is disabled
+	ioTimeout time.Duration
+	// bounds the initial connect of NewConnection including its retries, 0 is disabled
+	initialDialTimeout time.Duration
+
+	// client properties that are advertised on every (re)connect in addition to the connection name
+	properties Table
+
+	// network that is used for dialing, e.g. tcp4 or tcp6, empty for the default tcp
+	addressFamily string
+
+	// authenticate with SASL EXTERNAL instead of the url credentials and retry rejected attempts once with PLAIN
+	externalAuth      bool
+	plainAuthFallback bool
 
 	errors chan *amqp.Error
 	// flow control messages from rabbitmq
@@ -51,17 +96,58 @@ type Connection struct {
 	ctx    context.Context
 	cancel context.CancelFunc
 
-	log logging.Logger
-
 	recoverCB ConnectionRecoverCallback
+	blockedCB ConnectionBlockedCallback
+
+	maxBackoffCB ConnectionMaxBackoffCallback
+	// the recovery backoff reached its maximum since the connection was last connected
+	backoffMaxed bool
+
+	recoveries      *atomic.Uint64
+	recoveryLimiter chan struct{}
+	recoveryGate    *recoveryGate
+
+	closeCB   func()
+	closeOnce sync.Once
 }
 
 // NewConnection creates a connection wrapper.
 // name: unique connection name
 func NewConnection(ctx context.Context, connectUrl, name string, options ...ConnectionOption) (*Connection, error) {
+	conn, err := newConnection(ctx, connectUrl, name, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	connectCtx := ctx
+	if conn.initialDialTimeout > 0 {
+		var cancel context.CancelFunc
+		connectCtx, cancel = context.WithTimeout(ctx, conn.initialDialTimeout)
+		defer cancel()
+	}
+
+	err = conn.Connect(connectCtx)
+	if err == nil {
+		return conn, nil
+	}
+
+	if !recoverable(err) {
+		return nil, err
+	}
+
+	err = conn.Recover(connectCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// newConnection creates the connection wrapper without connecting to the broker.
+func newConnection(ctx context.Context, connectUrl, name string, options ...ConnectionOption) (*Connection, error) {
 	// use sane defaults
 	option := connectionOption{
-		Logger:            logging.NewNoOpLogger(),
+		Logger:            defaultLogger(),
 		Cached:            false,
 		HeartbeatInterval: 15 * time.Second,
 		ConnectionTimeout: 30 * time.Second,
@@ -75,13 +161,25 @@ func NewConnection(ctx context.Context, connectUrl, name string, options ...Conn
 		o(&option)
 	}
 
-	u, err := url.ParseRequestURI(connectUrl)
+	tlsConfig := withSessionCache(withServerName(option.TLSConfig, option.TLSServerName))
+
+	u, err := parseURL(connectUrl, tlsConfig != nil)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrInvalidConnectURL, err)
+		return nil, err
 	}
 
-	if option.TLSConfig != nil {
-		u.Scheme = "amqps"
+	failover := make([]string, 0, len(option.FailoverURLs))
+	for _, f := range option.FailoverURLs {
+		fu, err := parseURL(f, tlsConfig != nil)
+		if err != nil {
+			return nil, err
+		}
+		failover = append(failover, fu.String())
+	}
+
+	err = validateAddressFamily(option.AddressFamily)
+	if err != nil {
+		return nil, err
 	}
 
 	// we derive a new context from the parent one in order to
@@ -90,18 +188,25 @@ func NewConnection(ctx context.Context, connectUrl, name string, options ...Conn
 	cancel := toCancelFunc(fmt.Errorf("connection %w", ErrClosed), cc)
 
 	conn := &Connection{
-		url:     u.String(),
-		addr:    u.Host,
-		name:    name,
-		cached:  option.Cached,
-		flagged: false,
-		tls:     option.TLSConfig,
-
-		conn: nil, // will be initialized below
-
-		heartbeat:    option.HeartbeatInterval,
-		connTimeout:  option.ConnectionTimeout,
-		errorBackoff: option.BackoffPolicy,
+		url:          u.String(),
+		addr:         u.Host,
+		failoverURLs: failover,
+		cached:       option.Cached,
+		flagged:      false,
+		tls:          tlsConfig,
+
+		conn: nil, // will be initialized in connect
+
+		heartbeat:          option.HeartbeatInterval,
+		connTimeout:        option.ConnectionTimeout,
+		tcpConnectTimeout:  option.TCPConnectTimeout,
+		initialDialTimeout: option.InitialDialTimeout,
+		ioTimeout:          option.IOTimeout,
+		properties:         option.Properties,
+		addressFamily:      option.AddressFamily,
+		externalAuth:       option.ExternalAuth,
+		plainAuthFallback:  option.PlainAuthFallback,
+		errorBackoff:       option.BackoffPolicy,
 
 		errors:   make(chan *amqp.Error, 10),
 		blocking: make(chan amqp.Blocking, 10),
@@ -113,23 +218,47 @@ func NewConnection(ctx context.Context, connectUrl, name string, options ...Conn
 		lastConnLoss: time.Now(),
 
 		recoverCB: option.RecoverCallback,
-	}
+		blockedCB: option.BlockedCallback,
 
-	err = conn.Connect(ctx)
-	if err == nil {
-		return conn, nil
-	}
+		maxBackoffCB: option.MaxBackoffCallback,
 
-	if !recoverable(err) {
-		return nil, err
+		recoveries:      option.recoveries,
+		recoveryLimiter: option.recoveryLimiter,
+		recoveryGate:    option.recoveryGate,
+
+		closeCB: option.closeCallback,
 	}
+	conn.name.Store(name)
+	conn.idleSince.Store(time.Now().UnixNano())
+	return conn, nil
+}
 
-	err = conn.Recover(ctx)
-	if err != nil {
-		return nil, err
+// withServerName returns a clone of the passed tls config whose server name is overridden by a non-empty serverName.
+// A non-empty serverName implies the usage of tls.
+func withServerName(config *tls.Config, serverName string) *tls.Config {
+	if serverName == "" {
+		return config
 	}
+	// clone the config in order not to mutate a config that might be shared
+	if config == nil {
+		config = &tls.Config{}
+	} else {
+		config = config.Clone()
+	}
+	config.ServerName = serverName
+	return config
+}
 
-	return conn, nil
+// withSessionCache returns a clone of the passed tls config with a client session cache, which allows to resume
+// tls sessions instead of doing full handshakes when reconnecting. The config is returned as is in case it is nil,
+// already has a session cache or session resumption was explicitly disabled via SessionTicketsDisabled.
+func withSessionCache(config *tls.Config) *tls.Config {
+	if config == nil || config.ClientSessionCache != nil || config.SessionTicketsDisabled {
+		return config
+	}
+	config = config.Clone()
+	config.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+	return config
 }
 
 func (ch *Connection) Close() (err error) {
@@ -146,6 +275,10 @@ func (ch *Connection) Close() (err error) {
 	}()
 
 	ch.cancel() // close derived context
+	ch.setState(ConnectionStateClosed)
+	if ch.closeCB != nil {
+		ch.closeOnce.Do(ch.closeCB)
+	}
 
 	if !ch.isClosed() {
 		return ch.conn.Close() // close internal channel
@@ -161,10 +294,14 @@ func (ch *Connection) Flag(err error) {
 	ch.mu.Lock()
 	defer ch.mu.Unlock()
 
+	if err != nil {
+		ch.setLastError(err)
+	}
 	flagged := err != nil && recoverable(err)
 
 	if !ch.flagged && flagged {
 		ch.flagged = flagged
+		ch.state.CompareAndSwap(int32(ConnectionStateConnected), int32(ConnectionStateRecovering))
 	}
 }
 
@@ -177,6 +314,8 @@ func (ch *Connection) IsFlagged() bool {
 // Connect tries to connect (or reconnect)
 // Does not block indefinitely, but returns an error
 // upon connection failure.
+// The passed context bounds the dial including the TLS and AMQP handshakes independent of the connection timeout,
+// which allows to abort a dial that is in flight.
 func (ch *Connection) Connect(ctx context.Context) error {
 	ch.mu.Lock()
 	defer ch.mu.Unlock()
@@ -184,6 +323,12 @@ func (ch *Connection) Connect(ctx context.Context) error {
 }
 
 func (ch *Connection) connect(ctx context.Context) error {
+	return ch.connectExcept(ctx, "")
+}
+
+// connectExcept connects to any broker of the connection except for the broker with the passed url.
+// not threadsafe
+func (ch *Connection) connectExcept(ctx context.Context, except string) error {
 
 	// not closed, close before reconnecting
 	if !ch.isClosed() {
@@ -192,22 +337,24 @@ func (ch *Connection) connect(ctx context.Context) error {
 	}
 
 	ch.debug("connecting...")
-	amqpConn, err := amqp.DialConfig(ch.url,
-		amqp.Config{
-			Heartbeat:       ch.heartbeat,
-			Dial:            defaultDial(ctx, ch.connTimeout),
-			TLSClientConfig: ch.tls.Clone(),
-			Properties: amqp.Table{
-				"connection_name": ch.name,
-			},
-		})
+	amqpConn, brokerURL, err := ch.dial(ctx, except)
 	if err != nil {
+		if connectionLimitReached(err) {
+			return fmt.Errorf("%v: %w: %w", ErrConnectionFailed, ErrConnectionLimitReached, err)
+		}
+		if refused(err) {
+			return fmt.Errorf("%v: %w: %w", ErrConnectionFailed, ErrAccessRefused, err)
+		}
 		// wrap the underlying amqp091 error
 		return fmt.Errorf("%v: %w", ErrConnectionFailed, err)
 	}
 
 	// override upon reconnect
 	ch.conn = amqpConn
+	ch.connectedAt.Store(time.Now().UnixNano())
+	ch.remoteAddr.Store(amqpConn.RemoteAddr().String())
+	ch.brokerURL.Store(brokerURL)
+	ch.setNegotiated(amqpConn.Config)
 	ch.errors = make(chan *amqp.Error, 10)
 	ch.blocking = make(chan amqp.Blocking, 10)
 
@@ -215,10 +362,313 @@ func (ch *Connection) connect(ctx context.Context) error {
 	ch.conn.NotifyClose(ch.errors)
 	ch.conn.NotifyBlocked(ch.blocking)
 
+	ch.setBlocked(ch.Name(), false)
+	ch.watchState(amqpConn)
+	ch.setState(ConnectionStateConnected)
+
 	ch.info("connected")
 	return nil
 }
 
+// dial connects to the broker of the connection url and falls back to the failover urls in their given order.
+// The broker with the url except is skipped, in case it is not empty.
+// not threadsafe
+func (ch *Connection) dial(ctx context.Context, except string) (*amqp.Connection, string, error) {
+	var errs error
+	for _, u := range ch.brokerURLs() {
+		if u == except {
+			continue
+		}
+		conn, err := ch.dialBroker(ctx, u)
+		if err == nil {
+			if errs != nil {
+				ch.warn(errs, "preferred broker is not reachable, connected to failover broker")
+			}
+			return conn, u, nil
+		}
+		if errs == nil {
+			errs = err
+		} else {
+			errs = errors.Join(errs, err)
+		}
+		if ctx.Err() != nil {
+			// do not try the remaining brokers
+			break
+		}
+	}
+	if errs == nil {
+		return nil, "", fmt.Errorf("%w: no other broker to connect to", ErrInvalidConnectURL)
+	}
+	return nil, "", errs
+}
+
+// dialBroker connects to the broker with the passed url.
+// A rejected EXTERNAL authentication is retried exactly once with PLAIN authentication, in order not to mask
+// genuine authentication failures.
+// not threadsafe
+func (ch *Connection) dialBroker(ctx context.Context, u string) (*amqp.Connection, error) {
+	cfg := ch.dialConfig(ctx)
+	conn, err := dialContext(ctx, u, cfg)
+	if err == nil || !ch.externalAuth || !ch.plainAuthFallback || !authRejected(err) || ctx.Err() != nil {
+		return conn, err
+	}
+
+	ch.warn(err, "broker rejected EXTERNAL authentication, falling back to PLAIN authentication")
+	cfg = ch.dialConfig(ctx)
+	cfg.SASL = nil // PLAIN authentication with the credentials of the url
+	conn, plainErr := dialContext(ctx, u, cfg)
+	if plainErr != nil {
+		return nil, fmt.Errorf("%w: fallback to PLAIN authentication failed: %w", err, plainErr)
+	}
+	return conn, nil
+}
+
+// brokerURLs returns the connection url followed by the failover urls.
+func (ch *Connection) brokerURLs() []string {
+	return append([]string{ch.url}, ch.failoverURLs...)
+}
+
+// migrateOff reconnects the connection to another broker in case it is currently connected
+// to the broker with the passed url. Connections that are flagged or closed are not migrated.
+func (ch *Connection) migrateOff(ctx context.Context, url string) (migrated bool, err error) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	current, _ := ch.brokerURL.Load().(string)
+	if current != url || ch.flagged || ch.isClosed() {
+		return false, nil
+	}
+	if len(ch.failoverURLs) == 0 {
+		return false, fmt.Errorf("failed to migrate connection %s: %w: no other broker to migrate to", ch.Name(), ErrInvalidConnectURL)
+	}
+
+	ch.setState(ConnectionStateRecovering)
+	err = ch.connectExcept(ctx, url)
+	if err != nil {
+		// the previous connection is closed, recover it upon its next usage
+		ch.setLastError(err)
+		ch.flagged = true
+		return false, fmt.Errorf("failed to migrate connection %s: %w", ch.Name(), err)
+	}
+	ch.info("migrated to another broker")
+	return true, nil
+}
+
+// recycle reconnects the connection in case its underlying connection is older than maxLifetime.
+// Connections that are flagged or closed are not recycled, as they are recovered anyway.
+func (ch *Connection) recycle(ctx context.Context, maxLifetime time.Duration) (recycled bool, err error) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	if ch.age(time.Now()) < maxLifetime || ch.flagged || ch.isClosed() {
+		return false, nil
+	}
+
+	ch.setState(ConnectionStateRecovering)
+	err = ch.connect(ctx)
+	if err != nil {
+		// the previous connection is closed, recover it upon its next usage
+		ch.setLastError(err)
+		ch.flagged = true
+		return false, fmt.Errorf("failed to recycle connection %s: %w", ch.Name(), err)
+	}
+	ch.info("recycled connection that exceeded its max lifetime")
+	return true, nil
+}
+
+// release closes the underlying connection of an idle connection in order to free resources on the broker side.
+// The connection is reopened by reopen. Connections that are flagged or closed are not released.
+func (ch *Connection) release() bool {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	if ch.released || ch.flagged || ch.isClosed() {
+		return false
+	}
+
+	// a released connection is not taken into account for the age of the connections
+	ch.connectedAt.Store(0)
+	ch.released = true
+	_ = ch.conn.Close()
+	ch.setState(ConnectionStateReleased)
+	ch.info("released idle connection")
+	return true
+}
+
+// reopen connects a released connection to the broker again.
+// A failed reconnect flags the connection, so that it is recovered.
+func (ch *Connection) reopen(ctx context.Context) error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	if !ch.released {
+		return nil
+	}
+	ch.released = false
+
+	err := ch.connect(ctx)
+	if err != nil {
+		ch.setLastError(err)
+		ch.flagged = true
+		return fmt.Errorf("failed to reopen connection %s: %w", ch.Name(), err)
+	}
+	return nil
+}
+
+// isReleased returns true in case the underlying connection was released by the pool.
+func (ch *Connection) isReleased() bool {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	return ch.released
+}
+
+// State returns the current lifecycle state of the connection.
+// State does not block during a recovery of the connection.
+func (ch *Connection) State() ConnectionState {
+	state := ConnectionState(ch.state.Load())
+	if state == ConnectionStateConnected && ch.blocked.Load() {
+		return ConnectionStateBlocked
+	}
+	return state
+}
+
+// setState changes the lifecycle state, a closed connection stays closed.
+func (ch *Connection) setState(state ConnectionState) {
+	for {
+		current := ch.state.Load()
+		if ConnectionState(current) == ConnectionStateClosed {
+			return
+		}
+		if ch.state.CompareAndSwap(current, int32(state)) {
+			return
+		}
+	}
+}
+
+// watchState tracks flow control and unexpected connection loss of the underlying connection.
+// The goroutine terminates as soon as the underlying connection is closed.
+func (ch *Connection) watchState(conn *amqp.Connection) {
+	var (
+		name       = ch.Name()
+		generation = ch.generation.Add(1)
+		current    = func() bool { return ch.generation.Load() == generation }
+		blocking   = conn.NotifyBlocked(make(chan amqp.Blocking, 1))
+		closed     = conn.NotifyClose(make(chan *amqp.Error, 1))
+	)
+
+	go func() {
+		for {
+			select {
+			case b, ok := <-blocking:
+				if !ok {
+					blocking = nil
+					continue
+				}
+				if current() {
+					ch.setBlocked(name, b.Active)
+				}
+			case err := <-closed:
+				if !current() {
+					return
+				}
+				ch.setBlocked(name, false)
+				if err != nil {
+					ch.setLastError(err)
+					// connection lost, it is recovered upon its next usage
+					ch.state.CompareAndSwap(int32(ConnectionStateConnected), int32(ConnectionStateRecovering))
+				}
+				return
+			}
+		}
+	}()
+}
+
+// ConnectedAt returns the point in time at which the underlying connection was established.
+// It is reset whenever the connection is recovered and is the zero time before the connection was established.
+func (ch *Connection) ConnectedAt() time.Time {
+	ns := ch.connectedAt.Load()
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
+
+// Age returns the duration since the underlying connection was established or 0 in case
+// the connection was not established, yet.
+func (ch *Connection) Age() time.Duration {
+	return ch.age(time.Now())
+}
+
+func (ch *Connection) age(now time.Time) time.Duration {
+	ns := ch.connectedAt.Load()
+	if ns == 0 {
+		return 0
+	}
+	return now.Sub(time.Unix(0, ns))
+}
+
+// setNegotiated stores the parameters that the broker negotiated during the connection handshake,
+// which may be lower than the requested ones.
+func (ch *Connection) setNegotiated(cfg amqp.Config) {
+	ch.negotiatedChannelMax.Store(int64(cfg.ChannelMax))
+	ch.negotiatedFrameSize.Store(int64(cfg.FrameSize))
+	ch.negotiatedHeartbeat.Store(int64(cfg.Heartbeat))
+}
+
+// NegotiatedChannelMax returns the maximum number of channels that the broker allows to be opened
+// on the connection, as negotiated upon the latest connect. It returns 0 before the connection was established.
+func (ch *Connection) NegotiatedChannelMax() int {
+	return int(ch.negotiatedChannelMax.Load())
+}
+
+// NegotiatedFrameSize returns the maximum frame size in bytes, as negotiated upon the latest connect.
+// It returns 0 before the connection was established.
+func (ch *Connection) NegotiatedFrameSize() int {
+	return int(ch.negotiatedFrameSize.Load())
+}
+
+// NegotiatedHeartbeat returns the heartbeat interval, as negotiated upon the latest connect.
+// It returns 0 before the connection was established or in case heartbeats were disabled by the broker.
+func (ch *Connection) NegotiatedHeartbeat() time.Duration {
+	return time.Duration(ch.negotiatedHeartbeat.Load())
+}
+
+// setBlocked updates the flow control state and notifies the blocked callback upon changes.
+func (ch *Connection) setBlocked(name string, blocked bool) {
+	if ch.blocked.Swap(blocked) != blocked && ch.blockedCB != nil {
+		// the name is passed, as the state is also tracked without holding the lock
+		log := ch.logger().WithField("connection", name)
+		_ = callSafe(log, "connection blocked callback", ErrCallbackPanic, func() error {
+			ch.blockedCB(name, blocked)
+			return nil
+		})
+	}
+}
+
+// dialConfig returns the configuration that is used to (re)connect to the broker.
+// not threadsafe
+func (ch *Connection) dialConfig(ctx context.Context) amqp.Config {
+	// the amqp library modifies the properties, which is why a new table is created for every dial
+	properties := copyTable(ch.properties)
+	if properties == nil {
+		properties = make(amqp.Table, 1)
+	}
+	properties["connection_name"] = ch.Name()
+
+	var sasl []amqp.Authentication
+	if ch.externalAuth {
+		sasl = []amqp.Authentication{&amqp.ExternalAuth{}}
+	}
+
+	return amqp.Config{
+		SASL:            sasl,
+		Heartbeat:       ch.heartbeat,
+		Dial:            defaultDial(ctx, ch.addressFamily, ch.tcpConnectTimeout, ch.connTimeout, ch.ioTimeout),
+		TLSClientConfig: ch.tlsConfig().Clone(),
+		Properties:      properties,
+	}
+}
+
 func (ch *Connection) BlockingFlowControl() <-chan amqp.Blocking {
 	ch.mu.Lock()
 	defer ch.mu.Unlock()
@@ -276,15 +726,71 @@ func (ch *Connection) error() error {
 	}
 }
 
+// Ping checks the health of the connection with a cheap round trip to the broker by opening and closing
+// a throwaway channel. An error is returned in case the connection is closed, ran into an error or the broker
+// did not respond. Ping neither flags nor recovers the connection, see Flag and Recover.
+// In case ctx is done before the broker responded, ctx.Err() is returned while the round trip is completed in the background.
+// See ConnectionPoolWithHealthCheck and ConnectionPoolWithLivenessInterval in order to ping idle pooled connections periodically.
+func (ch *Connection) Ping(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("failed to ping connection %s: %w", ch.Name(), ctx.Err())
+	default:
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- ch.ping()
+	}()
+
+	select {
+	case err := <-errc:
+		if err != nil {
+			return fmt.Errorf("failed to ping connection %s: %w", ch.Name(), err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to ping connection %s: %w", ch.Name(), ctx.Err())
+	}
+}
+
+// ping checks the health of the connection with a round trip to the broker
+// by opening and closing a channel.
+func (ch *Connection) ping() error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	if ch.isClosed() {
+		return fmt.Errorf("%w: connection is not open", ErrConnectionFailed)
+	}
+	if err := ch.error(); err != nil {
+		return err
+	}
+
+	c, err := ch.conn.Channel()
+	if err != nil {
+		return err
+	}
+	return c.Close()
+}
+
 // Recover tries to recover the connection until
 // a shutdown occurs via context cancelation or until the passed context is closed.
 func (ch *Connection) Recover(ctx context.Context) error {
 	ch.mu.Lock()
 	defer ch.mu.Unlock()
-	return ch.recover(ctx)
+	return ch.recover(ctx, false)
+}
+
+// recoverUrgently recovers the connection for a caller that waits for it, which is why its recovery
+// is admitted before other queued recoveries of the pool, see ConnectionPoolWithMaxConcurrentRecoveries.
+func (ch *Connection) recoverUrgently(ctx context.Context) error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	return ch.recover(ctx, true)
 }
 
-func (ch *Connection) recover(ctx context.Context) (err error) {
+func (ch *Connection) recover(ctx context.Context, urgent bool) (err error) {
 
 	select {
 	case <-ctx.Done():
@@ -295,11 +801,19 @@ func (ch *Connection) recover(ctx context.Context) (err error) {
 		// try recovering after checking if contexts are still valid
 	}
 
-	healthy := !ch.flagged && ch.error() == nil && !ch.isClosed()
-
-	if healthy {
+	if ch.healthy() {
 		return nil
 	}
+	ch.setState(ConnectionStateRecovering)
+
+	release, ok := ch.recoveryGate.acquire(ctx, ch.catchShutdown(), urgent)
+	if !ok {
+		if ctx.Err() != nil {
+			return fmt.Errorf("connection recovery failed: %w", ctx.Err())
+		}
+		return fmt.Errorf("connection recovery failed: %w", ch.shutdownErr())
+	}
+	defer release()
 
 	var (
 		timer   = time.NewTimer(0)
@@ -308,13 +822,16 @@ func (ch *Connection) recover(ctx context.Context) (err error) {
 	defer closeTimer(timer, &drained)
 
 	ch.info("recovering")
+	// the first attempt is made immediately, as most connection losses, e.g. heartbeat timeouts
+	// due to network blips, can be recovered right away. The backoff only applies to retries.
 	for try := 0; ; try++ {
 		ch.lastConnLoss = time.Now()
-		err := ch.connect(ctx)
+		err := ch.reconnect(ctx)
 		if err == nil {
 			// connection established successfully
 			break
 		}
+		ch.setLastError(err)
 
 		if !recoverable(err) {
 			return err
@@ -323,11 +840,25 @@ func (ch *Connection) recover(ctx context.Context) (err error) {
 		if ch.recoverCB != nil {
 			// allow a user to hook into the recovery process
 			// in order to notify about the recovery process
-			ch.recoverCB(ch.name, try, err)
+			ch.notifyRecover(try, err)
+			if ch.healthy() {
+				// recovered by another goroutine while the lock was released
+				return nil
+			}
 		}
 
 		// reset to exponential backoff
-		resetTimer(timer, ch.errorBackoff(try), &drained)
+		sleep := ch.backoff(try)
+		if ch.maxBackoffCB != nil && !ch.backoffMaxed && ch.backoff(try+1) <= sleep {
+			// the backoff does not escalate any further
+			ch.backoffMaxed = true
+			ch.notifyMaxBackoff(true)
+			if ch.healthy() {
+				// recovered by another goroutine while the lock was released
+				return nil
+			}
+		}
+		resetTimer(timer, sleep, &drained)
 
 		select {
 		case <-ch.catchShutdown():
@@ -346,25 +877,185 @@ func (ch *Connection) recover(ctx context.Context) (err error) {
 	// flagged connections can only
 	// be unflagged via recovery
 	ch.flagged = false
+	if ch.recoveries != nil {
+		ch.recoveries.Add(1)
+	}
 
 	ch.info("recovered")
+	if ch.backoffMaxed {
+		ch.backoffMaxed = false
+		ch.notifyMaxBackoff(false)
+	}
 	return nil
 }
 
+// healthy returns true in case the connection is neither flagged nor closed and did not run into an error.
+// not threadsafe
+func (ch *Connection) healthy() bool {
+	return !ch.flagged && ch.error() == nil && !ch.isClosed()
+}
+
+// notifyRecover calls the recover callback without holding the connection lock,
+// which allows the callback to use the connection without deadlocking.
+// not threadsafe, must be called while holding the lock
+func (ch *Connection) notifyRecover(try int, err error) {
+	name, log := ch.Name(), ch.clog()
+	ch.mu.Unlock()
+	defer ch.mu.Lock()
+
+	_ = callSafe(log, "connection recover callback", ErrCallbackPanic, func() error {
+		ch.recoverCB(name, try, err)
+		return nil
+	})
+}
+
+// notifyMaxBackoff calls the max backoff callback without holding the connection lock.
+// not threadsafe, must be called while holding the lock
+func (ch *Connection) notifyMaxBackoff(maxed bool) {
+	name, log := ch.Name(), ch.clog()
+	ch.mu.Unlock()
+	defer ch.mu.Lock()
+
+	_ = callSafe(log, "connection max backoff callback", ErrCallbackPanic, func() error {
+		ch.maxBackoffCB(name, maxed)
+		return nil
+	})
+}
+
+// reconnect connects to the broker once the recovery limiter, if any, allows another connection to reconnect.
+// not threadsafe
+func (ch *Connection) reconnect(ctx context.Context) error {
+	if ch.recoveryLimiter != nil {
+		select {
+		case ch.recoveryLimiter <- struct{}{}:
+			defer func() {
+				<-ch.recoveryLimiter
+			}()
+		case <-ch.catchShutdown():
+			return fmt.Errorf("connection recovery failed: %w", ch.shutdownErr())
+		case <-ctx.Done():
+			return fmt.Errorf("connection recovery failed: %w", ctx.Err())
+		}
+	}
+	return ch.connect(ctx)
+}
+
 func (c *Connection) channel() (*amqp.Channel, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	return c.conn.Channel()
+	return c.openChannel()
+}
+
+// OpenChannel opens a raw amqp channel on the underlying connection for operations that are not supported by Session.
+// The caller owns the channel and must close it. In contrast to sessions, the channel is not recovered:
+// it is closed as soon as the connection is lost or recovered, in which case a new channel must be opened.
+// Channels cannot be opened on flagged connections, which must be recovered first.
+func (c *Connection) OpenChannel() (*amqp.Channel, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.flagged {
+		return nil, fmt.Errorf("failed to open channel: %w: connection is flagged", ErrConnectionFailed)
+	}
+	channel, err := c.openChannel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open channel: %w", err)
+	}
+	return channel, nil
+}
+
+// not threadsafe
+func (c *Connection) openChannel() (*amqp.Channel, error) {
+	if c.isClosed() {
+		return nil, fmt.Errorf("%w: connection is not open", ErrConnectionFailed)
+	}
+	channel, err := c.conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+	c.channelsOpened.Add(1)
+	return channel, nil
 }
 
-// IsCached returns true in case this session is supposed to be returned to a session pool.
+// ChannelsOpened returns the number of channels that were opened on this connection by sessions and OpenChannel.
+func (c *Connection) ChannelsOpened() uint64 {
+	return c.channelsOpened.Load()
+}
+
+// IsCached returns true in case this connection is supposed to be returned to a connection pool, see ConnectionWithCached.
+// The cached state is set upon creation and does not change during recoveries.
 func (c *Connection) IsCached() bool {
 	return c.cached
 }
 
 // Name returns the name of the connection
-func (c *Connection) Name() string {
-	return c.name
+func (ch *Connection) Name() string {
+	name, _ := ch.name.Load().(string)
+	return name
+}
+
+// SetName changes the name of the connection.
+// The new name is sent to the broker upon the next (re)connect,
+// e.g. after the connection was recovered.
+func (ch *Connection) SetName(name string) {
+	ch.name.Store(name)
+}
+
+// SetLogger replaces the logger of the connection, which is used by all subsequent log messages,
+// e.g. of the next recovery.
+func (ch *Connection) SetLogger(logger logging.Logger) {
+	ch.settingsMu.Lock()
+	defer ch.settingsMu.Unlock()
+	ch.log = logger
+}
+
+// SetBackoffPolicy replaces the backoff policy of the connection, which is used by all subsequent recoveries
+// and by the next retry of a recovery that is in progress.
+func (ch *Connection) SetBackoffPolicy(policy BackoffFunc) {
+	ch.settingsMu.Lock()
+	defer ch.settingsMu.Unlock()
+	ch.errorBackoff = policy
+}
+
+// SetTLSConfig replaces the tls config of the connection, e.g. in order to rotate client certificates.
+// The config is used by the next (re)connect, the current connection is not closed.
+// Whether tls is used at all is determined by the connect url upon creation, see ConnectionWithTLS.
+func (ch *Connection) SetTLSConfig(config *tls.Config) {
+	config = withSessionCache(config)
+
+	ch.settingsMu.Lock()
+	defer ch.settingsMu.Unlock()
+	ch.tls = config
+}
+
+func (ch *Connection) logger() logging.Logger {
+	ch.settingsMu.RLock()
+	defer ch.settingsMu.RUnlock()
+	return ch.log
+}
+
+// backoff returns the backoff duration of the passed retry.
+// The policy is called while the connection lock is held during recoveries, which is why a panicking
+// policy is recovered from and replaced by the default policy.
+func (ch *Connection) backoff(retry int) (sleep time.Duration) {
+	ch.settingsMu.RLock()
+	policy, log := ch.errorBackoff, ch.log
+	ch.settingsMu.RUnlock()
+
+	err := callSafe(log, "connection backoff policy", ErrCallbackPanic, func() error {
+		sleep = policy(retry)
+		return nil
+	})
+	if err != nil {
+		return newDefaultBackoffPolicy(time.Second, 15*time.Second)(retry)
+	}
+	return sleep
+}
+
+func (ch *Connection) tlsConfig() *tls.Config {
+	ch.settingsMu.RLock()
+	defer ch.settingsMu.RUnlock()
+	return ch.tls
 }
 
 func (ch *Connection) catchShutdown() <-chan struct{} {
@@ -376,8 +1067,8 @@ func (ch *Connection) shutdownErr() error {
 }
 
 func (ch *Connection) clog() logging.Logger {
-	return ch.log.WithFields(map[string]any{
-		"connection": ch.name,
+	return ch.logger().WithFields(map[string]any{
+		"connection": ch.Name(),
 		"address":    ch.addr,
 	})
 }
@@ -0,0 +1,68 @@
+package pool
+
+import (
+	"net/url"
+	"time"
+)
+
+// ConnectionInfo is a diagnostic snapshot of a connection, see ConnectionPool.Snapshot.
+type ConnectionInfo struct {
+	// ID is the id of the connection within its pool.
+	ID int
+	// Name is the name of the connection.
+	Name  string
+	State ConnectionState
+	// Flagged is only set in case the connection was not busy while the snapshot was taken.
+	// Busy connections that are flagged are in the recovering state.
+	Flagged bool
+	Blocked bool
+	// ConnectedAt is the point in time at which the underlying connection was established, see Connection.ConnectedAt.
+	ConnectedAt time.Time
+	Age         time.Duration
+	// ChannelsOpened is the number of channels that were opened on the connection.
+	ChannelsOpened uint64
+	// RemoteAddr is the address of the broker that the connection was connected to most recently.
+	RemoteAddr string
+	// BrokerURL is the url of the broker that the connection was connected to most recently, without password.
+	BrokerURL string
+	// LastError is the message of the latest error of the connection, e.g. the reason for its latest connection loss.
+	LastError string
+}
+
+// snapshot collects the diagnostic information of the connection without blocking.
+func (ch *Connection) snapshot(id int, now time.Time) ConnectionInfo {
+	info := ConnectionInfo{
+		ID:             id,
+		Name:           ch.Name(),
+		State:          ch.State(),
+		Blocked:        ch.blocked.Load(),
+		ConnectedAt:    ch.ConnectedAt(),
+		Age:            ch.age(now),
+		ChannelsOpened: ch.channelsOpened.Load(),
+	}
+	info.RemoteAddr, _ = ch.remoteAddr.Load().(string)
+	info.LastError, _ = ch.lastErr.Load().(string)
+	if brokerURL, ok := ch.brokerURL.Load().(string); ok {
+		info.BrokerURL = redactURL(brokerURL)
+	}
+
+	// the connection lock is held during recoveries, which must not block the snapshot
+	if ch.mu.TryLock() {
+		info.Flagged = ch.flagged
+		ch.mu.Unlock()
+	}
+	return info
+}
+
+func (ch *Connection) setLastError(err error) {
+	ch.lastErr.Store(err.Error())
+}
+
+// redactURL removes the password from the passed url.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Redacted()
+}
@@ -3,25 +3,46 @@ package pool
 import (
 	"context"
 	"crypto/tls"
+	"sync/atomic"
 	"time"
 
 	"github.com/jxsl13/amqpx/logging"
 )
 
 type connectionOption struct {
-	Logger            logging.Logger
-	Cached            bool
-	HeartbeatInterval time.Duration
-	ConnectionTimeout time.Duration
-	BackoffPolicy     BackoffFunc
-	Ctx               context.Context
-	TLSConfig         *tls.Config
-	RecoverCallback   ConnectionRecoverCallback
+	Logger             logging.Logger
+	Cached             bool
+	HeartbeatInterval  time.Duration
+	ConnectionTimeout  time.Duration
+	TCPConnectTimeout  time.Duration
+	InitialDialTimeout time.Duration
+	IOTimeout          time.Duration
+	BackoffPolicy      BackoffFunc
+	Ctx                context.Context
+	TLSConfig          *tls.Config
+	TLSServerName      string
+	AddressFamily      string
+	ExternalAuth       bool
+	PlainAuthFallback  bool
+	FailoverURLs       []string
+	Properties         Table
+	RecoverCallback    ConnectionRecoverCallback
+	BlockedCallback    ConnectionBlockedCallback
+	MaxBackoffCallback ConnectionMaxBackoffCallback
+
+	// counts successful recoveries, shared by all connections of a pool
+	recoveries *atomic.Uint64
+	// limits the number of concurrent reconnects, shared by all connections of a pool
+	recoveryLimiter chan struct{}
+	// limits the number of concurrent recoveries, shared by all connections of a pool
+	recoveryGate *recoveryGate
+	// called once when the connection is closed
+	closeCallback func()
 }
 
 type ConnectionOption func(*connectionOption)
 
-// ConnectionWithLogger allows to set a logger. By default no logger is set.
+// ConnectionWithLogger allows to set a logger. By default the logger of SetDefaultLogger is used.
 func ConnectionWithLogger(logger logging.Logger) ConnectionOption {
 	return func(co *connectionOption) {
 		co.Logger = logger
@@ -57,7 +78,51 @@ func ConnectionWithTimeout(timeout time.Duration) ConnectionOption {
 	}
 }
 
+// ConnectionWithTCPConnectTimeout bounds establishing the TCP connection separately from the connection timeout,
+// which bounds the TLS and AMQP handshakes. A short TCP connect timeout skips unreachable brokers quickly,
+// e.g. in order to fail over to the next url, while slow but reachable brokers may still complete the handshake.
+// A timeout <= 0 bounds the TCP connect by the connection timeout (default).
+func ConnectionWithTCPConnectTimeout(timeout time.Duration) ConnectionOption {
+	return func(co *connectionOption) {
+		co.TCPConnectTimeout = timeout
+	}
+}
+
+// ConnectionWithInitialDialTimeout bounds the initial connect of NewConnection including its retries,
+// which allows to fail fast upon startup, e.g. in case the broker is not reachable.
+// In contrast to ConnectionWithTimeout, which bounds every single dial, it does not affect later recoveries.
+// A timeout <= 0 retries the initial connect until the context of the connection is done (default).
+func ConnectionWithInitialDialTimeout(timeout time.Duration) ConnectionOption {
+	return func(co *connectionOption) {
+		co.InitialDialTimeout = timeout
+	}
+}
+
+// ConnectionWithIOTimeout sets a deadline for every read and write on the socket of the connection,
+// which detects stalled (half-open) sockets, e.g. while publishing, faster than missed heartbeats.
+// A write fails in case it does not complete within the timeout. A read only fails in case no data was received
+// within the timeout after something was written, so that idle connections are not closed.
+// As the broker does not respond to every frame, e.g. publishes without confirms, the timeout should be
+// larger than the heartbeat interval in order to prevent false positives.
+// A timeout <= 0 disables the deadlines (default).
+func ConnectionWithIOTimeout(timeout time.Duration) ConnectionOption {
+	return func(co *connectionOption) {
+		co.IOTimeout = timeout
+	}
+}
+
+// ConnectionWithProperties sets the client properties that are advertised to the broker whenever the connection
+// is (re)established, e.g. product information, which is shown in the management UI.
+// The property "connection_name" is always set to the name of the connection and the property "capabilities"
+// is always set by the underlying amqp library.
+func ConnectionWithProperties(properties Table) ConnectionOption {
+	return func(co *connectionOption) {
+		co.Properties = copyTable(properties)
+	}
+}
+
 // ConnectionWithBackoffPolicy influences the sleep interval between connection recovery retries.
+// In case the policy panics, the default policy is used for that retry.
 func ConnectionWithBackoffPolicy(policy BackoffFunc) ConnectionOption {
 	return func(co *connectionOption) {
 		co.BackoffPolicy = policy
@@ -65,15 +130,115 @@ func ConnectionWithBackoffPolicy(policy BackoffFunc) ConnectionOption {
 }
 
 // ConnectionWithTLS allows to configure tls connectivity.
+// A client session cache is added to a clone of the config in case it has none, which allows to resume tls sessions
+// when reconnecting. Set SessionTicketsDisabled in order to disable session resumption.
 func ConnectionWithTLS(config *tls.Config) ConnectionOption {
 	return func(co *connectionOption) {
 		co.TLSConfig = config
 	}
 }
 
+// ConnectionWithServerName allows to override the server name that is used to verify the broker's certificate.
+// This is necessary in case the broker is reachable via a host name that differs from the one in its certificate,
+// e.g. when connecting via a TLS terminating load balancer.
+// The tls config passed via ConnectionWithTLS is cloned and not modified.
+// Setting a server name implies the usage of tls.
+func ConnectionWithServerName(serverName string) ConnectionOption {
+	return func(co *connectionOption) {
+		co.TLSServerName = serverName
+	}
+}
+
 // ConnectionWithRecoverCallback allows to set a custom recover callback.
 func ConnectionWithRecoverCallback(callback ConnectionRecoverCallback) ConnectionOption {
 	return func(co *connectionOption) {
 		co.RecoverCallback = callback
 	}
 }
+
+// ConnectionWithBlockedCallback allows to be notified when the broker starts or stops blocking the connection.
+// The callback is only called when the blocked state changes and must not block.
+func ConnectionWithBlockedCallback(callback ConnectionBlockedCallback) ConnectionOption {
+	return func(co *connectionOption) {
+		co.BlockedCallback = callback
+	}
+}
+
+// ConnectionWithMaxBackoffCallback allows to be notified when the recovery backoff of the connection reaches its maximum
+// and when the connection was recovered afterwards, which allows to alert on prolonged outages.
+// The backoff is considered to be at its maximum as soon as the backoff policy returns a sleep interval for the next retry
+// that is not longer than the one of the current retry, e.g. 15s for the default policy.
+func ConnectionWithMaxBackoffCallback(callback ConnectionMaxBackoffCallback) ConnectionOption {
+	return func(co *connectionOption) {
+		co.MaxBackoffCallback = callback
+	}
+}
+
+// ConnectionWithAddressFamily forces the usage of a specific network when dialing the broker.
+// Supported values are "tcp" (default, dual-stack), "tcp4" (IPv4 only) and "tcp6" (IPv6 only).
+// Any other value causes the connection creation to fail with ErrInvalidAddressFamily.
+func ConnectionWithAddressFamily(network string) ConnectionOption {
+	return func(co *connectionOption) {
+		co.AddressFamily = network
+	}
+}
+
+// ConnectionWithExternalAuth authenticates with the SASL EXTERNAL mechanism, e.g. by using the client certificate of the
+// tls config, instead of the credentials of the connection url.
+// In case fallbackToPlain is set and a broker rejects EXTERNAL authentication, the connection retries the broker once
+// with PLAIN authentication and the credentials of the connection url, which allows to use a single configuration
+// for brokers that do and do not support EXTERNAL authentication, e.g. during a migration.
+// In case the PLAIN authentication is rejected as well, the connection fails with ErrAccessRefused.
+func ConnectionWithExternalAuth(fallbackToPlain bool) ConnectionOption {
+	return func(co *connectionOption) {
+		co.ExternalAuth = true
+		co.PlainAuthFallback = fallbackToPlain
+	}
+}
+
+// ConnectionWithFailoverURLs allows to connect to other brokers in case the broker of the connection url is not reachable.
+// Every (re)connect attempt prefers the broker of the connection url and tries the failover urls in the given order
+// only if that broker is not reachable, which is why a connection returns to its preferred broker upon its next recovery.
+func ConnectionWithFailoverURLs(urls ...string) ConnectionOption {
+	return func(co *connectionOption) {
+		co.FailoverURLs = urls
+	}
+}
+
+// connectionWithContext overrides the context that bounds the lifetime of the connection,
+// which defaults to the context that is passed to NewConnection and that bounds the initial connect.
+func connectionWithContext(ctx context.Context) ConnectionOption {
+	return func(co *connectionOption) {
+		co.Ctx = ctx
+	}
+}
+
+// connectionWithRecoveryLimiter limits the number of connections that reconnect concurrently during their recovery
+// to the capacity of the passed channel, which must be shared by all connections that are limited together.
+func connectionWithRecoveryLimiter(limiter chan struct{}) ConnectionOption {
+	return func(co *connectionOption) {
+		co.recoveryLimiter = limiter
+	}
+}
+
+// connectionWithRecoveryGate limits the number of connections that are in their recovery loop concurrently,
+// the gate must be shared by all connections that are limited together.
+func connectionWithRecoveryGate(gate *recoveryGate) ConnectionOption {
+	return func(co *connectionOption) {
+		co.recoveryGate = gate
+	}
+}
+
+// connectionWithCloseCallback registers a callback that is called once when the connection is closed.
+func connectionWithCloseCallback(callback func()) ConnectionOption {
+	return func(co *connectionOption) {
+		co.closeCallback = callback
+	}
+}
+
+// connectionWithRecoveryCounter increments the passed counter whenever the connection was recovered.
+func connectionWithRecoveryCounter(counter *atomic.Uint64) ConnectionOption {
+	return func(co *connectionOption) {
+		co.recoveries = counter
+	}
+}
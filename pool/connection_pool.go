@@ -3,9 +3,10 @@ package pool
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
-	"net/url"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jxsl13/amqpx/logging"
@@ -21,23 +22,75 @@ type ConnectionPool struct {
 
 	heartbeat   time.Duration
 	connTimeout time.Duration
+	ioTimeout   time.Duration
 
+	tcpConnectTimeout time.Duration
+
+	// cached connections that are older are reconnected upon GetConnection, 0 is disabled
+	maxLifetime time.Duration
+
+	// number of cached connections, guarded by mu as it is changed by Resize
 	capacity int
 
-	tls *tls.Config
+	tlsServerName string
+	addressFamily string
+	properties    Table
+
+	// authenticate with SASL EXTERNAL and optionally fall back to PLAIN
+	externalAuth      bool
+	plainAuthFallback bool
+
+	// brokers that the cached connections are distributed across and the broker index of each cached connection
+	brokers          []WeightedURL
+	brokerAssignment []int
+
+	ctx         context.Context
+	cancel      context.CancelFunc
+	cancelCause context.CancelCauseFunc
+
+	// settings that can be replaced at runtime and are passed to all connections, guarded by settingsMu
+	settingsMu sync.RWMutex
+	tls        *tls.Config // shared by all connections of the pool
+	backoff    BackoffFunc // nil in case the default policy of the connections is used
+	log        logging.Logger
+
+	recoverCB    ConnectionRecoverCallback
+	maxBackoffCB ConnectionMaxBackoffCallback
+	blocked      *blockedAggregate
+
+	metrics      MetricsCollector
+	acquisitions acquisitionCounter
+	recoveries   atomic.Uint64
+	// GetConnection calls that block longer than this are logged, 0 is disabled
+	slowAcquisition time.Duration
+	// limits the number of concurrently reconnecting connections, nil in case reconnects are not limited
+	recoveryLimiter chan struct{}
+	// limits and counts the concurrently recovering connections
+	recoveryGate *recoveryGate
+
+	// idle cached connections, guarded by mu as the queue is replaced by Resize
+	connections chan *Connection
 
-	ctx    context.Context
-	cancel context.CancelFunc
+	mu         sync.Mutex
+	transients transientIDs
+	// all cached connections, whether idle or in use
+	cached []*Connection
+	// id of the next cached connection that is added by Resize
+	nextCachedID int64
 
-	log logging.Logger
+	// serializes Resize calls
+	resizeMu sync.Mutex
 
-	recoverCB ConnectionRecoverCallback
+	// settings that were used to create this pool, required for cloning.
+	option connectionPoolOption
 
-	connections chan *Connection
+	// session pools that are closed before the connections of this pool are closed
+	dependents []*SessionPool
+	// set by Close, session pools that are registered afterwards are closed right away
+	dependentsClosed bool
 
-	mu                  sync.Mutex
-	transientID         int64
-	concurrentTransient int
+	// background goroutines that must have terminated when Close returns
+	wg sync.WaitGroup
 }
 
 // NewConnectionPool creates a new connection pool which has a maximum size it
@@ -58,7 +111,7 @@ func NewConnectionPool(ctx context.Context, connectUrl string, numConns int, opt
 		ConnTimeout:           30 * time.Second,
 		TLSConfig:             nil,
 
-		Logger: logging.NewNoOpLogger(),
+		Logger: defaultLogger(),
 
 		ConnectionRecoverCallback: nil,
 	}
@@ -72,13 +125,19 @@ func NewConnectionPool(ctx context.Context, connectUrl string, numConns int, opt
 }
 
 func newConnectionPoolFromOption(connectUrl string, option connectionPoolOption) (_ *ConnectionPool, err error) {
-	u, err := url.ParseRequestURI(connectUrl)
+	if option.Capacity < 1 {
+		return nil, fmt.Errorf("%w: %d", errInvalidPoolSize, option.Capacity)
+	}
+
+	tlsEnabled := option.TLSConfig != nil || option.TLSServerName != ""
+	u, err := parseURL(connectUrl, tlsEnabled)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrInvalidConnectURL, err)
+		return nil, err
 	}
 
-	if option.TLSConfig != nil {
-		u.Scheme = "amqps"
+	brokers, err := parseBrokerWeights(option.BrokerWeights, tlsEnabled)
+	if err != nil {
+		return nil, err
 	}
 
 	// decouple from parent context, in case we want to close this context ourselves.
@@ -91,20 +150,56 @@ func newConnectionPoolFromOption(connectUrl string, option connectionPoolOption)
 
 		heartbeat:   option.ConnHeartbeatInterval,
 		connTimeout: option.ConnTimeout,
+		ioTimeout:   option.ConnIOTimeout,
+
+		tcpConnectTimeout: option.ConnTCPConnectTimeout,
+		maxLifetime:       option.MaxLifetime,
+
+		capacity:      option.Capacity,
+		nextCachedID:  int64(option.Capacity),
+		tls:           withSessionCache(option.TLSConfig), // shared by all connections of the pool
+		tlsServerName: option.TLSServerName,
+		addressFamily: option.AddressFamily,
+		properties:    option.ConnProperties,
+		connections:   make(chan *Connection, option.Capacity),
+		transients:    transientIDs{strategy: option.TransientIDStrategy},
+
+		externalAuth:      option.ExternalAuth,
+		plainAuthFallback: option.PlainAuthFallback,
 
-		capacity:    option.Capacity,
-		tls:         option.TLSConfig,
-		connections: make(chan *Connection, option.Capacity),
+		brokers:          brokers,
+		brokerAssignment: distributeByWeight(option.Capacity, brokers),
 
-		ctx:    ctx,
-		cancel: cancel,
+		ctx:         ctx,
+		cancel:      cancel,
+		cancelCause: cc,
 
 		log: option.Logger,
 
-		recoverCB: option.ConnectionRecoverCallback,
+		recoverCB:    option.ConnectionRecoverCallback,
+		maxBackoffCB: option.MaxBackoffCallback,
+		blocked:      newBlockedAggregate(option.Name, option.OnBlocked, option.OnUnblocked),
+
+		metrics:         option.MetricsCollector,
+		slowAcquisition: option.SlowAcquisition,
+		recoveryGate:    newRecoveryGate(option.MaxRecoveries),
+
+		option: option,
+	}
+
+	if option.SerialRecovery {
+		cp.recoveryLimiter = make(chan struct{}, 1)
+	}
+
+	cp.name, err = registerPoolName(cp, option.Name, option.NameUniqueness)
+	if err != nil {
+		cancel()
+		return nil, err
 	}
+	cp.blocked.poolName = cp.name
 
-	cp.debug("initializing pool connections...")
+	// never log credentials
+	cp.logger().WithField("connectionPool", cp.name).WithField("url", u.Redacted()).Debug("initializing pool connections...")
 	defer func() {
 		if err != nil {
 			cp.error(err, "failed to initialize pool connections")
@@ -115,18 +210,161 @@ func newConnectionPoolFromOption(connectUrl string, option connectionPoolOption)
 
 	err = cp.initCachedConns()
 	if err != nil {
+		unregisterPoolName(cp)
 		return nil, err
 	}
 
+	if option.ShutdownHook != nil {
+		go cp.awaitShutdown(option.ShutdownHook)
+	}
+
+	if option.LivenessInterval > 0 {
+		cp.wg.Add(1)
+		go cp.checkLiveness(option.LivenessInterval, true)
+	}
+
+	if option.HealthCheckInterval > 0 {
+		cp.wg.Add(1)
+		go cp.checkLiveness(option.HealthCheckInterval, false)
+	}
+
+	if option.IdleTimeout > 0 {
+		cp.wg.Add(1)
+		go cp.reapIdle(option.IdleTimeout, option.MinIdle)
+	}
+
 	return cp, nil
 }
 
+// Clone creates a new connection pool with the same settings as this pool.
+// The passed options override the settings of this pool, e.g. ConnectionPoolWithCapacity.
+// The cloned pool has its own connections and its own context which is derived from the
+// parent context that was passed to NewConnectionPool, so closing either pool does not affect the other.
+func (cp *ConnectionPool) Clone(options ...ConnectionPoolOption) (*ConnectionPool, error) {
+	option := cp.option
+	for _, o := range options {
+		o(&option)
+	}
+	return newConnectionPoolFromOption(cp.url, option)
+}
+
+// LinkContext links an additional context to the connection pool.
+// The pool is shut down as soon as either the parent context that was passed to NewConnectionPool
+// or any of the linked contexts is done.
+// The cause of the pool shutdown wraps ErrClosed as well as the cause of the linked context.
+func (cp *ConnectionPool) LinkContext(ctx context.Context) {
+	go func() {
+		select {
+		case <-ctx.Done():
+			cp.cancelCause(fmt.Errorf("connection pool %w: linked context done: %w", ErrClosed, context.Cause(ctx)))
+		case <-cp.catchShutdown():
+			// pool already closed, stop watching the linked context
+		}
+	}()
+}
+
+// awaitShutdown blocks until the pool context is canceled and then
+// calls the hook with the cause of the shutdown.
+func (cp *ConnectionPool) awaitShutdown(hook func(cause error)) {
+	<-cp.catchShutdown()
+	cause := context.Cause(cp.ctx)
+	_ = callSafe(cp.logger(), "connection pool shutdown hook", ErrCallbackPanic, func() error {
+		hook(cause)
+		return nil
+	})
+}
+
+// checkLiveness periodically pings all idle cached connections until the pool is closed.
+// Dead connections are recovered in case recoverDead is set, otherwise they are only flagged.
+func (cp *ConnectionPool) checkLiveness(interval time.Duration, recoverDead bool) {
+	defer cp.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-cp.catchShutdown():
+			return
+		case <-ticker.C:
+			// every connection that is idle at the beginning of the round is checked at most once,
+			// as returned connections are appended to the end of the queue.
+			for i, n := 0, cp.Size(); i < n; i++ {
+				if !cp.checkIdleConnection(interval, recoverDead) {
+					break
+				}
+			}
+		}
+	}
+}
+
+// checkIdleConnection borrows the next idle connection, pings it and puts it back.
+// Dead connections are flagged and, in case recoverDead is set, recovered, which is bounded by the timeout
+// in order not to hold the connection for too long. It returns false in case there was no idle connection
+// or the pool was closed.
+func (cp *ConnectionPool) checkIdleConnection(timeout time.Duration, recoverDead bool) bool {
+	var conn *Connection
+	select {
+	case <-cp.catchShutdown():
+		return false
+	case c, ok := <-cp.queue():
+		if !ok {
+			// the queue was replaced by Resize
+			return false
+		}
+		conn = c
+	default:
+		// all connections are in use, never block real checkouts
+		return false
+	}
+
+	if conn.isReleased() {
+		// released connections are reopened on demand
+		cp.requeue(conn)
+		return true
+	}
+
+	if !recoverDead {
+		err := conn.Ping(cp.ctx)
+		if err != nil && cp.ctx.Err() == nil {
+			cp.debug(fmt.Sprintf("health check of connection %s failed: %v", conn.Name(), err))
+			// recovered by the next GetConnection call
+			conn.Flag(err)
+		}
+		cp.requeue(conn)
+		return cp.ctx.Err() == nil
+	}
+
+	err := conn.ping()
+	if err == nil {
+		cp.requeue(conn)
+		return true
+	}
+
+	cp.debug(fmt.Sprintf("liveness check of connection %s failed: %v", conn.Name(), err))
+
+	ctx, cancel := context.WithTimeout(cp.ctx, timeout)
+	defer cancel()
+
+	err = cp.ReturnConnectionSync(ctx, conn, err)
+	if err != nil {
+		cp.error(err, fmt.Sprintf("failed to recover connection %s after failed liveness check", conn.Name()))
+	}
+	return cp.ctx.Err() == nil
+}
+
 func (cp *ConnectionPool) initCachedConns() error {
 	for id := int64(0); id < int64(cp.capacity); id++ {
-		conn, err := cp.deriveConnection(cp.ctx, id, true)
+		conn, err := cp.deriveConnection(cp.ctx, id, true, "",
+			ConnectionWithInitialDialTimeout(cp.option.InitialDialTimeout),
+		)
 		if err != nil {
-			return fmt.Errorf("%w: %v", ErrPoolInitializationFailed, err)
+			// keep typed errors, e.g. ErrConnectionLimitReached, which point at a misconfiguration
+			return fmt.Errorf("%w: %w", ErrPoolInitializationFailed, err)
 		}
+		cp.mu.Lock()
+		cp.cached = append(cp.cached, conn)
+		cp.mu.Unlock()
 
 		select {
 		case cp.connections <- conn:
@@ -141,104 +379,300 @@ func (cp *ConnectionPool) initCachedConns() error {
 	return nil
 }
 
-func (cp *ConnectionPool) deriveConnection(ctx context.Context, id int64, cached bool) (*Connection, error) {
+// deriveConnection creates a new connection of the pool.
+// A non-empty vhost overrides the vhost of the connect url and of all failover urls.
+func (cp *ConnectionPool) deriveConnection(ctx context.Context, id int64, cached bool, vhost string, options ...ConnectionOption) (*Connection, error) {
 	var name string
 	if cached {
 		name = fmt.Sprintf("%s-cached-connection-%d", cp.name, id)
 	} else {
 		name = fmt.Sprintf("%s-transient-connection-%d", cp.name, id)
 	}
-	return NewConnection(ctx, cp.url, name,
+	connectURL, failover := cp.brokerURLs(id, cached)
+	if vhost != "" {
+		urls, err := withVHost(vhost, append([]string{connectURL}, failover...)...)
+		if err != nil {
+			return nil, err
+		}
+		connectURL, failover = urls[0], urls[1:]
+	}
+	cp.settingsMu.RLock()
+	tlsConfig, backoff, log := cp.tls, cp.backoff, cp.log
+	cp.settingsMu.RUnlock()
+
+	options = append([]ConnectionOption{
+		ConnectionWithFailoverURLs(failover...),
 		ConnectionWithTimeout(cp.connTimeout),
+		ConnectionWithTCPConnectTimeout(cp.tcpConnectTimeout),
+		ConnectionWithIOTimeout(cp.ioTimeout),
 		ConnectionWithHeartbeatInterval(cp.heartbeat),
-		ConnectionWithTLS(cp.tls),
+		ConnectionWithTLS(tlsConfig),
+		ConnectionWithServerName(cp.tlsServerName),
+		ConnectionWithAddressFamily(cp.addressFamily),
+		ConnectionWithProperties(cp.properties),
 		ConnectionWithCached(cached),
-		ConnectionWithLogger(cp.log),
+		ConnectionWithLogger(log),
 		ConnectionWithRecoverCallback(cp.recoverCB),
-	)
+		ConnectionWithMaxBackoffCallback(cp.maxBackoffCB),
+		ConnectionWithBlockedCallback(cp.blocked.update),
+		connectionWithRecoveryCounter(&cp.recoveries),
+		connectionWithRecoveryLimiter(cp.recoveryLimiter),
+		connectionWithRecoveryGate(cp.recoveryGate),
+	}, options...)
+	if backoff != nil {
+		options = append(options, ConnectionWithBackoffPolicy(backoff))
+	}
+	if cp.externalAuth {
+		options = append(options, ConnectionWithExternalAuth(cp.plainAuthFallback))
+	}
+	return NewConnection(ctx, connectURL, name, options...)
+}
+
+// brokerURLs returns the url of the preferred broker of a connection and the urls of the failover brokers.
+func (cp *ConnectionPool) brokerURLs(id int64, cached bool) (string, []string) {
+	if len(cp.brokers) == 0 {
+		return cp.url, nil
+	}
+	if !cached {
+		return cp.url, failoverURLs(cp.brokers, -1)
+	}
+	idx := cp.brokerAssignment[id]
+	return cp.brokers[idx].URL, failoverURLs(cp.brokers, idx)
 }
 
 // GetConnection only returns an error upon shutdown
 func (cp *ConnectionPool) GetConnection(ctx context.Context) (conn *Connection, err error) {
-	select {
-	case conn, ok := <-cp.connections:
-		if !ok {
-			return nil, fmt.Errorf("connection pool %w", ErrClosed)
-		}
+	start := time.Now()
+	done := cp.watchAcquisition()
+	defer func() {
+		done()
+		cp.observeAcquisition(AcquisitionPathCached, start, err)
+	}()
 
-		// recovery may fail, that's why we MUST check for errors
-		// and return the connection back to the pool in case that the recovery failed
-		// due to e.g. the pool being closed, the context being canceled, etc.
-		defer func() {
+	for {
+		select {
+		case conn, ok := <-cp.queue():
+			if !ok {
+				// the queue was replaced by Resize, wait for the new one
+				continue
+			}
+
+			// recovery may fail, that's why we MUST check for errors
+			// and return the connection back to the pool in case that the recovery failed
+			// due to e.g. the pool being closed, the context being canceled, etc.
+			defer func() {
+				if err != nil {
+					cp.ReturnConnection(conn, err)
+				}
+			}()
+
+			select {
+			case <-cp.catchShutdown():
+				// Close may already be closing the connection, returning it closes it as well
+				err = fmt.Errorf("connection pool %w", ErrClosed)
+				return nil, err
+			default:
+			}
+
+			err = conn.reopen(ctx)
 			if err != nil {
-				cp.ReturnConnection(conn, err)
+				// flagged, recovered right away
+				cp.debug(err.Error())
 			}
-		}()
 
-		err = conn.Recover(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get connection: %w", err)
-		}
+			if cp.maxLifetime > 0 {
+				// a failed reconnect flags the connection, which is recovered right away
+				_, _ = conn.recycle(ctx, cp.maxLifetime)
+			}
 
-		return conn, nil
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	case <-cp.catchShutdown():
-		return nil, fmt.Errorf("connection pool %w", ErrClosed)
+			err = conn.recoverUrgently(ctx)
+			if err != nil {
+				if cp.ctx.Err() != nil {
+					// the recovery was canceled by Close
+					err = fmt.Errorf("connection pool %w", ErrClosed)
+					return nil, err
+				}
+				return nil, fmt.Errorf("failed to get connection: %w", err)
+			}
+
+			return conn, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-cp.catchShutdown():
+			return nil, fmt.Errorf("connection pool %w", ErrClosed)
+		}
 	}
 }
 
-func (cp *ConnectionPool) nextTransientID() int64 {
+func (cp *ConnectionPool) acquireTransientID() int64 {
 	cp.mu.Lock()
 	defer cp.mu.Unlock()
-	cp.transientID++
-	return cp.transientID
+	return cp.transients.acquire()
+}
+
+func (cp *ConnectionPool) releaseTransientID(id int64) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	cp.transients.release(id)
 }
 
 // GetTransientConnection may return an error when the context was cancelled before the connection could be obtained.
 // Transient connections may be returned to the pool. The are closed properly upon returning.
 func (cp *ConnectionPool) GetTransientConnection(ctx context.Context) (conn *Connection, err error) {
-	conn, err = cp.deriveConnection(ctx, cp.nextTransientID(), false)
-	if err == nil {
-		return conn, nil
+	return cp.getTransientConnection(ctx, "")
+}
+
+// GetTransientConnectionForVHost returns a transient connection to the passed vhost instead of the vhost of the connect url.
+// All other settings of the pool, e.g. credentials, TLS and failover brokers, are used as is.
+// Connections to a vhost that does not exist or that the user is not allowed to access are retried until the context is done.
+func (cp *ConnectionPool) GetTransientConnectionForVHost(ctx context.Context, vhost string) (conn *Connection, err error) {
+	if vhost == "" {
+		return nil, fmt.Errorf("%w: empty vhost", ErrInvalidConnectURL)
 	}
+	return cp.getTransientConnection(ctx, vhost)
+}
+
+func (cp *ConnectionPool) getTransientConnection(ctx context.Context, vhost string) (conn *Connection, err error) {
+	start := time.Now()
 	defer func() {
-		if err != nil {
-			_ = conn.Close()
-		}
+		cp.observeAcquisition(AcquisitionPathTransient, start, err)
 	}()
 
-	// recover until context is closed
-	err = conn.Recover(ctx)
+	// the id is released as soon as the transient connection is closed
+	id := cp.acquireTransientID()
+	release := func() { cp.releaseTransientID(id) }
+
+	// recovers until context is closed
+	conn, err = cp.deriveConnection(ctx, id, false, vhost, connectionWithCloseCallback(release))
 	if err != nil {
+		release()
 		return nil, fmt.Errorf("failed to get transient connection: %w", err)
 	}
 
+	// the connection context is derived from ctx, which only stops the recovery of the connection
+	// but does not close the connection to the broker.
+	go closeOnCancel(ctx, conn.ctx, conn.Close)
 	return conn, nil
 }
 
-// ReturnConnection puts the connection back in the queue and flag it for error.
+func (cp *ConnectionPool) observeAcquisition(path AcquisitionPath, start time.Time, err error) {
+	cp.acquisitions.observe(path, err)
+	if cp.metrics != nil {
+		cp.metrics.ConnectionAcquired(cp.name, path, time.Since(start), err)
+	}
+}
+
+// watchAcquisition logs a warning in case a connection could not be acquired within the slow acquisition threshold.
+// The returned function must be called as soon as the acquisition finished.
+func (cp *ConnectionPool) watchAcquisition() (done func()) {
+	return watchdog(cp.slowAcquisition, func(elapsed time.Duration) {
+		cp.logger().WithFields(map[string]any{
+			"connectionPool": cp.name,
+			"waited":         elapsed.String(),
+		}).Warn(fmt.Sprintf("waited %s for a connection", elapsed.Round(time.Millisecond)))
+	})
+}
+
+// Use gets a connection from the pool, passes it to f and returns it to the pool afterwards.
+// The error returned by f is used to flag the connection, see ReturnConnection.
+// In case f panics, the connection is returned as flagged before the panic is propagated,
+// as the state of the connection is unknown at that point.
+func (cp *ConnectionPool) Use(ctx context.Context, f func(*Connection) error) (err error) {
+	conn, err := cp.GetConnection(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			cp.ReturnConnection(conn, fmt.Errorf("panic while using connection: %v", r))
+			panic(r)
+		}
+		cp.ReturnConnection(conn, err)
+	}()
+
+	return f(conn)
+}
+
+// ReturnConnection puts the connection back in the queue and flags it in case of a recoverable error.
 // This helps maintain a Round Robin on Connections and their resources.
-// If the connection is flagged, it will be recovered and returned to the pool.
-// If the context is canceled, the connection will be immediately returned to the pool
-// without any recovery attempt.
+// A flagged connection is not recovered here but by the next GetConnection call.
+// ReturnConnection is equivalent to ReturnConnectionFast.
+// Transient connections are closed.
 func (cp *ConnectionPool) ReturnConnection(conn *Connection, err error) {
+	cp.ReturnConnectionFast(conn, err)
+}
+
+// ReturnConnectionFast flags the connection in case of a recoverable error and puts it back
+// in the queue without any recovery attempt.
+// The recovery of a flagged connection is deferred to the next GetConnection call.
+// Transient connections are closed.
+func (cp *ConnectionPool) ReturnConnectionFast(conn *Connection, err error) {
 	// close transient connections
 	if !conn.IsCached() {
 		_ = conn.Close()
 		return
 	}
 	conn.Flag(err)
+	cp.putConnection(conn)
+}
+
+// ReturnConnectionSync flags the connection in case of a recoverable error and recovers it
+// before putting it back in the queue.
+// The connection is put back in the queue even if the recovery fails, in which case
+// the recovery error is returned and the next GetConnection call retries the recovery.
+// Transient connections are closed.
+func (cp *ConnectionPool) ReturnConnectionSync(ctx context.Context, conn *Connection, err error) error {
+	// close transient connections
+	if !conn.IsCached() {
+		_ = conn.Close()
+		return nil
+	}
+	defer cp.putConnection(conn)
+
+	conn.Flag(err)
+	return conn.Recover(ctx)
+}
+
+// putConnection puts a returned cached connection back into the queue, its idle time starts now.
+func (cp *ConnectionPool) putConnection(conn *Connection) {
+	conn.idleSince.Store(time.Now().UnixNano())
+	cp.requeue(conn)
+}
 
+// requeue puts a cached connection back into the queue without resetting its idle time.
+// Connections that are returned after the pool was closed are closed.
+// A full queue implies that the connection was returned more than once or that it does not belong to this pool,
+// which is logged instead of blocking or panicking. Connections of other pools are closed.
+func (cp *ConnectionPool) requeue(conn *Connection) {
+	select {
+	case <-cp.catchShutdown():
+		_ = conn.Close()
+		return
+	default:
+	}
+
+	cp.mu.Lock()
 	select {
 	case cp.connections <- conn:
+		cp.mu.Unlock()
+		return
 	default:
-		panic("connection pool connections buffer full: not supposed to happen")
 	}
+	cp.mu.Unlock()
+
+	for _, c := range cp.cachedConns() {
+		if c == conn {
+			cp.warn(fmt.Errorf("%w: connection %s", ErrConnectionReturned, conn.Name()), "connection was returned more than once")
+			return
+		}
+	}
+	cp.warn(fmt.Errorf("%w: connection %s", ErrForeignConnection, conn.Name()), "closing connection that does not belong to the pool")
+	_ = conn.Close()
 }
 
 // Close closes the connection pool.
 // Closes all connections and sessions that are currently known to the pool.
+// Cached connections that are currently in use are closed as well, Close does not wait for them to be returned.
 // Any new connections or session requests will return an error.
 // Any returned sessions or connections will be closed properly.
 func (cp *ConnectionPool) Close() {
@@ -246,44 +680,247 @@ func (cp *ConnectionPool) Close() {
 	cp.debug("closing connection pool...")
 	defer cp.info("closed")
 
-	wg := &sync.WaitGroup{}
-	wg.Add(cp.capacity)
+	// sessions must be closed before their underlying connections
+	cp.mu.Lock()
+	dependents := cp.dependents
+	cp.dependents = nil
+	cp.dependentsClosed = true
+	cp.mu.Unlock()
+
+	for _, sp := range dependents {
+		sp.close()
+	}
+
 	cp.cancel()
 
-	for i := 0; i < cp.capacity; i++ {
-		go func() {
+	// the liveness check may hold a connection, which is put back before it terminates
+	cp.wg.Wait()
+
+	// close all cached connections, whether they are idle or in use, as waiting for connections that are
+	// never returned would block forever. Returning a closed connection is safe.
+	wg := &sync.WaitGroup{}
+	for _, conn := range cp.cachedConns() {
+		wg.Add(1)
+		go func(conn *Connection) {
 			defer wg.Done()
-			conn := <-cp.connections
 			_ = conn.Close()
-		}()
+		}(conn)
 	}
 
 	wg.Wait()
+	unregisterPoolName(cp)
+}
+
+// RegisterDependent registers a session pool that is closed by Close before any connection of this pool is closed.
+// Closing the session pool explicitly before or after closing the connection pool is safe.
+// Closed session pools unregister themselves. Session pools that are registered after the connection pool
+// was closed are closed immediately.
+func (cp *ConnectionPool) RegisterDependent(sp *SessionPool) {
+	cp.mu.Lock()
+	if !cp.dependentsClosed {
+		cp.dependents = append(cp.dependents, sp)
+		cp.mu.Unlock()
+		return
+	}
+	cp.mu.Unlock()
+
+	sp.close()
+}
+
+// unregisterDependent removes the session pool from the session pools that are closed by Close.
+func (cp *ConnectionPool) unregisterDependent(sp *SessionPool) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	for i, dependent := range cp.dependents {
+		if dependent == sp {
+			cp.dependents = append(cp.dependents[:i], cp.dependents[i+1:]...)
+			return
+		}
+	}
+}
+
+// ConnectionPoolStats is a snapshot of the connection pool statistics.
+type ConnectionPoolStats struct {
+	// Capacity is the number of cached connections
+	Capacity int
+	// Size is the number of cached connections that are currently not in use
+	Size int
+	// CachedIdle is the number of cached connections that are currently not in use and connected to the broker
+	CachedIdle int
+	// TransientActive is the number of transient connections that are currently in use
+	TransientActive int
+	// Recoveries is the number of successful connection recoveries
+	Recoveries uint64
+	// RecoveriesInFlight is the number of connections that are currently recovering
+	RecoveriesInFlight int
+	// MinAge, MaxAge and AvgAge describe the age distribution of the cached connections,
+	// which is the time since the underlying connections were (re)established.
+	// Connections that were never established are not taken into account.
+	MinAge time.Duration
+	MaxAge time.Duration
+	AvgAge time.Duration
+	// Acquisitions counts cached and transient connection acquisitions separately
+	Acquisitions AcquisitionStats
+}
+
+// Stats returns a snapshot of the connection pool statistics.
+func (cp *ConnectionPool) Stats() ConnectionPoolStats {
+	cp.mu.Lock()
+	cached := cp.cached
+	cp.mu.Unlock()
+
+	minAge, maxAge, avgAge := connectionAges(cached, time.Now())
+	return ConnectionPoolStats{
+		Capacity:           cp.Capacity(),
+		Size:               cp.Size(),
+		CachedIdle:         cp.StatCachedIdle(),
+		TransientActive:    cp.StatTransientActive(),
+		Recoveries:         cp.recoveries.Load(),
+		RecoveriesInFlight: cp.StatRecoveriesInFlight(),
+		MinAge:             minAge,
+		MaxAge:             maxAge,
+		AvgAge:             avgAge,
+		Acquisitions:       cp.acquisitions.stats(),
+	}
+}
+
+// Snapshot returns diagnostic information about all cached connections of the pool, ordered by their id,
+// e.g. for debugging incidents or for a debug http endpoint.
+// Taking a snapshot does not check out any connection and does not block on busy connections,
+// which is why the information of connections that are currently in use or recovering is best-effort.
+func (cp *ConnectionPool) Snapshot() []ConnectionInfo {
+	cp.mu.Lock()
+	cached := cp.cached
+	cp.mu.Unlock()
+
+	now := time.Now()
+	infos := make([]ConnectionInfo, 0, len(cached))
+	for id, conn := range cached {
+		infos = append(infos, conn.snapshot(id, now))
+	}
+	return infos
+}
+
+// MigrateOffURL reconnects all cached connections that are currently connected to the broker with the passed url
+// to the other brokers of the pool, e.g. before the broker is shut down for maintenance.
+// Connections are migrated one after another in order to prevent a reconnect storm. Sessions of migrated connections
+// are recovered on the new connection, which interrupts them for a short time.
+// Connections that are flagged or recovering are not migrated. Connections may return to the broker
+// when they are recovered later on and the broker is their preferred one.
+// The url must be one of the broker urls of the pool, see ConnectionPoolWithBrokerWeights.
+func (cp *ConnectionPool) MigrateOffURL(ctx context.Context, url string) error {
+	u, err := parseURL(url, cp.option.TLSConfig != nil || cp.tlsServerName != "")
+	if err != nil {
+		return err
+	}
+	target := u.String()
+
+	cp.mu.Lock()
+	cached := cp.cached
+	cp.mu.Unlock()
+
+	known := false
+	for _, conn := range cached {
+		for _, brokerURL := range conn.brokerURLs() {
+			if brokerURL == target {
+				known = true
+			}
+		}
+	}
+	if !known {
+		return fmt.Errorf("%w: %s is not a broker url of connection pool %s", ErrInvalidConnectURL, u.Redacted(), cp.name)
+	}
+
+	var (
+		errs     error
+		migrated = 0
+	)
+	for _, conn := range cached {
+		ok, err := conn.migrateOff(ctx, target)
+		if err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+		if ok {
+			migrated++
+		}
+	}
+	cp.info(fmt.Sprintf("migrated %d connections off %s", migrated, u.Redacted()))
+	return errs
+}
+
+// connectionAges returns the minimum, maximum and average age of all established connections.
+func connectionAges(conns []*Connection, now time.Time) (minAge, maxAge, avgAge time.Duration) {
+	var (
+		sum time.Duration
+		n   int
+	)
+	for _, conn := range conns {
+		age := conn.age(now)
+		if age <= 0 {
+			// not established
+			continue
+		}
+		if n == 0 || age < minAge {
+			minAge = age
+		}
+		if age > maxAge {
+			maxAge = age
+		}
+		sum += age
+		n++
+	}
+	if n == 0 {
+		return 0, 0, 0
+	}
+	return minAge, maxAge, sum / time.Duration(n)
 }
 
 // StatTransientActive returns the number of active transient connections.
 func (cp *ConnectionPool) StatTransientActive() int {
 	cp.mu.Lock()
 	defer cp.mu.Unlock()
-	return cp.concurrentTransient
+	return cp.transients.active
+}
+
+// StatRecoveriesInFlight returns the number of connections that are currently recovering,
+// recoveries that are queued due to ConnectionPoolWithMaxConcurrentRecoveries are not taken into account.
+func (cp *ConnectionPool) StatRecoveriesInFlight() int {
+	return cp.recoveryGate.active()
 }
 
 // StatCachedActive returns the number of active cached connections.
 func (cp *ConnectionPool) StatCachedActive() int {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
 	return cp.capacity - len(cp.connections)
 }
 
+// IsBlocked returns true in case the broker currently blocks at least one connection of the pool.
+func (cp *ConnectionPool) IsBlocked() bool {
+	return cp.blocked.isBlocked()
+}
+
 // Size returns the number of idle cached connections.
 func (cp *ConnectionPool) Size() int {
-	return len(cp.connections)
+	return len(cp.queue())
 }
 
 // Capacity is the capacity of the cached connection pool without any transient connections.
-// It is the initial number of connections that were created for this connection pool.
+// It is the initial number of connections that were created for this connection pool, unless the pool was resized.
 func (cp *ConnectionPool) Capacity() int {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
 	return cp.capacity
 }
 
+// queue returns the queue of idle cached connections.
+func (cp *ConnectionPool) queue() chan *Connection {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	return cp.connections
+}
+
 func (cp *ConnectionPool) catchShutdown() <-chan struct{} {
 	return cp.ctx.Done()
 }
@@ -292,14 +929,76 @@ func (cp *ConnectionPool) Name() string {
 	return cp.name
 }
 
+// SetLogger replaces the logger of the pool and of all of its cached connections, e.g. in order to change the log level
+// at runtime. Cached connections use the new logger for all subsequent log messages, including those of a recovery
+// that is in progress. Transient connections that are created afterwards use the new logger as well, transient
+// connections that are already in use keep their logger until they are closed.
+// Session pools keep the logger that they were created with.
+func (cp *ConnectionPool) SetLogger(logger logging.Logger) {
+	cp.settingsMu.Lock()
+	cp.log = logger
+	cp.settingsMu.Unlock()
+
+	for _, conn := range cp.cachedConns() {
+		conn.SetLogger(logger)
+	}
+}
+
+// SetBackoffPolicy replaces the backoff policy of all cached connections of the pool and of connections
+// that are created afterwards. Recoveries that are in progress use the new policy for their next retry.
+// Connections are not reconnected.
+func (cp *ConnectionPool) SetBackoffPolicy(policy BackoffFunc) {
+	cp.settingsMu.Lock()
+	cp.backoff = policy
+	cp.settingsMu.Unlock()
+
+	for _, conn := range cp.cachedConns() {
+		conn.SetBackoffPolicy(policy)
+	}
+}
+
+// SetTLSConfig replaces the tls config of all cached connections of the pool and of connections that are
+// created afterwards, e.g. in order to rotate client certificates during a config reload.
+// Connections are not reconnected, the new config is used upon their next (re)connect.
+// The server name of ConnectionPoolWithServerName is applied to the new config as well.
+// Whether tls is used at all is determined by the connect url upon creation, see ConnectionPoolWithTLS.
+func (cp *ConnectionPool) SetTLSConfig(config *tls.Config) {
+	config = withSessionCache(config) // shared by all connections of the pool
+
+	cp.settingsMu.Lock()
+	cp.tls = config
+	cp.settingsMu.Unlock()
+
+	for _, conn := range cp.cachedConns() {
+		conn.SetTLSConfig(withServerName(config, cp.tlsServerName))
+	}
+}
+
+// cachedConns returns all cached connections, whether idle or in use.
+func (cp *ConnectionPool) cachedConns() []*Connection {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	return cp.cached
+}
+
+func (cp *ConnectionPool) logger() logging.Logger {
+	cp.settingsMu.RLock()
+	defer cp.settingsMu.RUnlock()
+	return cp.log
+}
+
 func (cp *ConnectionPool) info(a ...any) {
-	cp.log.WithField("connectionPool", cp.name).Info(a...)
+	cp.logger().WithField("connectionPool", cp.name).Info(a...)
+}
+
+func (cp *ConnectionPool) warn(err error, a ...any) {
+	cp.logger().WithField("connectionPool", cp.name).WithField("error", err.Error()).Warn(a...)
 }
 
 func (cp *ConnectionPool) error(err error, a ...any) {
-	cp.log.WithField("connectionPool", cp.name).WithField("error", err.Error()).Error(a...)
+	cp.logger().WithField("connectionPool", cp.name).WithField("error", err.Error()).Error(a...)
 }
 
 func (cp *ConnectionPool) debug(a ...any) {
-	cp.log.WithField("connectionPool", cp.name).Debug(a...)
+	cp.logger().WithField("connectionPool", cp.name).Debug(a...)
 }
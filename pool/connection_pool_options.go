@@ -14,18 +14,43 @@ import (
 )
 
 type connectionPoolOption struct {
-	Name string
-	Ctx  context.Context
+	Name           string
+	NameUniqueness NameUniqueness
+	Ctx            context.Context
 
 	Capacity int
 
 	ConnHeartbeatInterval time.Duration
 	ConnTimeout           time.Duration
+	ConnTCPConnectTimeout time.Duration
+	InitialDialTimeout    time.Duration
+	ConnIOTimeout         time.Duration
+	LivenessInterval      time.Duration
+	HealthCheckInterval   time.Duration
+	MaxLifetime           time.Duration
+	MinIdle               int
+	IdleTimeout           time.Duration
+	SerialRecovery        bool
+	MaxRecoveries         int
+	TransientIDStrategy   TransientIDStrategy
+	SlowAcquisition       time.Duration
 	TLSConfig             *tls.Config
+	TLSServerName         string
+	AddressFamily         string
+	ExternalAuth          bool
+	PlainAuthFallback     bool
+	ConnProperties        Table
+	BrokerWeights         []WeightedURL
 
 	Logger logging.Logger
 
 	ConnectionRecoverCallback ConnectionRecoverCallback
+	MaxBackoffCallback        ConnectionMaxBackoffCallback
+	ShutdownHook              func(cause error)
+	OnBlocked                 PoolBlockedCallback
+	OnUnblocked               PoolBlockedCallback
+
+	MetricsCollector MetricsCollector
 }
 
 type ConnectionPoolOption func(*connectionPoolOption)
@@ -78,6 +103,31 @@ func ConnectionPoolWithNamePrefix(prefix string) ConnectionPoolOption {
 	}
 }
 
+// ConnectionPoolWithNameUniqueness enforces that no other open connection pool of the process uses the same name.
+// The name is released when the pool is closed. Enforcement is disabled by default, as the same name
+// is usually wanted across restarts or for clones of a pool.
+func ConnectionPoolWithNameUniqueness(uniqueness NameUniqueness) ConnectionPoolOption {
+	return func(po *connectionPoolOption) {
+		po.NameUniqueness = uniqueness
+	}
+}
+
+// ConnectionPoolWithTransientIDStrategy defines how the ids of transient connections are generated,
+// which are part of their names. Ids are assigned sequentially by default.
+func ConnectionPoolWithTransientIDStrategy(strategy TransientIDStrategy) ConnectionPoolOption {
+	return func(po *connectionPoolOption) {
+		po.TransientIDStrategy = strategy
+	}
+}
+
+// ConnectionPoolWithCapacity overrides the number of cached connections of the pool.
+// This is mainly useful in combination with ConnectionPool.Clone.
+func ConnectionPoolWithCapacity(capacity int) ConnectionPoolOption {
+	return func(po *connectionPoolOption) {
+		po.Capacity = capacity
+	}
+}
+
 // WithHeartbeatInterval allows to set a custom heartbeat interval, that MUST be >= 1 * time.Second
 func ConnectionPoolWithHeartbeatInterval(interval time.Duration) ConnectionPoolOption {
 	if interval < time.Second {
@@ -98,13 +148,194 @@ func ConnectionPoolWithConnectionTimeout(timeout time.Duration) ConnectionPoolOp
 	}
 }
 
+// ConnectionPoolWithTCPConnectTimeout bounds establishing the TCP connections of the pool separately from
+// the connection timeout, which bounds the TLS and AMQP handshakes, see ConnectionWithTCPConnectTimeout.
+// A timeout <= 0 bounds the TCP connect by the connection timeout (default).
+func ConnectionPoolWithTCPConnectTimeout(timeout time.Duration) ConnectionPoolOption {
+	return func(po *connectionPoolOption) {
+		po.ConnTCPConnectTimeout = timeout
+	}
+}
+
+// ConnectionPoolWithInitialDialTimeout bounds the initial connect of every cached connection while the pool is created,
+// so that NewConnectionPool fails fast with ErrPoolInitializationFailed in case the broker is not reachable,
+// see ConnectionWithInitialDialTimeout. Connections are still recovered without that bound afterwards.
+// A timeout <= 0 retries until the context of the pool is done (default).
+func ConnectionPoolWithInitialDialTimeout(timeout time.Duration) ConnectionPoolOption {
+	return func(po *connectionPoolOption) {
+		po.InitialDialTimeout = timeout
+	}
+}
+
+// ConnectionPoolWithIOTimeout sets a deadline for every read and write on the sockets of the pool's connections
+// in order to detect stalled sockets, see ConnectionWithIOTimeout. A timeout <= 0 disables the deadlines (default).
+func ConnectionPoolWithIOTimeout(timeout time.Duration) ConnectionPoolOption {
+	return func(po *connectionPoolOption) {
+		po.ConnIOTimeout = timeout
+	}
+}
+
+// ConnectionPoolWithLivenessInterval enables a background check that pings every idle cached connection
+// once per interval and flags and recovers connections that turned out to be dead,
+// so that their loss is detected before the next GetConnection call.
+// Connections that are currently in use are skipped. An interval <= 0 disables the check (default).
+func ConnectionPoolWithLivenessInterval(interval time.Duration) ConnectionPoolOption {
+	return func(po *connectionPoolOption) {
+		po.LivenessInterval = interval
+	}
+}
+
+// ConnectionPoolWithHealthCheck enables a background check that pings every idle cached connection once per interval
+// with Connection.Ping and flags connections whose ping failed, so that they are recovered by the next GetConnection call
+// instead of failing the first operation of the caller. In contrast to ConnectionPoolWithLivenessInterval,
+// flagged connections are not recovered in the background. Connections that are currently in use are skipped.
+// An interval <= 0 disables the check (default).
+func ConnectionPoolWithHealthCheck(interval time.Duration) ConnectionPoolOption {
+	return func(po *connectionPoolOption) {
+		po.HealthCheckInterval = interval
+	}
+}
+
+// ConnectionPoolWithMaxLifetime reconnects cached connections that are older than the passed lifetime
+// when they are acquired with GetConnection, before they are handed out, see Connection.Age.
+// Recycling connections releases state that accumulates on the broker side and replaces connections
+// that were silently dropped by load balancers. A lifetime <= 0 keeps connections until they fail (default).
+func ConnectionPoolWithMaxLifetime(lifetime time.Duration) ConnectionPoolOption {
+	return func(po *connectionPoolOption) {
+		po.MaxLifetime = lifetime
+	}
+}
+
+// ConnectionPoolWithIdleTimeout enables a background reaper that closes the underlying connections of cached
+// connections that were not used for the passed timeout, which releases resources of pools that are oversized during
+// quiet periods. Released connections stay in the pool and are reopened on demand by GetConnection.
+// At least ConnectionPoolWithMinIdle idle connections are kept open. A timeout <= 0 disables the reaper (default).
+func ConnectionPoolWithIdleTimeout(timeout time.Duration) ConnectionPoolOption {
+	return func(po *connectionPoolOption) {
+		po.IdleTimeout = timeout
+	}
+}
+
+// ConnectionPoolWithMinIdle sets the number of idle cached connections that are kept open by the idle reaper,
+// see ConnectionPoolWithIdleTimeout. Defaults to 0.
+// It has no effect unless the idle reaper is enabled with ConnectionPoolWithIdleTimeout.
+func ConnectionPoolWithMinIdle(min int) ConnectionPoolOption {
+	if min < 0 {
+		min = 0
+	}
+	return func(po *connectionPoolOption) {
+		po.MinIdle = min
+	}
+}
+
+// ConnectionPoolWithSerialRecovery makes connections of the pool reconnect strictly one at a time during their recovery,
+// e.g. in order not to overload brokers with an expensive authentication backend after a mass outage.
+// Connections wait for their turn before every reconnect attempt, which slows down the total recovery of the pool.
+func ConnectionPoolWithSerialRecovery() ConnectionPoolOption {
+	return func(po *connectionPoolOption) {
+		po.SerialRecovery = true
+	}
+}
+
+// ConnectionPoolWithMaxConcurrentRecoveries limits the number of connections of the pool that recover concurrently,
+// e.g. in order to bound the number of goroutines and backoff timers during a mass outage.
+// A connection holds its slot during its whole recovery including the backoff between its reconnect attempts,
+// the recoveries of all other connections are queued. The recoveries of connections that are requested via
+// GetConnection are admitted before all other queued recoveries.
+// A limit of 0 or less does not limit the number of concurrent recoveries, which is the default.
+// See ConnectionPool.StatRecoveriesInFlight.
+func ConnectionPoolWithMaxConcurrentRecoveries(n int) ConnectionPoolOption {
+	return func(po *connectionPoolOption) {
+		po.MaxRecoveries = n
+	}
+}
+
+// ConnectionPoolWithSlowAcquisitionThreshold logs a warning with the pool name and the wait duration
+// as soon as GetConnection blocks longer than the threshold, e.g. because the pool is saturated or its connections are recovering.
+// A threshold <= 0 disables the warning (default).
+func ConnectionPoolWithSlowAcquisitionThreshold(threshold time.Duration) ConnectionPoolOption {
+	return func(po *connectionPoolOption) {
+		po.SlowAcquisition = threshold
+	}
+}
+
 // ConnectionPoolWithTLS allows to configure tls connectivity.
+// All connections of the pool share a client session cache in case the config has none, which allows to resume
+// tls sessions when reconnecting. Set SessionTicketsDisabled in order to disable session resumption.
 func ConnectionPoolWithTLS(config *tls.Config) ConnectionPoolOption {
 	return func(po *connectionPoolOption) {
 		po.TLSConfig = config
 	}
 }
 
+// ConnectionPoolWithServerName allows to override the server name that is used to verify the broker's certificate.
+// The tls config passed via ConnectionPoolWithTLS is cloned and not modified.
+// Setting a server name implies the usage of tls.
+func ConnectionPoolWithServerName(serverName string) ConnectionPoolOption {
+	return func(po *connectionPoolOption) {
+		po.TLSServerName = serverName
+	}
+}
+
+// ConnectionPoolWithConnectionProperties sets the client properties that all connections of the pool advertise
+// to the broker whenever they are (re)established, see ConnectionWithProperties.
+func ConnectionPoolWithConnectionProperties(properties Table) ConnectionPoolOption {
+	return func(po *connectionPoolOption) {
+		po.ConnProperties = copyTable(properties)
+	}
+}
+
+// ConnectionPoolWithAddressFamily forces the usage of a specific network when dialing the broker.
+// Supported values are "tcp" (default, dual-stack), "tcp4" (IPv4 only) and "tcp6" (IPv6 only).
+func ConnectionPoolWithAddressFamily(network string) ConnectionPoolOption {
+	return func(po *connectionPoolOption) {
+		po.AddressFamily = network
+	}
+}
+
+// ConnectionPoolWithExternalAuth authenticates all connections of the pool with the SASL EXTERNAL mechanism
+// instead of the credentials of the connection url.
+// In case fallbackToPlain is set, a connection whose EXTERNAL authentication is rejected by the broker retries it once
+// with PLAIN authentication and the credentials of the connection url, see ConnectionWithExternalAuth.
+func ConnectionPoolWithExternalAuth(fallbackToPlain bool) ConnectionPoolOption {
+	return func(po *connectionPoolOption) {
+		po.ExternalAuth = true
+		po.PlainAuthFallback = fallbackToPlain
+	}
+}
+
+// ConnectionPoolWithBrokerWeights distributes the cached connections of the pool across multiple brokers
+// proportionally to their weights, e.g. 70% of the connections to the primary region and 30% to a secondary region.
+// Every cached connection prefers its assigned broker and only fails over to the other brokers
+// in case its broker is not reachable. A failed over connection returns to its assigned broker
+// upon its next recovery, e.g. after the connection was flagged or lost.
+// The connection url that is passed to NewConnectionPool is only used for transient connections,
+// which fail over to the weighted brokers in their given order.
+func ConnectionPoolWithBrokerWeights(brokers []WeightedURL) ConnectionPoolOption {
+	return func(po *connectionPoolOption) {
+		po.BrokerWeights = brokers
+	}
+}
+
+// ConnectionPoolWithOnBlocked sets a callback that is called once when the broker starts blocking
+// the first connection of the pool due to resource alarms, e.g. high memory usage or low disk space.
+// While blocked, the broker refuses publishes from this pool.
+// The callback must not block.
+func ConnectionPoolWithOnBlocked(callback PoolBlockedCallback) ConnectionPoolOption {
+	return func(po *connectionPoolOption) {
+		po.OnBlocked = callback
+	}
+}
+
+// ConnectionPoolWithOnUnblocked sets a callback that is called once when the broker stops blocking
+// the last blocked connection of the pool, or when that connection is lost.
+// The callback must not block.
+func ConnectionPoolWithOnUnblocked(callback PoolBlockedCallback) ConnectionPoolOption {
+	return func(po *connectionPoolOption) {
+		po.OnUnblocked = callback
+	}
+}
+
 // ConnectionPoolWithRecoverCallback allows to set a custom recover callback.
 func ConnectionPoolWithRecoverCallback(callback ConnectionRecoverCallback) ConnectionPoolOption {
 	return func(po *connectionPoolOption) {
@@ -112,8 +343,34 @@ func ConnectionPoolWithRecoverCallback(callback ConnectionRecoverCallback) Conne
 	}
 }
 
+// ConnectionPoolWithMaxBackoffCallback sets a callback that is called whenever the recovery backoff of a connection
+// of the pool reaches its maximum and when such a connection was recovered, see ConnectionWithMaxBackoffCallback.
+// This allows to distinguish prolonged outages from brief connection losses, e.g. in order to alert on them.
+func ConnectionPoolWithMaxBackoffCallback(callback ConnectionMaxBackoffCallback) ConnectionPoolOption {
+	return func(po *connectionPoolOption) {
+		po.MaxBackoffCallback = callback
+	}
+}
+
+// ConnectionPoolWithShutdownHook allows to set a hook that is called exactly once when the connection pool is shut down.
+// This is the case when the pool is closed or when its parent context is canceled.
+// The hook receives the cause of the shutdown and is executed asynchronously in order not to block Close.
+// Panics of the hook are recovered and logged.
+func ConnectionPoolWithShutdownHook(hook func(cause error)) ConnectionPoolOption {
+	return func(po *connectionPoolOption) {
+		po.ShutdownHook = hook
+	}
+}
+
 type BackoffFunc func(retry int) (sleep time.Duration)
 
+// ConnectionPoolWithMetricsCollector allows to export connection pool metrics, e.g. to Prometheus.
+func ConnectionPoolWithMetricsCollector(collector MetricsCollector) ConnectionPoolOption {
+	return func(po *connectionPoolOption) {
+		po.MetricsCollector = collector
+	}
+}
+
 func newDefaultBackoffPolicy(min, max time.Duration) BackoffFunc {
 	r := rand.New(rand.NewSource(time.Now().Unix()))
 
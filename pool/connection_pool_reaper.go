@@ -0,0 +1,84 @@
+package pool
+
+import (
+	"time"
+)
+
+// reapIdle periodically releases the underlying connections of cached connections that were idle
+// for at least the timeout until the pool is closed, keeping at least minIdle idle connections open.
+func (cp *ConnectionPool) reapIdle(timeout time.Duration, minIdle int) {
+	defer cp.wg.Done()
+
+	interval := timeout / 2
+	if interval <= 0 {
+		interval = timeout
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-cp.catchShutdown():
+			return
+		case now := <-ticker.C:
+			// every connection that is idle at the beginning of the round is checked at most once,
+			// as requeued connections are appended to the end of the queue.
+			for i, n := 0, cp.Size(); i < n; i++ {
+				if !cp.reapIdleConnection(now, timeout, minIdle) {
+					break
+				}
+			}
+		}
+	}
+}
+
+// reapIdleConnection takes the next idle connection out of the queue, releases it in case it was idle for
+// at least the timeout and more than minIdle idle connections are open, and puts it back.
+// It returns false in case there was no idle connection or the pool was closed.
+func (cp *ConnectionPool) reapIdleConnection(now time.Time, timeout time.Duration, minIdle int) bool {
+	var conn *Connection
+	select {
+	case <-cp.catchShutdown():
+		return false
+	case c, ok := <-cp.queue():
+		if !ok {
+			// the queue was replaced by Resize
+			return false
+		}
+		conn = c
+	default:
+		// all connections are in use
+		return false
+	}
+	defer cp.requeue(conn)
+
+	idle := now.Sub(time.Unix(0, conn.idleSince.Load()))
+	// the connection that was taken out of the queue is not counted as idle
+	if idle >= timeout && cp.StatCachedIdle() >= minIdle && conn.release() {
+		cp.debug("released connection ", conn.Name(), " after being idle for ", idle)
+	}
+	return true
+}
+
+// StatCachedIdle returns the number of cached connections that are currently not in use and
+// connected to the broker, which excludes connections that were released by the idle reaper,
+// see ConnectionPoolWithIdleTimeout.
+func (cp *ConnectionPool) StatCachedIdle() int {
+	cp.mu.Lock()
+	cached := cp.cached
+	cp.mu.Unlock()
+
+	released := 0
+	for _, conn := range cached {
+		if conn.isReleased() {
+			released++
+		}
+	}
+
+	idle := cp.Size() - released
+	if idle < 0 {
+		// released connections are reopened after they were taken out of the queue
+		return 0
+	}
+	return idle
+}
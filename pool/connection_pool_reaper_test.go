@@ -0,0 +1,116 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jxsl13/amqpx/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectionPoolIdleReaper(t *testing.T) {
+	t.Parallel()
+
+	addr, _ := newFakeBroker(t, "PLAIN")
+	cp, err := NewConnectionPool(context.TODO(), fmt.Sprintf("amqp://admin:password@%s/", addr), 3,
+		ConnectionPoolWithName("idle-reaper"),
+		ConnectionPoolWithLogger(logging.NewNoOpLogger()),
+		ConnectionPoolWithIdleTimeout(100*time.Millisecond),
+		ConnectionPoolWithMinIdle(1),
+	)
+	require.NoError(t, err)
+	defer cp.Close()
+
+	assert.Equal(t, 3, cp.StatCachedIdle())
+
+	// idle connections age out down to the minimum
+	assert.Eventually(t, func() bool {
+		return cp.StatCachedIdle() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, 1, cp.StatCachedIdle(), "the minimum number of idle connections must be kept open")
+	assert.Equal(t, 3, cp.Size(), "released connections stay in the pool")
+	assert.Equal(t, 1, cp.Stats().CachedIdle)
+
+	// released connections are reopened on demand
+	conns := make([]*Connection, 0, 3)
+	for i := 0; i < 3; i++ {
+		conn, err := cp.GetConnection(context.TODO())
+		require.NoError(t, err)
+		assert.False(t, conn.IsClosed())
+		assert.False(t, conn.IsFlagged())
+		conns = append(conns, conn)
+	}
+	assert.Equal(t, 0, cp.StatCachedIdle())
+
+	// connections in use are not released
+	time.Sleep(300 * time.Millisecond)
+	for _, conn := range conns {
+		assert.False(t, conn.IsClosed())
+		cp.ReturnConnection(conn, nil)
+	}
+	assert.Equal(t, 3, cp.StatCachedIdle())
+}
+
+func TestConnectionPoolIdleReaperHealth(t *testing.T) {
+	t.Parallel()
+
+	addr, _ := newFakeBroker(t, "PLAIN")
+	cp, err := NewConnectionPool(context.TODO(), fmt.Sprintf("amqp://admin:password@%s/", addr), 2,
+		ConnectionPoolWithName("idle-reaper-health"),
+		ConnectionPoolWithLogger(logging.NewNoOpLogger()),
+		ConnectionPoolWithIdleTimeout(50*time.Millisecond),
+		ConnectionPoolWithMinIdle(0),
+	)
+	require.NoError(t, err)
+	defer cp.Close()
+
+	assert.Eventually(t, func() bool {
+		return cp.StatCachedIdle() == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	for _, info := range cp.Snapshot() {
+		assert.Equal(t, ConnectionStateReleased, info.State)
+		assert.False(t, info.Flagged)
+	}
+
+	// released connections are idle by design and do not degrade the health of the pool
+	report := cp.Health()
+	assert.Equal(t, HealthStatusHealthy, report.Status)
+	assert.Equal(t, 2, report.Healthy)
+	assert.Equal(t, 0, report.Flagged)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	report, err = cp.WaitReadyWithBackoff(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, HealthStatusHealthy, report.Status)
+}
+
+func TestConnectionPoolIdleReaperStopsOnClose(t *testing.T) {
+	t.Parallel()
+
+	addr, _ := newFakeBroker(t, "PLAIN")
+	cp, err := NewConnectionPool(context.TODO(), fmt.Sprintf("amqp://admin:password@%s/", addr), 1,
+		ConnectionPoolWithName("idle-reaper-close"),
+		ConnectionPoolWithLogger(logging.NewNoOpLogger()),
+		ConnectionPoolWithIdleTimeout(time.Millisecond),
+	)
+	require.NoError(t, err)
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		cp.Close()
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "Close did not stop the idle reaper")
+	}
+}
@@ -0,0 +1,155 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Resize grows or shrinks the number of cached connections of the pool at runtime, e.g. in response to load.
+// Growing derives and adds new cached connections. Shrinking removes and closes idle connections and blocks until
+// enough connections are returned to the pool in case too many connections are in use.
+// In case ctx is done or a new connection cannot be established before the target size is reached,
+// the connections that were added or removed so far are kept and an error is returned, see Capacity.
+// Session pools keep the number of sessions that they were created with.
+func (cp *ConnectionPool) Resize(ctx context.Context, newSize int) error {
+	if newSize < 1 {
+		return fmt.Errorf("%w: %d", errInvalidPoolSize, newSize)
+	}
+
+	cp.resizeMu.Lock()
+	defer cp.resizeMu.Unlock()
+
+	select {
+	case <-cp.catchShutdown():
+		return fmt.Errorf("failed to resize connection pool: connection pool %w", ErrClosed)
+	default:
+	}
+
+	capacity := cp.Capacity()
+	switch {
+	case newSize > capacity:
+		return cp.grow(ctx, newSize)
+	case newSize < capacity:
+		return cp.shrink(ctx, newSize)
+	default:
+		return nil
+	}
+}
+
+// grow adds cached connections until the pool has newSize connections.
+func (cp *ConnectionPool) grow(ctx context.Context, newSize int) error {
+	cp.mu.Lock()
+	added := newSize - cp.capacity
+	if len(cp.brokers) > 0 {
+		// new connections are assigned to brokers as if the pool was created with the new size
+		assignment := distributeByWeight(newSize, cp.brokers)
+		cp.brokerAssignment = append(cp.brokerAssignment, assignment[cp.capacity:]...)
+	}
+	cp.replaceQueue(newSize)
+	cp.mu.Unlock()
+
+	for i := 0; i < added; i++ {
+		cp.mu.Lock()
+		id := cp.nextCachedID
+		cp.nextCachedID++
+		cp.mu.Unlock()
+
+		// ctx only bounds the connect, the connection lives as long as the pool
+		conn, err := cp.deriveConnection(ctx, id, true, "", connectionWithContext(cp.ctx))
+		if err != nil {
+			return fmt.Errorf("failed to grow connection pool %s to %d connections: %w", cp.name, newSize, err)
+		}
+
+		cp.mu.Lock()
+		if cp.ctx.Err() != nil {
+			// closed concurrently, Close does not know about the new connection
+			cp.mu.Unlock()
+			_ = conn.Close()
+			return fmt.Errorf("failed to grow connection pool: connection pool %w", ErrClosed)
+		}
+		cp.cached = append(cp.cached, conn)
+		cp.capacity++
+		cp.connections <- conn // the queue has room for all connections
+		cp.mu.Unlock()
+	}
+
+	cp.info(fmt.Sprintf("resized to %d connections", newSize))
+	return nil
+}
+
+// shrink removes and closes idle cached connections until the pool has newSize connections.
+func (cp *ConnectionPool) shrink(ctx context.Context, newSize int) error {
+	var (
+		remove  = cp.Capacity() - newSize
+		removed = make([]*Connection, 0, remove)
+		err     error
+	)
+
+	// only Resize replaces the queue, which is why it does not change while shrinking
+	queue := cp.queue()
+	for len(removed) < remove && err == nil {
+		select {
+		case conn := <-queue:
+			removed = append(removed, conn)
+		case <-ctx.Done():
+			err = fmt.Errorf("%d connections are in use: %w", remove-len(removed), ctx.Err())
+		case <-cp.catchShutdown():
+			err = fmt.Errorf("connection pool %w", ErrClosed)
+		}
+	}
+
+	cp.mu.Lock()
+	cached := make([]*Connection, 0, len(cp.cached))
+	for _, conn := range cp.cached {
+		if !containsConnection(removed, conn) {
+			cached = append(cached, conn)
+		}
+	}
+	cp.cached = cached
+	cp.capacity -= len(removed)
+	cp.replaceQueue(cp.capacity)
+	cp.mu.Unlock()
+
+	var errs error
+	for _, conn := range removed {
+		errs = errors.Join(errs, conn.Close())
+	}
+	if errs != nil {
+		cp.warn(errs, "failed to close removed connections")
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to shrink connection pool %s to %d connections: %w", cp.name, newSize, err)
+	}
+	cp.info(fmt.Sprintf("resized to %d connections", newSize))
+	return nil
+}
+
+// replaceQueue moves the idle connections to a new queue with the passed capacity and closes the old queue,
+// which wakes up all GetConnection calls that wait for the old queue.
+// not threadsafe, must be called while holding the lock
+func (cp *ConnectionPool) replaceQueue(capacity int) {
+	old := cp.connections
+	queue := make(chan *Connection, capacity)
+drain:
+	for {
+		select {
+		case conn := <-old:
+			queue <- conn
+		default:
+			break drain
+		}
+	}
+	cp.connections = queue
+	close(old)
+}
+
+func containsConnection(conns []*Connection, conn *Connection) bool {
+	for _, c := range conns {
+		if c == conn {
+			return true
+		}
+	}
+	return false
+}
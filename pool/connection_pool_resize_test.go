@@ -0,0 +1,128 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jxsl13/amqpx/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newResizeTestPool(t *testing.T, name string, size int) *ConnectionPool {
+	t.Helper()
+
+	addr, _ := newFakeBroker(t, "PLAIN")
+	cp, err := NewConnectionPool(context.TODO(), fmt.Sprintf("amqp://admin:password@%s/", addr), size,
+		ConnectionPoolWithName(name),
+		ConnectionPoolWithLogger(logging.NewNoOpLogger()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(cp.Close)
+	return cp
+}
+
+func TestConnectionPoolResizeGrow(t *testing.T) {
+	t.Parallel()
+
+	cp := newResizeTestPool(t, "resize-grow", 1)
+
+	// a caller that waits for a connection is served by the grown pool
+	conn, err := cp.GetConnection(context.TODO())
+	require.NoError(t, err)
+	acquired := make(chan *Connection)
+	go func() {
+		c, err := cp.GetConnection(context.TODO())
+		assert.NoError(t, err)
+		acquired <- c
+	}()
+
+	require.NoError(t, cp.Resize(context.TODO(), 3))
+	waiting := <-acquired
+	assert.False(t, waiting.IsClosed())
+
+	assert.Equal(t, 3, cp.Capacity())
+	assert.Equal(t, 1, cp.Size())
+	assert.Equal(t, 2, cp.StatCachedActive())
+
+	cp.ReturnConnection(conn, nil)
+	cp.ReturnConnection(waiting, nil)
+	assert.Equal(t, 3, cp.Size())
+
+	names := map[string]bool{}
+	for _, c := range cp.cachedConns() {
+		names[c.Name()] = true
+	}
+	assert.Len(t, names, 3, "connection names must be unique")
+
+	// the size is unchanged
+	require.NoError(t, cp.Resize(context.TODO(), 3))
+	assert.Equal(t, 3, cp.Capacity())
+	assert.ErrorIs(t, cp.Resize(context.TODO(), 0), errInvalidPoolSize)
+}
+
+func TestConnectionPoolResizeShrink(t *testing.T) {
+	t.Parallel()
+
+	cp := newResizeTestPool(t, "resize-shrink", 3)
+	before := cp.cachedConns()
+
+	require.NoError(t, cp.Resize(context.TODO(), 1))
+	assert.Equal(t, 1, cp.Capacity())
+	assert.Equal(t, 1, cp.Size())
+	assert.Equal(t, 0, cp.StatCachedActive())
+	require.Len(t, cp.cachedConns(), 1)
+
+	closed := 0
+	for _, c := range before {
+		if c.IsClosed() {
+			closed++
+		}
+	}
+	assert.Equal(t, 2, closed, "removed connections must be closed")
+
+	// growing again does not reuse the names of removed connections
+	require.NoError(t, cp.Resize(context.TODO(), 2))
+	for _, c := range cp.cachedConns() {
+		for _, removed := range before {
+			if removed.IsClosed() {
+				assert.NotEqual(t, removed.Name(), c.Name())
+			}
+		}
+	}
+}
+
+func TestConnectionPoolResizeShrinkBorrowed(t *testing.T) {
+	t.Parallel()
+
+	cp := newResizeTestPool(t, "resize-shrink-borrowed", 2)
+	c1, err := cp.GetConnection(context.TODO())
+	require.NoError(t, err)
+	c2, err := cp.GetConnection(context.TODO())
+	require.NoError(t, err)
+
+	// all connections are in use
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = cp.Resize(ctx, 1)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 2, cp.Capacity())
+	assert.Equal(t, 2, cp.StatCachedActive())
+
+	// shrinking blocks until a connection is returned
+	done := make(chan error, 1)
+	go func() {
+		done <- cp.Resize(context.TODO(), 1)
+	}()
+	cp.ReturnConnection(c1, nil)
+	require.NoError(t, <-done)
+	assert.True(t, c1.IsClosed())
+
+	assert.Equal(t, 1, cp.Capacity())
+	assert.Equal(t, 1, cp.StatCachedActive())
+	cp.ReturnConnection(c2, nil)
+	assert.Equal(t, 1, cp.Size())
+	assert.False(t, c2.IsClosed())
+}
@@ -0,0 +1,43 @@
+package pool
+
+// ConnectionState is the lifecycle state of a Connection.
+// The numeric values are stable and may be exported as metrics.
+type ConnectionState int32
+
+const (
+	// ConnectionStateConnecting is the state of a connection that has not been connected to the broker yet.
+	ConnectionStateConnecting ConnectionState = iota
+	// ConnectionStateConnected is the state of a healthy connection.
+	ConnectionStateConnected
+	// ConnectionStateBlocked is the state of a connection that was blocked by the broker due to
+	// resource alarms (flow control), e.g. high memory usage or low disk space.
+	// Publishing is not possible on a blocked connection.
+	ConnectionStateBlocked
+	// ConnectionStateRecovering is the state of a connection that was flagged as broken or that was lost
+	// and which is (or will be) recovered.
+	ConnectionStateRecovering
+	// ConnectionStateClosed is the final state of a connection that was closed.
+	ConnectionStateClosed
+	// ConnectionStateReleased is the state of an idle connection whose underlying connection was closed
+	// by the idle reaper, see ConnectionPoolWithIdleTimeout. It is reopened upon its next usage.
+	ConnectionStateReleased
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case ConnectionStateConnecting:
+		return "connecting"
+	case ConnectionStateConnected:
+		return "connected"
+	case ConnectionStateBlocked:
+		return "blocked"
+	case ConnectionStateRecovering:
+		return "recovering"
+	case ConnectionStateClosed:
+		return "closed"
+	case ConnectionStateReleased:
+		return "released"
+	default:
+		return "unknown"
+	}
+}
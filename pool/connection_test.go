@@ -2,6 +2,7 @@ package pool_test
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"testing"
 	"time"
@@ -10,6 +11,7 @@ import (
 	"github.com/jxsl13/amqpx/logging"
 	"github.com/jxsl13/amqpx/pool"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewSingleConnection(t *testing.T) {
@@ -144,3 +146,176 @@ func TestManyNewConnectionWithDisconnect(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestConnectionSetNameRecover(t *testing.T) {
+	t.Parallel()
+	var (
+		ctx      = context.TODO()
+		nextName = testutils.ConnectionNameGenerator()
+		oldName  = nextName()
+		newName  = nextName()
+	)
+
+	c, err := pool.NewConnection(
+		ctx,
+		testutils.HealthyConnectURL,
+		oldName,
+		pool.ConnectionWithLogger(logging.NewTestLogger(t)),
+	)
+	if err != nil {
+		assert.NoError(t, err)
+		return
+	}
+	defer func() {
+		assert.NoError(t, c.Close())
+	}()
+
+	c.SetName(newName)
+	assert.Equal(t, newName, c.Name())
+
+	// force a reconnect which sends the new connection name to the broker
+	c.Flag(errors.New("forced recovery"))
+	assert.True(t, c.IsFlagged())
+	assert.NoError(t, c.Recover(ctx))
+	assert.False(t, c.IsFlagged())
+	assert.Equal(t, newName, c.Name())
+}
+
+func TestConnectionState(t *testing.T) {
+	t.Parallel()
+	var (
+		ctx      = context.TODO()
+		nextName = testutils.ConnectionNameGenerator()
+	)
+
+	c, err := pool.NewConnection(
+		ctx,
+		testutils.HealthyConnectURL,
+		nextName(),
+		pool.ConnectionWithLogger(logging.NewTestLogger(t)),
+	)
+	if err != nil {
+		assert.NoError(t, err)
+		return
+	}
+	assert.Equal(t, pool.ConnectionStateConnected, c.State())
+
+	c.Flag(errors.New("forced recovery"))
+	assert.Equal(t, pool.ConnectionStateRecovering, c.State())
+
+	assert.NoError(t, c.Recover(ctx))
+	assert.Equal(t, pool.ConnectionStateConnected, c.State())
+
+	assert.NoError(t, c.Close())
+	assert.Equal(t, pool.ConnectionStateClosed, c.State())
+}
+
+func TestConnectionStateBlocked(t *testing.T) {
+	t.Parallel()
+	var (
+		ctx      = context.TODO()
+		nextName = testutils.ConnectionNameGenerator()
+		connName = nextName()
+	)
+
+	c, err := pool.NewConnection(
+		ctx,
+		testutils.BrokenConnectURL, // out of memory rabbitmq
+		connName,
+		pool.ConnectionWithLogger(logging.NewTestLogger(t)),
+	)
+	if err != nil {
+		assert.NoError(t, err)
+		return
+	}
+	defer func() {
+		assert.NoError(t, c.Close())
+	}()
+
+	s, err := pool.NewSession(c, testutils.SessionNameGenerator(connName)())
+	if err != nil {
+		assert.NoError(t, err)
+		return
+	}
+	defer func() {
+		assert.NoError(t, s.Close())
+	}()
+
+	// the broker blocks publishing connections in case of a resource alarm
+	_, err = s.Publish(ctx, "", testutils.QueueNameGenerator(s.Name())(), pool.Publishing{Body: []byte("blocked")})
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return c.State() == pool.ConnectionStateBlocked
+	}, 10*time.Second, 50*time.Millisecond)
+}
+
+func TestConnectionNegotiatedParameters(t *testing.T) {
+	t.Parallel()
+	var (
+		ctx      = context.TODO()
+		nextName = testutils.ConnectionNameGenerator()
+	)
+
+	c, err := pool.NewConnection(
+		ctx,
+		testutils.HealthyConnectURL,
+		nextName(),
+		pool.ConnectionWithLogger(logging.NewTestLogger(t)),
+		pool.ConnectionWithHeartbeatInterval(5*time.Second),
+	)
+	if err != nil {
+		assert.NoError(t, err)
+		return
+	}
+	defer func() {
+		assert.NoError(t, c.Close())
+	}()
+
+	assert.Greater(t, c.NegotiatedChannelMax(), 0)
+	assert.Greater(t, c.NegotiatedFrameSize(), 0)
+	// the broker may only negotiate the requested heartbeat down
+	assert.Greater(t, c.NegotiatedHeartbeat(), time.Duration(0))
+	assert.LessOrEqual(t, c.NegotiatedHeartbeat(), 5*time.Second)
+}
+
+func TestConnectionOpenChannel(t *testing.T) {
+	t.Parallel()
+	var (
+		ctx      = context.TODO()
+		nextName = testutils.ConnectionNameGenerator()
+	)
+
+	c, err := pool.NewConnection(
+		ctx,
+		testutils.HealthyConnectURL,
+		nextName(),
+		pool.ConnectionWithLogger(logging.NewTestLogger(t)),
+	)
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, c.Close())
+	}()
+	opened := c.ChannelsOpened()
+
+	channel, err := c.OpenChannel()
+	require.NoError(t, err)
+	assert.Equal(t, opened+1, c.ChannelsOpened())
+
+	// the raw channel can be used for operations that are not covered by sessions
+	_, err = channel.QueueDeclare(testutils.QueueNameGenerator(c.Name())(), false, true, true, false, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, channel.Close())
+	assert.True(t, channel.IsClosed())
+
+	// flagged connections must be recovered first
+	c.Flag(errors.New("forced recovery"))
+	_, err = c.OpenChannel()
+	assert.ErrorIs(t, err, pool.ErrConnectionFailed)
+
+	require.NoError(t, c.Recover(ctx))
+	channel, err = c.OpenChannel()
+	require.NoError(t, err)
+	assert.Equal(t, opened+2, c.ChannelsOpened())
+	assert.NoError(t, channel.Close())
+}
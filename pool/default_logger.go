@@ -0,0 +1,34 @@
+package pool
+
+import (
+	"sync/atomic"
+
+	"github.com/jxsl13/amqpx/logging"
+)
+
+// loggerHolder wraps the default logger, as atomic pointers require a concrete type.
+type loggerHolder struct {
+	logger logging.Logger
+}
+
+var defaultLoggerHolder atomic.Pointer[loggerHolder]
+
+// SetDefaultLogger sets the logger that is used by all pools, connections and typed consumers
+// that are created afterwards without an explicit logger option.
+// Already created components keep their logger, which is why the default logger should be set at startup.
+// Passing nil restores the default, which does not log anything.
+func SetDefaultLogger(logger logging.Logger) {
+	if logger == nil {
+		defaultLoggerHolder.Store(nil)
+		return
+	}
+	defaultLoggerHolder.Store(&loggerHolder{logger: logger})
+}
+
+// defaultLogger returns the logger that was set via SetDefaultLogger or a logger that does not log anything.
+func defaultLogger() logging.Logger {
+	if h := defaultLoggerHolder.Load(); h != nil {
+		return h.logger
+	}
+	return logging.NewNoOpLogger()
+}
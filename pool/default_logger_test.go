@@ -0,0 +1,38 @@
+package pool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jxsl13/amqpx/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// not parallel, as the default logger is global
+func TestSetDefaultLogger(t *testing.T) {
+	ctx := context.TODO()
+
+	_, ok := defaultLogger().(*logging.NoOpLogger)
+	assert.True(t, ok, "expected no-op logger by default")
+
+	logger := logging.NewTestLogger(t)
+	SetDefaultLogger(logger)
+	defer SetDefaultLogger(nil)
+
+	c, err := newConnection(ctx, testConnectURL, "default-logger")
+	require.NoError(t, err)
+	defer c.Close()
+	assert.Same(t, logger, c.log)
+
+	// explicit loggers take precedence over the default
+	explicit := logging.NewNoOpLogger()
+	c, err = newConnection(ctx, testConnectURL, "explicit-logger", ConnectionWithLogger(explicit))
+	require.NoError(t, err)
+	defer c.Close()
+	assert.Same(t, explicit, c.log)
+
+	SetDefaultLogger(nil)
+	_, ok = defaultLogger().(*logging.NoOpLogger)
+	assert.True(t, ok, "expected no-op logger after reset")
+}
@@ -2,15 +2,31 @@ package pool
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 // defaultDial establishes a connection
 // it allows to additionally pass a context to the dialer
-func defaultDial(ctx context.Context, connectionTimeout time.Duration) func(network, addr string) (net.Conn, error) {
+// addressFamily overrides the network ("tcp") that is passed by the amqp library, e.g. "tcp4" or "tcp6".
+// A tcpConnectTimeout > 0 bounds the TCP connect instead of the connectionTimeout, which bounds the handshakes,
+// see ConnectionWithTCPConnectTimeout.
+// An ioTimeout > 0 sets a deadline for every read and write, see ConnectionWithIOTimeout.
+func defaultDial(ctx context.Context, addressFamily string, tcpConnectTimeout, connectionTimeout, ioTimeout time.Duration) func(network, addr string) (net.Conn, error) {
+	if tcpConnectTimeout <= 0 {
+		tcpConnectTimeout = connectionTimeout
+	}
 	return func(network, addr string) (net.Conn, error) {
-		d := net.Dialer{Timeout: connectionTimeout}
+		if addressFamily != "" {
+			network = addressFamily
+		}
+		d := net.Dialer{Timeout: tcpConnectTimeout}
 
 		conn, err := d.DialContext(ctx, network, addr)
 		if err != nil {
@@ -20,6 +36,9 @@ func defaultDial(ctx context.Context, connectionTimeout time.Duration) func(netw
 		// Heartbeating hasn't started yet, don't stall forever on a dead server.
 		// A deadline is set for TLS and AMQP handshaking. After AMQP is established,
 		// the deadline is cleared in openComplete.
+		if ioTimeout > 0 {
+			conn = newIOTimeoutConn(conn, ioTimeout)
+		}
 		if err := conn.SetDeadline(time.Now().Add(connectionTimeout)); err != nil {
 			return nil, err
 		}
@@ -27,3 +46,169 @@ func defaultDial(ctx context.Context, connectionTimeout time.Duration) func(netw
 		return conn, nil
 	}
 }
+
+// dialContext connects to the broker with the passed url and config.
+// The amqp library bounds the TLS and AMQP handshakes only with the connection timeout, which is why the socket
+// is closed as soon as ctx is done while a dial is in flight.
+func dialContext(ctx context.Context, url string, cfg amqp.Config) (*amqp.Connection, error) {
+	var (
+		mu       sync.Mutex
+		netConn  net.Conn
+		finished bool
+		aborted  bool
+	)
+
+	dial := cfg.Dial
+	cfg.Dial = func(network, addr string) (net.Conn, error) {
+		conn, err := dial(network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if aborted {
+			_ = conn.Close()
+			return nil, ctx.Err()
+		}
+		netConn = conn
+		return conn, nil
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-stop:
+		case <-ctx.Done():
+			mu.Lock()
+			defer mu.Unlock()
+			if finished {
+				return
+			}
+			aborted = true
+			if netConn != nil {
+				_ = netConn.Close()
+			}
+		}
+	}()
+
+	conn, err := amqp.DialConfig(url, cfg)
+
+	mu.Lock()
+	defer mu.Unlock()
+	finished = true
+	if aborted {
+		if err == nil {
+			// the handshake completed while the socket was closed
+			_ = conn.Close()
+		}
+		return nil, fmt.Errorf("dial aborted: %w", ctx.Err())
+	}
+	return conn, err
+}
+
+// ioTimeoutConn sets a deadline for every read and write in order to detect stalled sockets.
+// Deadlines that are set by the amqp library, e.g. for the handshake or based on heartbeats, are kept
+// in case they are earlier.
+type ioTimeoutConn struct {
+	net.Conn
+	timeout time.Duration
+
+	// unix nano timestamps, 0 if not set
+	readDeadline  atomic.Int64
+	writeDeadline atomic.Int64
+	lastRead      atomic.Int64 // latest read that returned data
+	lastWrite     atomic.Int64 // start of the latest write
+}
+
+func newIOTimeoutConn(conn net.Conn, timeout time.Duration) *ioTimeoutConn {
+	c := &ioTimeoutConn{
+		Conn:    conn,
+		timeout: timeout,
+	}
+	c.lastRead.Store(time.Now().UnixNano())
+	return c
+}
+
+func (c *ioTimeoutConn) Read(b []byte) (int, error) {
+	for {
+		if err := c.Conn.SetReadDeadline(c.deadline(&c.readDeadline)); err != nil {
+			return 0, err
+		}
+		n, err := c.Conn.Read(b)
+		if n > 0 {
+			c.lastRead.Store(time.Now().UnixNano())
+		}
+		if n == 0 && c.idle(err) {
+			// nothing was expected to be received, keep waiting
+			continue
+		}
+		return n, err
+	}
+}
+
+func (c *ioTimeoutConn) Write(b []byte) (int, error) {
+	c.lastWrite.Store(time.Now().UnixNano())
+	if err := c.Conn.SetWriteDeadline(c.deadline(&c.writeDeadline)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Write(b)
+}
+
+func (c *ioTimeoutConn) SetDeadline(t time.Time) error {
+	c.readDeadline.Store(unixNano(t))
+	c.writeDeadline.Store(unixNano(t))
+	return c.Conn.SetDeadline(t)
+}
+
+func (c *ioTimeoutConn) SetReadDeadline(t time.Time) error {
+	c.readDeadline.Store(unixNano(t))
+	return c.Conn.SetReadDeadline(t)
+}
+
+func (c *ioTimeoutConn) SetWriteDeadline(t time.Time) error {
+	c.writeDeadline.Store(unixNano(t))
+	return c.Conn.SetWriteDeadline(t)
+}
+
+// deadline returns the deadline of the next operation, which is the earlier one
+// of the io timeout and the deadline that was set explicitly.
+func (c *ioTimeoutConn) deadline(explicit *atomic.Int64) time.Time {
+	deadline := time.Now().Add(c.timeout)
+	if d := explicit.Load(); d != 0 && d < deadline.UnixNano() {
+		return time.Unix(0, d)
+	}
+	return deadline
+}
+
+// idle returns true in case the read timed out only because the connection is idle,
+// meaning that nothing was written since data was received the last time.
+func (c *ioTimeoutConn) idle(err error) bool {
+	var ne net.Error
+	if !errors.As(err, &ne) || !ne.Timeout() {
+		return false
+	}
+	if d := c.readDeadline.Load(); d != 0 && time.Now().UnixNano() >= d {
+		// explicit deadline exceeded
+		return false
+	}
+	return c.lastWrite.Load() < c.lastRead.Load()
+}
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// validateAddressFamily checks whether the network can be used for dialing the broker.
+func validateAddressFamily(network string) error {
+	switch network {
+	case "", "tcp", "tcp4", "tcp6":
+		return nil
+	default:
+		return fmt.Errorf("%w: %q, expected one of tcp, tcp4 or tcp6", ErrInvalidAddressFamily, network)
+	}
+}
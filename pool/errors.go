@@ -3,6 +3,7 @@ package pool
 import (
 	"context"
 	"errors"
+	"strings"
 
 	"github.com/rabbitmq/amqp091-go"
 )
@@ -10,22 +11,64 @@ import (
 var (
 	ErrInvalidConnectURL = errors.New("invalid connection url")
 
+	// ErrInvalidAddressFamily is returned in case an unsupported network is passed to ConnectionWithAddressFamily.
+	ErrInvalidAddressFamily = errors.New("invalid address family")
+
+	// ErrInvalidBrokerWeight is returned in case a broker passed to ConnectionPoolWithBrokerWeights has a weight < 1.
+	ErrInvalidBrokerWeight = errors.New("invalid broker weight")
+
+	// ErrInvalidHeader is returned in case a message is published with a header that the broker does not support,
+	// e.g. CC or BCC headers that are not arrays of strings.
+	ErrInvalidHeader = errors.New("invalid header")
+
+	// ErrInvalidConsumerTag is returned in case a consumer tag exceeds the maximum length of 255 bytes.
+	ErrInvalidConsumerTag = errors.New("invalid consumer tag")
+
+	// ErrMessageTooLarge is returned in case the body of a published message exceeds the maximum message size
+	// of the session. Such messages are rejected before they are sent to the broker.
+	ErrMessageTooLarge = errors.New("message too large")
+
+	// ErrDuplicatePoolName is returned in case a connection pool is created with NameUniquenessError
+	// and another open pool of the process already uses its name.
+	ErrDuplicatePoolName = errors.New("duplicate pool name")
+
+	// ErrExpvarExists is returned by ConnectionPool.PublishExpvar in case the name is already used by another expvar variable.
+	ErrExpvarExists = errors.New("expvar variable already exists")
+
+	// ErrAccessRefused is returned in case the broker refuses a connection for a reason that reconnecting cannot fix,
+	// e.g. invalid credentials, a vhost that does not exist or that the user is not allowed to access.
+	// Such connections are not recovered.
+	ErrAccessRefused = errors.New("access refused")
+
+	// ErrConnectionLimitReached is returned in case the broker refuses a connection, because a connection limit of the
+	// broker node, the vhost or the user is reached, e.g. because the pool size multiplied by the number of replicas
+	// of a service exceeds the limit. Such connections are not recovered, the pool size or the limit must be adjusted.
+	ErrConnectionLimitReached = errors.New("connection limit reached")
+
 	// ErrConnectionFailed is just a generic error that is not checked
 	// explicitly against in the code.
 	ErrConnectionFailed = errors.New("connection failed")
 
+	// ErrPoolExhausted is recorded as the acquisition error of SessionPool.TryGetSession in case
+	// no idle session could be acquired without blocking, see MetricsCollector.
+	ErrPoolExhausted = errors.New("pool exhausted")
+
 	errInvalidPoolSize          = errors.New("invalid pool size")
 	ErrPoolInitializationFailed = errors.New("pool initialization failed")
 	ErrClosed                   = errors.New("closed")
 
 	// ErrNotFound is returned by ExchangeDeclarePassive or QueueDeclarePassive in the case that
-	// the queue was not found.
+	// the queue was not found, or by SessionPool.PreWarmedConsumer for queues without a pre-warmed consumer.
 	ErrNotFound = errors.New("not found")
 
 	// ErrBlockingFlowControl is returned when the server is under flow control
 	// Your HTTP api may return 503 Service Unavailable or 429 Too Many Requests with a Retry-After header (https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Retry-After)
 	ErrBlockingFlowControl = errors.New("blocking flow control")
 
+	// ErrRateLimited is returned by a non-blocking publish rate limit in case the rate of publishings is exceeded.
+	// In contrast to ErrBlockingFlowControl, the limit is enforced by the client, see SessionPoolWithPublishRateLimit.
+	ErrRateLimited = errors.New("publish rate limit exceeded")
+
 	// errBlockingFlowControlClosed is returned when the flow control channel is closed
 	// Specifically interesting when awaiting publish confirms
 	// TODO: make public api after a while
@@ -44,6 +87,31 @@ var (
 	// ErrRejectSingle can be used to reject a specific message
 	// This is a special error that negatively acknowledges messages and does not reuque them
 	ErrRejectSingle = errors.New("single message rejected")
+
+	// ErrDecode is returned by TypedConsumer handlers in case the body of a message could not be decoded.
+	ErrDecode = errors.New("failed to decode message")
+
+	// ErrHandlerPanic is returned by handlers that are wrapped with RecoverMiddleware in case they panic.
+	// Subscribers also convert panics of their handlers into an error that wraps ErrHandlerPanic,
+	// in which case the message is (n)acked according to the NackPolicy of the handler.
+	ErrHandlerPanic = errors.New("handler panicked")
+
+	// ErrCallbackPanic wraps the panic of a user supplied callback, e.g. a recover or retry callback,
+	// which was recovered in order not to crash the pool.
+	ErrCallbackPanic = errors.New("callback panicked")
+
+	// ErrConnectionReturned is logged in case a cached connection is returned to its pool more than once.
+	ErrConnectionReturned = errors.New("connection was returned to the pool more than once")
+
+	// ErrForeignConnection is logged in case a connection is returned to a pool that it does not belong to.
+	ErrForeignConnection = errors.New("connection does not belong to the pool")
+
+	// ErrForeignSession is logged in case a cached session is returned to a session pool that it does not belong to.
+	ErrForeignSession = errors.New("session does not belong to the pool")
+
+	// ErrSessionReturned is returned by the operations of a pooled session that was returned to its pool
+	// and was not acquired again, e.g. when a reference to the session is kept and used after ReturnSession.
+	ErrSessionReturned = errors.New("session was returned to the pool")
 )
 
 var (
@@ -58,6 +126,26 @@ var (
 	ErrDeliveryTagMismatch = errors.New("delivery tag mismatch")
 
 	ErrDeliveryClosed = errors.New("delivery channel closed")
+
+	// ErrStaleDeliveryTag is returned when a message is (n)acked that was delivered on a channel
+	// which was recovered in the meantime. The broker requeues such messages.
+	ErrStaleDeliveryTag = errors.New("stale delivery tag")
+
+	// ErrConfirmLost is returned by ConfirmGroup.Wait in case the channel of a session was recovered after a message was
+	// published on it. The confirmations of the old channel are lost, the messages must be published again.
+	ErrConfirmLost = errors.New("confirmation lost")
+
+	// ErrForeignDelivery is returned when a message is (n)acked on a session that did not receive it.
+	// Delivery tags are scoped to a channel, (n)acking them on another channel would close that channel.
+	ErrForeignDelivery = errors.New("delivery belongs to another session")
+
+	// ErrTxAborted is returned by Session.WithTx in case the channel of the session was closed or recovered
+	// while the transaction was in progress, which discards all uncommitted publishings and acknowledgements.
+	ErrTxAborted = errors.New("transaction aborted")
+
+	// ErrTxConfirmMode is returned when a transaction is started on a session that is in confirm mode.
+	// A channel cannot be in transaction mode and in confirm mode at the same time.
+	ErrTxConfirmMode = errors.New("transactions are not supported in confirm mode")
 )
 
 func recoverable(err error) bool {
@@ -81,6 +169,24 @@ func recoverable(err error) bool {
 		return false
 	}
 
+	if errors.Is(err, ErrRateLimited) {
+		return false
+	}
+
+	if errors.Is(err, ErrAccessRefused) || errors.Is(err, ErrConnectionLimitReached) {
+		return false
+	}
+
+	if errors.Is(err, ErrSessionReturned) {
+		return false
+	}
+
+	// invalid messages and consumer tags are rejected before they reach the broker,
+	// retrying them would fail again.
+	if errors.Is(err, ErrMessageTooLarge) || errors.Is(err, ErrInvalidHeader) || errors.Is(err, ErrInvalidConsumerTag) {
+		return false
+	}
+
 	// invalid usage of the amqp protocol is not recoverable
 	// INFO: this should be checked last.
 	ae := &amqp091.Error{}
@@ -88,6 +194,13 @@ func recoverable(err error) bool {
 		switch ae.Code {
 		case notImplemented:
 			return false
+		case amqp091.ConnectionForced:
+			// the connection was closed by an operator, e.g. via the management ui, or due to a broker shutdown
+			return true
+		case amqp091.AccessRefused, amqp091.NotAllowed, amqp091.InvalidPath:
+			// e.g. invalid credentials or a missing vhost, which are hard errors that
+			// do not set the recover flag, but cannot be fixed by reconnecting either.
+			return false
 		default:
 			// recoverability according to amqp091 is when
 			// the result can be changing by changing use rinput.
@@ -104,3 +217,51 @@ func recoverable(err error) bool {
 	// every other unknown error is recoverable
 	return true
 }
+
+// connectionLimitReached returns true in case the broker refused the connection, because a connection limit is reached.
+// RabbitMQ refuses such connections with NOT_ALLOWED, e.g. "NOT_ALLOWED - access to vhost '/' refused for user 'admin':
+// connection limit (10) is reached" for vhost limits or "user connection limit (10) is reached" for user limits.
+func connectionLimitReached(err error) bool {
+	ae := &amqp091.Error{}
+	if !errors.As(err, &ae) {
+		return false
+	}
+	switch ae.Code {
+	case amqp091.NotAllowed, amqp091.ConnectionForced:
+		return strings.Contains(strings.ToLower(ae.Reason), "connection limit")
+	default:
+		return false
+	}
+}
+
+// authRejected returns true in case the broker rejected the authentication of a connection, e.g. because it does not
+// support the requested SASL mechanism or rejected the credentials of the mechanism.
+func authRejected(err error) bool {
+	if connectionLimitReached(err) {
+		return false
+	}
+	ae := &amqp091.Error{}
+	if !errors.As(err, &ae) {
+		return false
+	}
+	switch ae.Code {
+	case amqp091.AccessRefused, amqp091.NotAllowed:
+		return true
+	default:
+		return false
+	}
+}
+
+// refused returns true in case the broker refused the connection for a reason that reconnecting cannot fix.
+func refused(err error) bool {
+	ae := &amqp091.Error{}
+	if !errors.As(err, &ae) {
+		return false
+	}
+	switch ae.Code {
+	case amqp091.AccessRefused, amqp091.NotAllowed, amqp091.InvalidPath:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,139 @@
+package pool
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/jxsl13/amqpx/logging"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoverableBrokerErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		err         error
+		recoverable bool
+		refused     bool
+	}{
+		{"connection forced", &amqp091.Error{Code: amqp091.ConnectionForced, Reason: "CONNECTION_FORCED - Closed via management plugin"}, true, false},
+		{"internal error", &amqp091.Error{Code: amqp091.InternalError, Reason: "INTERNAL_ERROR"}, true, false},
+		{"connection lost", amqp091.ErrClosed, true, false},
+		{"not allowed", &amqp091.Error{Code: amqp091.NotAllowed, Reason: "NOT_ALLOWED - vhost not found"}, false, true},
+		{"invalid path", &amqp091.Error{Code: amqp091.InvalidPath, Reason: "INVALID_PATH"}, false, true},
+		{"invalid credentials", amqp091.ErrCredentials, false, true},
+		{"vhost access", amqp091.ErrVhost, false, true},
+		{"sasl", amqp091.ErrSASL, false, true},
+		{"not implemented", &amqp091.Error{Code: amqp091.NotImplemented, Reason: "NOT_IMPLEMENTED"}, false, false},
+		{"access refused", fmt.Errorf("%v: %w: %w", ErrConnectionFailed, ErrAccessRefused, errors.New("refused")), false, false},
+		{"connection limit reached", fmt.Errorf("%v: %w: %w", ErrConnectionFailed, ErrConnectionLimitReached, errors.New("refused")), false, false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			wrapped := fmt.Errorf("%v: %w", ErrConnectionFailed, tt.err)
+			assert.Equal(t, tt.recoverable, recoverable(wrapped))
+			assert.Equal(t, tt.refused, refused(wrapped))
+		})
+	}
+}
+
+func TestConnectionLimitReached(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		err     error
+		reached bool
+	}{
+		{"vhost limit", &amqp091.Error{Code: amqp091.NotAllowed, Reason: "NOT_ALLOWED - access to vhost '/' refused for user 'admin': connection limit (10) is reached"}, true},
+		{"user limit", &amqp091.Error{Code: amqp091.NotAllowed, Reason: "NOT_ALLOWED - Connection refused for user 'admin': user connection limit (10) is reached"}, true},
+		{"vhost not found", &amqp091.Error{Code: amqp091.NotAllowed, Reason: "NOT_ALLOWED - vhost not found"}, false},
+		{"connection forced", &amqp091.Error{Code: amqp091.ConnectionForced, Reason: "CONNECTION_FORCED - Closed via management plugin"}, false},
+		{"other", errors.New("connection limit"), false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.reached, connectionLimitReached(fmt.Errorf("%v: %w", ErrConnectionFailed, tt.err)))
+		})
+	}
+}
+
+func TestConnectionLimitRefusal(t *testing.T) {
+	t.Parallel()
+
+	reason := "NOT_ALLOWED - access to vhost '/' refused for user 'admin': connection limit (1) is reached"
+	addr := newRefusingListener(t, amqp091.NotAllowed, reason)
+
+	c, err := newConnection(context.TODO(), fmt.Sprintf("amqp://admin:password@%s/", addr), "limited",
+		ConnectionWithLogger(logging.NewNoOpLogger()),
+	)
+	require.NoError(t, err)
+	defer c.Close()
+
+	err = c.Connect(context.TODO())
+	assert.ErrorIs(t, err, ErrConnectionLimitReached)
+	assert.NotErrorIs(t, err, ErrAccessRefused)
+	assert.Contains(t, err.Error(), "connection limit (1) is reached")
+	assert.False(t, recoverable(err), "connections must not be recovered until the limit is adjusted")
+}
+
+// newRefusingListener simulates a broker that refuses every connection with a connection.close frame
+// right after the protocol header.
+func newRefusingListener(t *testing.T, code uint16, reason string) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = l.Close()
+	})
+
+	// connection.close method frame on channel 0
+	payload := binary.BigEndian.AppendUint16(nil, 10) // connection class
+	payload = binary.BigEndian.AppendUint16(payload, 50)
+	payload = binary.BigEndian.AppendUint16(payload, code)
+	payload = append(payload, byte(len(reason)))
+	payload = append(payload, reason...)
+	payload = binary.BigEndian.AppendUint16(payload, 0) // failing class
+	payload = binary.BigEndian.AppendUint16(payload, 0) // failing method
+
+	frame := []byte{1, 0, 0} // method frame, channel 0
+	frame = binary.BigEndian.AppendUint32(frame, uint32(len(payload)))
+	frame = append(frame, payload...)
+	frame = append(frame, 0xCE) // frame end
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				header := make([]byte, 8)
+				_, err := io.ReadFull(conn, header)
+				if err != nil {
+					return
+				}
+				_, _ = conn.Write(frame)
+				// wait for the close-ok of the client
+				_, _ = io.Copy(io.Discard, conn)
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
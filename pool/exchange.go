@@ -61,6 +61,15 @@ const (
 		For the header RabbitMQ exchange type, “amq.headers” is the default topic exchange that AMQP brokers must supply.
 	*/
 	ExchangeKindHeaders ExchangeKind = "headers"
+
+	/*
+		ExchangeKindDelayedMessage is provided by the rabbitmq-delayed-message-exchange plugin
+		(reference: https://github.com/rabbitmq/rabbitmq-delayed-message-exchange).
+		A delayed message exchange holds back every message for the delay that is set in its x-delay header (see PublishingWithDelay)
+		and routes it afterwards like an exchange of the kind that is set in its x-delayed-type argument (see ExchangeWithDelayedType).
+		Delayed messages are only stored on a single node, which is why they may be lost in case that node goes down.
+	*/
+	ExchangeKindDelayedMessage ExchangeKind = "x-delayed-message"
 )
 
 const (
@@ -72,4 +81,80 @@ const (
 		This exchange has the capability of capturing messages that are not deliverable.
 	*/
 	ExchangeKeyDeadLetter = "x-dead-letter-exchange"
+
+	// ExchangeKeyDelayedType is the exchange argument that defines how a delayed message exchange routes messages after their delay.
+	ExchangeKeyDelayedType = "x-delayed-type"
 )
+
+// ExchangeArgOption modifies the arguments of an exchange declaration.
+type ExchangeArgOption func(Table)
+
+// ExchangeArgs creates a new argument table that can be passed to ExchangeDeclareOptions.Args.
+func ExchangeArgs(options ...ExchangeArgOption) Table {
+	args := Table{}
+	for _, o := range options {
+		o(args)
+	}
+	return args
+}
+
+// ExchangeWithDelayedType sets the kind of routing that a delayed message exchange uses once the delay of a message elapsed.
+// The exchange must be declared with ExchangeKindDelayedMessage.
+func ExchangeWithDelayedType(kind ExchangeKind) ExchangeArgOption {
+	return func(t Table) {
+		t[ExchangeKeyDelayedType] = string(kind)
+	}
+}
+
+// ExchangeDeclareOption modifies the options of an exchange declaration.
+type ExchangeDeclareOption func(*ExchangeDeclareOptions)
+
+// ExchangeOptions creates new declaration options with the defaults of ExchangeDeclare (durable exchanges)
+// that can be passed to ExchangeDeclare, e.g. ExchangeOptions(ExchangeWithInternal(true)).
+func ExchangeOptions(options ...ExchangeDeclareOption) ExchangeDeclareOptions {
+	o := ExchangeDeclareOptions{
+		Durable: true,
+	}
+	for _, opt := range options {
+		opt(&o)
+	}
+	return o
+}
+
+// ExchangeWithInternal declares an internal exchange, which is only reachable via exchange to exchange bindings,
+// e.g. for federation or shovel topologies.
+// Internal exchanges do not accept publishings. The broker closes the channel of a publisher with ACCESS_REFUSED,
+// which is not detected by Publish itself, but by awaiting the confirmation of the message or by the next operation
+// of the session.
+func ExchangeWithInternal(internal bool) ExchangeDeclareOption {
+	return func(o *ExchangeDeclareOptions) {
+		o.Internal = internal
+	}
+}
+
+// ExchangeWithArgs sets the arguments of an exchange declaration, see ExchangeArgs.
+func ExchangeWithArgs(options ...ExchangeArgOption) ExchangeDeclareOption {
+	return func(o *ExchangeDeclareOptions) {
+		o.Args = ExchangeArgs(options...)
+	}
+}
+
+// delayedExchangeDeclareOptions returns the declaration options of a delayed message exchange which routes messages
+// like an exchange of the passed kind. The passed arguments are copied and not modified.
+func delayedExchangeDeclareOptions(kind ExchangeKind, option ...ExchangeDeclareOptions) ExchangeDeclareOptions {
+	// same defaults as ExchangeDeclare
+	o := ExchangeDeclareOptions{
+		Durable: true,
+	}
+	if len(option) > 0 {
+		o = option[0]
+	}
+
+	args := make(Table, len(o.Args)+1)
+	for k, v := range o.Args {
+		args[k] = v
+	}
+	ExchangeWithDelayedType(kind)(args)
+	o.Args = args
+	return o
+}
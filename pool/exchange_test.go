@@ -0,0 +1,43 @@
+package pool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDelayedExchangeDeclareOptions(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, Table{ExchangeKeyDelayedType: "direct"}, ExchangeArgs(ExchangeWithDelayedType(ExchangeKindDirect)))
+
+	// defaults of ExchangeDeclare
+	o := delayedExchangeDeclareOptions(ExchangeKindTopic)
+	assert.True(t, o.Durable)
+	assert.Equal(t, Table{ExchangeKeyDelayedType: "topic"}, o.Args)
+
+	// passed arguments are kept but not modified
+	args := Table{ExchangeKeyDeadLetter: "dlx"}
+	o = delayedExchangeDeclareOptions(ExchangeKindFanOut, ExchangeDeclareOptions{AutoDelete: true, Args: args})
+	assert.False(t, o.Durable)
+	assert.True(t, o.AutoDelete)
+	assert.Equal(t, Table{ExchangeKeyDeadLetter: "dlx", ExchangeKeyDelayedType: "fanout"}, o.Args)
+	assert.Equal(t, Table{ExchangeKeyDeadLetter: "dlx"}, args)
+}
+
+func TestExchangeWithInternal(t *testing.T) {
+	t.Parallel()
+
+	// defaults of ExchangeDeclare
+	o := ExchangeOptions()
+	assert.True(t, o.Durable)
+	assert.False(t, o.Internal)
+
+	o = ExchangeOptions(ExchangeWithInternal(true), ExchangeWithArgs(ExchangeWithDelayedType(ExchangeKindFanOut)))
+	assert.True(t, o.Durable)
+	assert.True(t, o.Internal)
+	assert.Equal(t, Table{ExchangeKeyDelayedType: "fanout"}, o.Args)
+
+	o = delayedExchangeDeclareOptions(ExchangeKindTopic, o)
+	assert.True(t, o.Internal, "delayed exchanges keep the internal flag")
+}
@@ -0,0 +1,35 @@
+package pool
+
+import (
+	"expvar"
+	"fmt"
+	"sync"
+)
+
+// expvarMu serializes the check for existing variables and their registration, as expvar.Publish panics on duplicates.
+var expvarMu sync.Mutex
+
+// PublishExpvar registers the statistics of the connection pool as expvar variables under the given name,
+// which are served as JSON at /debug/vars in case the expvar handler is registered.
+// The published values are evaluated whenever they are read.
+// Variables cannot be unregistered, which is why a name can only be published once.
+// ErrExpvarExists is returned in case the name is already in use, e.g. by another pool.
+func (cp *ConnectionPool) PublishExpvar(name string) error {
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+
+	if expvar.Get(name) != nil {
+		return fmt.Errorf("failed to publish connection pool %s: %w: %s", cp.name, ErrExpvarExists, name)
+	}
+
+	m := new(expvar.Map).Init()
+	m.Set("capacity", expvar.Func(func() any { return cp.Capacity() }))
+	m.Set("idle", expvar.Func(func() any { return cp.Size() }))
+	m.Set("active", expvar.Func(func() any { return cp.StatCachedActive() }))
+	m.Set("transient", expvar.Func(func() any { return cp.StatTransientActive() }))
+	m.Set("recoveries", expvar.Func(func() any { return cp.recoveries.Load() }))
+	m.Set("acquisitions", expvar.Func(func() any { return cp.acquisitions.stats() }))
+
+	expvar.Publish(name, m)
+	return nil
+}
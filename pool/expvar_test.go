@@ -0,0 +1,48 @@
+package pool
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishExpvar(t *testing.T) {
+	t.Parallel()
+
+	cp := &ConnectionPool{
+		name:        "TestPublishExpvar",
+		capacity:    2,
+		connections: make(chan *Connection, 2),
+	}
+	cp.connections <- &Connection{}
+
+	name := "amqpx.test.publish_expvar"
+	require.NoError(t, cp.PublishExpvar(name))
+
+	// values are evaluated whenever they are read
+	cp.recoveries.Add(1)
+	cp.acquisitions.observe(AcquisitionPathCached, nil)
+
+	var vars struct {
+		Capacity     int
+		Idle         int
+		Active       int
+		Transient    int
+		Recoveries   uint64
+		Acquisitions AcquisitionStats
+	}
+	require.NoError(t, json.Unmarshal([]byte(expvar.Get(name).String()), &vars))
+	assert.Equal(t, 2, vars.Capacity)
+	assert.Equal(t, 1, vars.Idle)
+	assert.Equal(t, 1, vars.Active)
+	assert.Equal(t, 0, vars.Transient)
+	assert.Equal(t, uint64(1), vars.Recoveries)
+	assert.Equal(t, uint64(1), vars.Acquisitions.CachedAcquired)
+
+	// names are global
+	other := &ConnectionPool{name: "other", capacity: 1, connections: make(chan *Connection, 1)}
+	assert.ErrorIs(t, other.PublishExpvar(name), ErrExpvarExists)
+}
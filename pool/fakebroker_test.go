@@ -0,0 +1,361 @@
+package pool
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newSilentListener accepts connections without ever responding, which stalls the AMQP handshake.
+func newSilentListener(t *testing.T) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var (
+		mu    sync.Mutex
+		conns []net.Conn
+	)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			conns = append(conns, conn)
+			mu.Unlock()
+		}
+	}()
+	t.Cleanup(func() {
+		_ = l.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+	})
+	return fmt.Sprintf("amqp://admin:password@%s/", l.Addr().String())
+}
+
+func newFakeBrokerAddr(t *testing.T) string {
+	addr, _ := newFakeBroker(t, "PLAIN")
+	return addr
+}
+
+// newSlowHandshakeProxy accepts TCP connections immediately and forwards them to the target
+// after the passed delay, which delays the AMQP handshake.
+func newSlowHandshakeProxy(t *testing.T, target string, delay time.Duration) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var (
+		done = make(chan struct{})
+		wg   sync.WaitGroup
+	)
+	t.Cleanup(func() {
+		_ = l.Close()
+		close(done)
+		wg.Wait()
+	})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer conn.Close()
+
+				select {
+				case <-time.After(delay):
+				case <-done:
+					return
+				}
+
+				upstream, err := net.Dial("tcp", target)
+				if err != nil {
+					return
+				}
+				defer upstream.Close()
+
+				go func() {
+					<-done
+					_ = conn.Close()
+					_ = upstream.Close()
+				}()
+				go func() {
+					_, _ = io.Copy(upstream, conn)
+					_ = upstream.Close()
+				}()
+				_, _ = io.Copy(conn, upstream)
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+// newConnectionNameRecorder forwards TCP connections to the target and records the connection name
+// that the clients send to the broker with their client properties upon every connect.
+func newConnectionNameRecorder(t *testing.T, target string) (addr string, names func() []string) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var (
+		done     = make(chan struct{})
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		recorded []string
+	)
+	t.Cleanup(func() {
+		_ = l.Close()
+		close(done)
+		wg.Wait()
+	})
+
+	// connection_name is a long string field of the client properties
+	key := append([]byte{byte(len("connection_name"))}, "connection_name"...)
+	key = append(key, 'S')
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer conn.Close()
+
+				upstream, err := net.Dial("tcp", target)
+				if err != nil {
+					return
+				}
+				defer upstream.Close()
+
+				go func() {
+					<-done
+					_ = conn.Close()
+					_ = upstream.Close()
+				}()
+				go func() {
+					_, _ = io.Copy(conn, upstream)
+					_ = conn.Close()
+				}()
+
+				// the broker responds to the protocol header with connection.start
+				protocol := make([]byte, 8)
+				_, err = io.ReadFull(conn, protocol)
+				if err != nil {
+					return
+				}
+				_, err = upstream.Write(protocol)
+				if err != nil {
+					return
+				}
+
+				// frame of connection.start-ok
+				header := make([]byte, 7)
+				_, err = io.ReadFull(conn, header)
+				if err != nil {
+					return
+				}
+				payload := make([]byte, binary.BigEndian.Uint32(header[3:])+1) // including frame end
+				_, err = io.ReadFull(conn, payload)
+				if err != nil {
+					return
+				}
+				if i := bytes.Index(payload, key); i >= 0 {
+					value := payload[i+len(key):]
+					n := binary.BigEndian.Uint32(value)
+					mu.Lock()
+					recorded = append(recorded, string(value[4:4+n]))
+					mu.Unlock()
+				}
+
+				_, err = upstream.Write(append(header, payload...))
+				if err != nil {
+					return
+				}
+				_, _ = io.Copy(upstream, conn)
+			}()
+		}
+	}()
+
+	return l.Addr().String(), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), recorded...)
+	}
+}
+
+// newFakeBroker simulates a broker that supports the EXTERNAL and PLAIN mechanisms, but only accepts the
+// authentication with the passed mechanism. Authenticated connections may open and close channels.
+// It returns the mechanisms of all authentication attempts.
+// fakeBrokerMissingQueue is a queue that does not exist on the fake broker, consuming it closes the channel.
+const fakeBrokerMissingQueue = "missing"
+
+func newFakeBroker(t *testing.T, accept string) (addr string, mechanisms func() []string) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = l.Close()
+	})
+
+	var (
+		mu       sync.Mutex
+		attempts []string
+	)
+
+	method := func(channel, class, method uint16, args []byte) []byte {
+		payload := binary.BigEndian.AppendUint16(nil, class)
+		payload = binary.BigEndian.AppendUint16(payload, method)
+		payload = append(payload, args...)
+
+		frame := binary.BigEndian.AppendUint16([]byte{1}, channel) // method frame
+		frame = binary.BigEndian.AppendUint32(frame, uint32(len(payload)))
+		frame = append(frame, payload...)
+		return append(frame, 0xCE) // frame end
+	}
+	longstr := func(b []byte, s string) []byte {
+		b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+		return append(b, s...)
+	}
+	readMethod := func(conn net.Conn) (channel, class, method uint16, args []byte, err error) {
+		for {
+			header := make([]byte, 7)
+			_, err = io.ReadFull(conn, header)
+			if err != nil {
+				return 0, 0, 0, nil, err
+			}
+			payload := make([]byte, binary.BigEndian.Uint32(header[3:])+1) // including frame end
+			_, err = io.ReadFull(conn, payload)
+			if err != nil {
+				return 0, 0, 0, nil, err
+			}
+			if header[0] != 1 {
+				continue // e.g. heartbeats
+			}
+			return binary.BigEndian.Uint16(header[1:]), binary.BigEndian.Uint16(payload), binary.BigEndian.Uint16(payload[2:]), payload[4 : len(payload)-1], nil
+		}
+	}
+
+	start := []byte{0, 9}                           // version
+	start = binary.BigEndian.AppendUint32(start, 0) // server properties
+	start = longstr(start, "PLAIN EXTERNAL")
+	start = longstr(start, "en_US")
+
+	tune := binary.BigEndian.AppendUint16(nil, 0) // channel max
+	tune = binary.BigEndian.AppendUint32(tune, 0) // frame max
+	tune = binary.BigEndian.AppendUint16(tune, 0) // heartbeat
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				header := make([]byte, 8)
+				_, err := io.ReadFull(conn, header)
+				if err != nil {
+					return
+				}
+				_, err = conn.Write(method(0, 10, 10, start))
+				if err != nil {
+					return
+				}
+
+				// connection.start-ok: client properties, mechanism, ...
+				_, _, _, args, err := readMethod(conn)
+				if err != nil {
+					return
+				}
+				args = args[4+binary.BigEndian.Uint32(args):]
+				mechanism := string(args[1 : 1+args[0]])
+
+				mu.Lock()
+				attempts = append(attempts, mechanism)
+				mu.Unlock()
+
+				if mechanism != accept {
+					// the broker closes the socket in case the authentication fails
+					return
+				}
+
+				_, err = conn.Write(method(0, 10, 30, tune))
+				if err != nil {
+					return
+				}
+				for {
+					channel, class, m, args, err := readMethod(conn)
+					if err != nil {
+						return
+					}
+					switch {
+					case class == 10 && m == 40: // connection.open
+						_, err = conn.Write(method(0, 10, 41, []byte{0}))
+					case class == 10 && m == 50: // connection.close
+						_, _ = conn.Write(method(0, 10, 51, nil))
+						return
+					case class == 20 && m == 10: // channel.open
+						_, err = conn.Write(method(channel, 20, 11, []byte{0, 0, 0, 0}))
+					case class == 20 && m == 40: // channel.close
+						_, err = conn.Write(method(channel, 20, 41, nil))
+					case class == 60 && m == 10: // basic.qos
+						_, err = conn.Write(method(channel, 60, 11, nil))
+					case class == 60 && m == 20: // basic.consume: reserved, queue, consumer tag, ...
+						queue := string(args[3 : 3+args[2]])
+						args = args[3+args[2]:]
+						tag := args[:1+args[0]]
+						if queue == fakeBrokerMissingQueue {
+							// channel.close: reply code, reply text, class, method
+							closeArgs := binary.BigEndian.AppendUint16(nil, 404)
+							closeArgs = append(closeArgs, byte(len("NOT_FOUND")))
+							closeArgs = append(closeArgs, "NOT_FOUND"...)
+							closeArgs = binary.BigEndian.AppendUint16(closeArgs, class)
+							closeArgs = binary.BigEndian.AppendUint16(closeArgs, m)
+							_, err = conn.Write(method(channel, 20, 40, closeArgs))
+						} else {
+							_, err = conn.Write(method(channel, 60, 21, tag))
+						}
+					case class == 60 && m == 30: // basic.cancel
+						_, err = conn.Write(method(channel, 60, 31, args[:1+args[0]]))
+					}
+					if err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+
+	return l.Addr().String(), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), attempts...)
+	}
+}
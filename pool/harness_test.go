@@ -0,0 +1,73 @@
+package pool_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jxsl13/amqpx/internal/testutils"
+	"github.com/jxsl13/amqpx/logging"
+	"github.com/jxsl13/amqpx/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHarness(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx          = context.TODO()
+		poolName     = testutils.FuncName()
+		nextConnName = testutils.ConnectionNameGenerator()
+	)
+
+	hs, hsclose := NewSession(t, ctx, testutils.HealthyConnectURL, nextConnName())
+	defer hsclose()
+
+	var (
+		nextExchangeName = testutils.ExchangeNameGenerator(hs.Name())
+		nextQueueName    = testutils.QueueNameGenerator(hs.Name())
+		exchangeName     = nextExchangeName()
+		queueName        = nextQueueName()
+	)
+	cleanup := DeclareExchangeQueue(t, ctx, hs, exchangeName, queueName)
+	defer cleanup()
+
+	h, err := pool.NewHarness(ctx, pool.HarnessConfig{
+		URL:         testutils.HealthyConnectURL,
+		Connections: 1,
+		Sessions:    2,
+		Options: []pool.Option{
+			pool.WithName(poolName),
+			pool.WithLogger(logging.NewTestLogger(t)),
+		},
+	})
+	require.NoError(t, err)
+	defer h.Close()
+
+	received := make(chan pool.Delivery, 1)
+	h.Consumer().RegisterHandlerFunc(queueName, func(ctx context.Context, msg pool.Delivery) error {
+		received <- msg
+		return nil
+	}, pool.ConsumeOptions{ConsumerTag: testutils.ConsumerNameGenerator(queueName)()})
+	require.NoError(t, h.Consumer().Start(ctx))
+
+	err = h.Publisher().Publish(ctx, exchangeName, "", pool.Publishing{Body: []byte("harness")})
+	require.NoError(t, err)
+
+	select {
+	case msg := <-received:
+		assert.Equal(t, "harness", string(msg.Body))
+	case <-time.After(10 * time.Second):
+		require.Fail(t, "expected message to be consumed")
+	}
+
+	// tears down everything and must not block when called twice
+	h.Close()
+	h.Close()
+
+	err = h.Publisher().Publish(ctx, exchangeName, "", pool.Publishing{Body: []byte("closed")})
+	assert.Error(t, err)
+	_, err = h.Pool().GetSession(ctx)
+	assert.Error(t, err)
+}
@@ -0,0 +1,112 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// HealthStatus is the overall status of a connection pool, see ConnectionPool.Health.
+type HealthStatus string
+
+const (
+	// HealthStatusHealthy means that all cached connections are healthy.
+	HealthStatusHealthy HealthStatus = "healthy"
+	// HealthStatusDegraded means that some cached connections are flagged or recovering,
+	// but at least one connection is healthy.
+	HealthStatusDegraded HealthStatus = "degraded"
+	// HealthStatusUnhealthy means that no cached connection is healthy or that the pool was closed.
+	HealthStatusUnhealthy HealthStatus = "unhealthy"
+)
+
+// HealthReport is a summary of the health of a connection pool, which can be serialized
+// directly into the JSON response of a /healthz handler.
+type HealthReport struct {
+	Status HealthStatus `json:"status"`
+	// Healthy is the number of cached connections that are connected and not flagged.
+	// Connections that are blocked by the broker are considered healthy, see Blocked,
+	// as well as idle connections that were released by the idle reaper, as they are reopened on demand.
+	Healthy int `json:"healthy"`
+	// Flagged is the number of cached connections that are flagged, recovering or closed.
+	Flagged int `json:"flagged"`
+	// Blocked is true in case the broker currently blocks at least one connection of the pool.
+	Blocked bool `json:"blocked"`
+	// LastConnectedAt is the latest point in time at which a cached connection was (re)established
+	// or the zero time in case no connection was ever established.
+	LastConnectedAt time.Time `json:"lastConnectedAt"`
+}
+
+// Health returns a summary of the health of the cached connections of the pool,
+// e.g. for liveness or readiness probes.
+// Like Snapshot, it does not check out any connection and does not block on busy connections.
+func (cp *ConnectionPool) Health() HealthReport {
+	report := HealthReport{
+		Blocked: cp.IsBlocked(),
+	}
+
+	for _, info := range cp.Snapshot() {
+		if info.ConnectedAt.After(report.LastConnectedAt) {
+			report.LastConnectedAt = info.ConnectedAt
+		}
+
+		switch {
+		case info.Flagged:
+			report.Flagged++
+		case info.State == ConnectionStateConnected, info.State == ConnectionStateBlocked,
+			info.State == ConnectionStateReleased:
+			report.Healthy++
+		default:
+			report.Flagged++
+		}
+	}
+
+	switch {
+	case cp.ctx.Err() != nil, report.Healthy == 0:
+		report.Status = HealthStatusUnhealthy
+	case report.Flagged > 0:
+		report.Status = HealthStatusDegraded
+	default:
+		report.Status = HealthStatusHealthy
+	}
+	return report
+}
+
+// WaitReadyWithBackoff polls the health of the pool until all cached connections are healthy,
+// e.g. during startup or after a broker outage. onAttempt is called after every poll with the number of healthy
+// connections and the number of connections that are required to be healthy, which is the capacity of the pool,
+// e.g. in order to log the progress or to drive a readiness gauge. onAttempt may be nil.
+// The polling interval grows exponentially up to one second.
+// In case ctx is done or the pool is closed before all connections are healthy, the last health report is returned
+// together with an error.
+func (cp *ConnectionPool) WaitReadyWithBackoff(ctx context.Context, onAttempt func(healthy, target int)) (HealthReport, error) {
+	var (
+		target  = cp.Capacity()
+		backoff = newDefaultBackoffPolicy(10*time.Millisecond, time.Second)
+		timer   = time.NewTimer(0)
+		drained = false
+	)
+	defer closeTimer(timer, &drained)
+
+	for try := 0; ; try++ {
+		report := cp.Health()
+		if onAttempt != nil {
+			_ = callSafe(cp.logger(), "wait ready callback", ErrCallbackPanic, func() error {
+				onAttempt(report.Healthy, target)
+				return nil
+			})
+		}
+		if report.Healthy >= target && report.Status != HealthStatusUnhealthy {
+			return report, nil
+		}
+
+		resetTimer(timer, backoff(try), &drained)
+		select {
+		case <-ctx.Done():
+			return report, fmt.Errorf("connection pool %s not ready: %d of %d connections healthy: %w", cp.name, report.Healthy, target, ctx.Err())
+		case <-cp.catchShutdown():
+			return report, fmt.Errorf("connection pool %s not ready: %w", cp.name, context.Cause(cp.ctx))
+		case <-timer.C:
+			drained = true
+		}
+	}
+}
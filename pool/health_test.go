@@ -0,0 +1,134 @@
+package pool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jxsl13/amqpx/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectionPoolHealth(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	cp := &ConnectionPool{
+		name:    "TestConnectionPoolHealth",
+		ctx:     ctx,
+		blocked: newBlockedAggregate("TestConnectionPoolHealth", nil, nil),
+	}
+
+	connectedAt := time.Now().Add(-time.Minute)
+	for i := 0; i < 3; i++ {
+		c, err := newConnection(ctx, testConnectURL, fmt.Sprintf("health-%d", i))
+		require.NoError(t, err)
+		defer c.Close()
+
+		c.setState(ConnectionStateConnected)
+		c.connectedAt.Store(connectedAt.Add(time.Duration(i) * time.Second).UnixNano())
+		cp.cached = append(cp.cached, c)
+	}
+
+	report := cp.Health()
+	assert.Equal(t, HealthReport{
+		Status:          HealthStatusHealthy,
+		Healthy:         3,
+		LastConnectedAt: time.Unix(0, cp.cached[2].connectedAt.Load()),
+	}, report)
+
+	// blocked connections are alive
+	cp.cached[0].blocked.Store(true)
+	cp.blocked.update("health-0", true)
+	report = cp.Health()
+	assert.Equal(t, HealthStatusHealthy, report.Status)
+	assert.True(t, report.Blocked)
+
+	cp.cached[1].flagged = true
+	cp.cached[2].setState(ConnectionStateRecovering)
+	report = cp.Health()
+	assert.Equal(t, HealthStatusDegraded, report.Status)
+	assert.Equal(t, 1, report.Healthy)
+	assert.Equal(t, 2, report.Flagged)
+
+	cp.cached[0].setState(ConnectionStateRecovering)
+	report = cp.Health()
+	assert.Equal(t, HealthStatusUnhealthy, report.Status)
+	assert.Equal(t, 0, report.Healthy)
+	assert.Equal(t, 3, report.Flagged)
+
+	// a closed pool is unhealthy, even if its connections are
+	cp.cached[0].setState(ConnectionStateConnected)
+	cp.cached[1].flagged = false
+	cp.cached[2].setState(ConnectionStateConnected)
+	require.Equal(t, HealthStatusHealthy, cp.Health().Status)
+	cancel()
+	report = cp.Health()
+	assert.Equal(t, HealthStatusUnhealthy, report.Status)
+
+	data, err := json.Marshal(report)
+	require.NoError(t, err)
+
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "unhealthy", decoded["status"])
+	assert.Equal(t, float64(3), decoded["healthy"])
+	assert.Equal(t, float64(0), decoded["flagged"])
+	assert.Equal(t, true, decoded["blocked"])
+	assert.Contains(t, decoded, "lastConnectedAt")
+}
+
+func TestConnectionPoolWaitReadyWithBackoff(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.TODO()
+	cp := &ConnectionPool{
+		name:     "TestConnectionPoolWaitReadyWithBackoff",
+		capacity: 3,
+		ctx:      ctx,
+		blocked:  newBlockedAggregate("TestConnectionPoolWaitReadyWithBackoff", nil, nil),
+		log:      logging.NewNoOpLogger(),
+	}
+	for i := 0; i < cp.capacity; i++ {
+		c, err := newConnection(ctx, testConnectURL, fmt.Sprintf("ready-%d", i))
+		require.NoError(t, err)
+		defer c.Close()
+		c.setState(ConnectionStateRecovering)
+		cp.cached = append(cp.cached, c)
+	}
+
+	// connections come up one after another
+	var attempts []int
+	report, err := cp.WaitReadyWithBackoff(ctx, func(healthy, target int) {
+		assert.Equal(t, 3, target)
+		attempts = append(attempts, healthy)
+		if healthy < target {
+			cp.cached[healthy].setState(ConnectionStateConnected)
+		}
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2, 3}, attempts)
+	assert.Equal(t, HealthStatusHealthy, report.Status)
+
+	// the last state is returned in case the pool does not become ready in time
+	cp.cached[1].setState(ConnectionStateRecovering)
+	timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+
+	attempts = attempts[:0]
+	report, err = cp.WaitReadyWithBackoff(timeoutCtx, func(healthy, target int) {
+		attempts = append(attempts, healthy)
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 2, report.Healthy)
+	assert.Equal(t, HealthStatusDegraded, report.Status)
+	assert.NotEmpty(t, attempts)
+	for _, healthy := range attempts {
+		assert.Equal(t, 2, healthy)
+	}
+}
@@ -0,0 +1,114 @@
+package pool
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// AcquisitionPath distinguishes connections and sessions that are taken from the cache of a pool
+// from transient connections and sessions that are created on demand.
+// Transient acquisitions are much more expensive, as they require a new connection to the broker.
+type AcquisitionPath string
+
+const (
+	AcquisitionPathCached    AcquisitionPath = "cached"
+	AcquisitionPathTransient AcquisitionPath = "transient"
+)
+
+// MetricsCollector is notified about pool operations in order to export them as metrics, e.g. to Prometheus.
+// The pool name and the acquisition path are supposed to be used as metric labels.
+// Implementations must be safe for concurrent use and must not block.
+// Implementations may additionally implement ConfirmMetricsCollector.
+type MetricsCollector interface {
+	// ConnectionAcquired is called whenever GetConnection or GetTransientConnection returns.
+	// err is nil in case a connection could be acquired.
+	ConnectionAcquired(pool string, path AcquisitionPath, wait time.Duration, err error)
+
+	// SessionAcquired is called whenever GetSession, TryGetSession or GetTransientSession returns.
+	// err is nil in case a session could be acquired and ErrPoolExhausted in case TryGetSession
+	// could not acquire an idle session without blocking.
+	SessionAcquired(pool string, path AcquisitionPath, wait time.Duration, err error)
+}
+
+// ConfirmMetricsCollector may be implemented by a MetricsCollector in addition in order to be notified about
+// every publisher confirmation of the sessions of a pool, e.g. in order to export the nack rate.
+// A rising nack rate is an urgent signal, as nacks usually mean that the broker could not persist messages,
+// e.g. due to disk issues of durable queues.
+type ConfirmMetricsCollector interface {
+	// PublishConfirmed is called whenever a session receives an ack (ack is true) or a nack (ack is false).
+	PublishConfirmed(pool string, ack bool)
+}
+
+// ConfirmStats contains the number of publisher confirmations that were received from the broker.
+type ConfirmStats struct {
+	Acks  uint64
+	Nacks uint64
+}
+
+// NackRate returns the ratio of nacks to all received confirmations or 0 in case no confirmation was received.
+func (cs ConfirmStats) NackRate() float64 {
+	total := cs.Acks + cs.Nacks
+	if total == 0 {
+		return 0
+	}
+	return float64(cs.Nacks) / float64(total)
+}
+
+// confirmCounter counts acks and nacks of publisher confirmations.
+type confirmCounter struct {
+	acks  atomic.Uint64
+	nacks atomic.Uint64
+}
+
+func (cc *confirmCounter) observe(ack bool) {
+	if ack {
+		cc.acks.Add(1)
+	} else {
+		cc.nacks.Add(1)
+	}
+}
+
+func (cc *confirmCounter) stats() ConfirmStats {
+	return ConfirmStats{
+		Acks:  cc.acks.Load(),
+		Nacks: cc.nacks.Load(),
+	}
+}
+
+// AcquisitionStats contains the number of successful and failed acquisitions of connections or sessions.
+type AcquisitionStats struct {
+	CachedAcquired         uint64
+	CachedAcquireErrors    uint64
+	TransientAcquired      uint64
+	TransientAcquireErrors uint64
+}
+
+// acquisitionCounter counts acquisitions separately for cached and transient connections or sessions.
+type acquisitionCounter struct {
+	cachedAcquired         atomic.Uint64
+	cachedAcquireErrors    atomic.Uint64
+	transientAcquired      atomic.Uint64
+	transientAcquireErrors atomic.Uint64
+}
+
+func (ac *acquisitionCounter) observe(path AcquisitionPath, err error) {
+	switch {
+	case path == AcquisitionPathCached && err == nil:
+		ac.cachedAcquired.Add(1)
+	case path == AcquisitionPathCached:
+		ac.cachedAcquireErrors.Add(1)
+	case err == nil:
+		ac.transientAcquired.Add(1)
+	default:
+		ac.transientAcquireErrors.Add(1)
+	}
+}
+
+func (ac *acquisitionCounter) stats() AcquisitionStats {
+	return AcquisitionStats{
+		CachedAcquired:         ac.cachedAcquired.Load(),
+		CachedAcquireErrors:    ac.cachedAcquireErrors.Load(),
+		TransientAcquired:      ac.transientAcquired.Load(),
+		TransientAcquireErrors: ac.transientAcquireErrors.Load(),
+	}
+}
@@ -0,0 +1,123 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquisitionCounter(t *testing.T) {
+	t.Parallel()
+
+	var ac acquisitionCounter
+	ac.observe(AcquisitionPathTransient, nil)
+	ac.observe(AcquisitionPathTransient, nil)
+	ac.observe(AcquisitionPathTransient, errors.New("transient error"))
+	ac.observe(AcquisitionPathCached, nil)
+
+	assert.Equal(t, AcquisitionStats{
+		CachedAcquired:         1,
+		CachedAcquireErrors:    0,
+		TransientAcquired:      2,
+		TransientAcquireErrors: 1,
+	}, ac.stats())
+}
+
+func TestConnectionAges(t *testing.T) {
+	t.Parallel()
+
+	var (
+		now      = time.Now()
+		conns    = make([]*Connection, 0, 4)
+		connAges = []time.Duration{time.Second, 3 * time.Second, 5 * time.Second}
+	)
+	for _, age := range connAges {
+		conn := &Connection{}
+		conn.connectedAt.Store(now.Add(-age).UnixNano())
+		conns = append(conns, conn)
+	}
+	// never connected
+	conns = append(conns, &Connection{})
+
+	minAge, maxAge, avgAge := connectionAges(conns, now)
+	assert.Equal(t, time.Second, minAge)
+	assert.Equal(t, 5*time.Second, maxAge)
+	assert.Equal(t, 3*time.Second, avgAge)
+
+	minAge, maxAge, avgAge = connectionAges(conns[3:], now)
+	assert.Zero(t, minAge)
+	assert.Zero(t, maxAge)
+	assert.Zero(t, avgAge)
+}
+
+type confirmCollector struct {
+	MetricsCollector
+	acks  map[string]int
+	nacks map[string]int
+}
+
+func (cc *confirmCollector) PublishConfirmed(pool string, ack bool) {
+	if ack {
+		cc.acks[pool]++
+	} else {
+		cc.nacks[pool]++
+	}
+}
+
+func TestSessionPoolConfirmStats(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx       = context.TODO()
+		collector = &confirmCollector{acks: map[string]int{}, nacks: map[string]int{}}
+		sp        = &SessionPool{
+			pool:    &ConnectionPool{name: "confirms"},
+			metrics: collector,
+		}
+	)
+
+	conn, err := newConnection(ctx, testConnectURL, "confirms")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// confirm stub which returns a mix of acks and nacks
+	acks := []bool{true, false, true, true, false}
+	s := &Session{
+		name:            "confirms",
+		confirmable:     true,
+		conn:            conn,
+		ctx:             ctx,
+		confirms:        make(chan amqp091.Confirmation, len(acks)+2),
+		confirmObserver: sp.observeConfirm,
+	}
+	for i, ack := range acks {
+		s.confirms <- amqp091.Confirmation{DeliveryTag: uint64(i + 1), Ack: ack}
+	}
+
+	for i, ack := range acks {
+		err := s.AwaitConfirm(ctx, uint64(i+1))
+		if ack {
+			assert.NoError(t, err)
+		} else {
+			assert.ErrorIs(t, err, ErrNack)
+		}
+	}
+
+	// confirmations that are discarded instead of awaited are counted as well
+	s.confirms <- amqp091.Confirmation{DeliveryTag: 6, Ack: true}
+	s.confirms <- amqp091.Confirmation{DeliveryTag: 7, Ack: false}
+	s.Flush()
+
+	stats := sp.Stats().Confirms
+	assert.Equal(t, ConfirmStats{Acks: 4, Nacks: 3}, stats)
+	assert.InDelta(t, 3.0/7.0, stats.NackRate(), 1e-9)
+	assert.Equal(t, map[string]int{"confirms": 4}, collector.acks)
+	assert.Equal(t, map[string]int{"confirms": 3}, collector.nacks)
+
+	assert.Zero(t, ConfirmStats{}.NackRate())
+}
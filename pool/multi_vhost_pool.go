@@ -0,0 +1,336 @@
+package pool
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/jxsl13/amqpx/logging"
+)
+
+// MultiVHostPool maintains a small pool of connections and sessions for every vhost that is in use,
+// e.g. for multi tenant applications that use a vhost per tenant.
+// The pool of a vhost is created lazily upon the first GetSession call for that vhost and
+// closed when the vhost was not used for the idle timeout or when it is the least recently used vhost
+// and the maximum number of vhosts is exceeded.
+// All vhosts share the same broker, credentials and settings.
+type MultiVHostPool struct {
+	url     *url.URL
+	options []Option
+
+	connsPerVHost    int
+	sessionsPerVHost int
+	maxVHosts        int
+	idleTimeout      time.Duration
+
+	mu     sync.Mutex
+	closed bool
+	vhosts map[string]*list.Element
+	// most recently used vhost at the front
+	lru      *list.List
+	borrowed map[*Session]*vhostEntry
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	log logging.Logger
+}
+
+type vhostEntry struct {
+	vhost string
+
+	// closed as soon as the pool was created
+	ready chan struct{}
+	pool  *Pool
+	err   error
+
+	// number of borrowed sessions and pending GetSession calls
+	active   int
+	lastUsed time.Time
+}
+
+// close closes the pool of the vhost as soon as it was created.
+func (e *vhostEntry) close() {
+	<-e.ready
+	if e.pool != nil {
+		e.pool.Close()
+	}
+}
+
+// NewMultiVHostPool creates a pool that connects to the broker of the connect url with a separate set
+// of connections for every vhost that is passed to GetSession. The vhost of the connect url is ignored.
+func NewMultiVHostPool(ctx context.Context, connectUrl string, options ...MultiVHostPoolOption) (*MultiVHostPool, error) {
+	// use sane defaults
+	option := multiVHostPoolOption{
+		ConnectionsPerVHost: 1,
+		MaxVHosts:           16,
+		IdleTimeout:         5 * time.Minute,
+
+		Logger: defaultLogger(),
+	}
+
+	for _, o := range options {
+		o(&option)
+	}
+
+	if option.SessionsPerVHost < 1 {
+		option.SessionsPerVHost = option.ConnectionsPerVHost
+	}
+
+	// tls settings are validated by the pools of the vhosts
+	u, err := parseURL(connectUrl, false)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cc := context.WithCancelCause(ctx)
+	cancel := toCancelFunc(fmt.Errorf("multi vhost pool %w", ErrClosed), cc)
+
+	mp := &MultiVHostPool{
+		url:     u,
+		options: append([]Option{WithLogger(option.Logger)}, option.PoolOptions...),
+
+		connsPerVHost:    option.ConnectionsPerVHost,
+		sessionsPerVHost: option.SessionsPerVHost,
+		maxVHosts:        option.MaxVHosts,
+		idleTimeout:      option.IdleTimeout,
+
+		vhosts:   make(map[string]*list.Element),
+		lru:      list.New(),
+		borrowed: make(map[*Session]*vhostEntry),
+
+		ctx:    ctx,
+		cancel: cancel,
+
+		log: option.Logger,
+	}
+
+	mp.wg.Add(1)
+	go mp.evictIdle()
+
+	return mp, nil
+}
+
+// GetSession returns a session of the passed vhost.
+// The connections of the vhost are established upon the first call for that vhost.
+// The session must be returned via ReturnSession.
+func (mp *MultiVHostPool) GetSession(ctx context.Context, vhost string) (*Session, error) {
+	e, err := mp.acquire(ctx, vhost)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := e.pool.GetSession(ctx)
+	if err != nil {
+		mp.release(e)
+		return nil, err
+	}
+
+	mp.mu.Lock()
+	mp.borrowed[s] = e
+	mp.mu.Unlock()
+	return s, nil
+}
+
+// ReturnSession returns a session that was acquired via GetSession back to the pool of its vhost.
+// See SessionPool.ReturnSession.
+func (mp *MultiVHostPool) ReturnSession(session *Session, err error) {
+	mp.mu.Lock()
+	e, ok := mp.borrowed[session]
+	delete(mp.borrowed, session)
+	mp.mu.Unlock()
+
+	if !ok {
+		// not supposed to happen, the session does not belong to any vhost pool
+		_ = session.Close()
+		return
+	}
+
+	e.pool.ReturnSession(session, err)
+	mp.release(e)
+}
+
+// VHosts returns the vhosts with open connections, the most recently used vhost first.
+func (mp *MultiVHostPool) VHosts() []string {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	vhosts := make([]string, 0, mp.lru.Len())
+	for el := mp.lru.Front(); el != nil; el = el.Next() {
+		vhosts = append(vhosts, el.Value.(*vhostEntry).vhost)
+	}
+	return vhosts
+}
+
+// Close closes the pools of all vhosts.
+func (mp *MultiVHostPool) Close() {
+	mp.debug("closing multi vhost pool...")
+	defer mp.info("closed")
+
+	mp.mu.Lock()
+	mp.closed = true
+	entries := make([]*vhostEntry, 0, mp.lru.Len())
+	for el := mp.lru.Front(); el != nil; el = el.Next() {
+		entries = append(entries, el.Value.(*vhostEntry))
+	}
+	mp.vhosts = make(map[string]*list.Element)
+	mp.lru.Init()
+	mp.mu.Unlock()
+
+	mp.cancel()
+	mp.wg.Wait()
+
+	for _, e := range entries {
+		e.close()
+	}
+}
+
+// acquire returns the entry of the vhost and creates its pool in case it does not exist yet.
+// The entry cannot be evicted until it is released.
+func (mp *MultiVHostPool) acquire(ctx context.Context, vhost string) (*vhostEntry, error) {
+	mp.mu.Lock()
+	if mp.closed {
+		mp.mu.Unlock()
+		return nil, fmt.Errorf("failed to get session of vhost %q: multi vhost pool %w", vhost, ErrClosed)
+	}
+
+	el, ok := mp.vhosts[vhost]
+	if ok {
+		e := el.Value.(*vhostEntry)
+		e.active++
+		mp.lru.MoveToFront(el)
+		mp.mu.Unlock()
+
+		select {
+		case <-e.ready:
+		case <-ctx.Done():
+			mp.release(e)
+			return nil, fmt.Errorf("failed to get session of vhost %q: %w", vhost, ctx.Err())
+		}
+		if e.err != nil {
+			mp.release(e)
+			return nil, e.err
+		}
+		return e, nil
+	}
+
+	e := mp.add(vhost)
+	evicted := mp.evict(time.Now())
+	mp.mu.Unlock()
+
+	for _, v := range evicted {
+		mp.info(fmt.Sprintf("evicted least recently used vhost %q", v.vhost))
+		v.close()
+	}
+
+	e.pool, e.err = New(mp.ctx, mp.vhostURL(vhost), mp.connsPerVHost, mp.sessionsPerVHost,
+		append(mp.options, WithNameSuffix("-"+vhost))...,
+	)
+	if e.err != nil {
+		e.err = fmt.Errorf("failed to create pool of vhost %q: %w", vhost, e.err)
+		mp.remove(e)
+	}
+	close(e.ready)
+
+	if e.err != nil {
+		return nil, e.err
+	}
+	return e, nil
+}
+
+// add inserts a new entry for the vhost as the most recently used one.
+// not threadsafe
+func (mp *MultiVHostPool) add(vhost string) *vhostEntry {
+	e := &vhostEntry{
+		vhost:    vhost,
+		ready:    make(chan struct{}),
+		active:   1,
+		lastUsed: time.Now(),
+	}
+	mp.vhosts[vhost] = mp.lru.PushFront(e)
+	return e
+}
+
+// remove removes an entry whose pool could not be created.
+func (mp *MultiVHostPool) remove(e *vhostEntry) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	el, ok := mp.vhosts[e.vhost]
+	if ok && el.Value.(*vhostEntry) == e {
+		delete(mp.vhosts, e.vhost)
+		mp.lru.Remove(el)
+	}
+}
+
+// release marks a previously acquired entry as no longer in use by the caller.
+func (mp *MultiVHostPool) release(e *vhostEntry) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	e.active--
+	e.lastUsed = time.Now()
+}
+
+// evict removes all idle vhosts that exceeded the idle timeout as well as the least recently used
+// idle vhosts in case there are more vhosts than allowed. The removed entries must be closed by the caller.
+// not threadsafe
+func (mp *MultiVHostPool) evict(now time.Time) []*vhostEntry {
+	var evicted []*vhostEntry
+	for el := mp.lru.Back(); el != nil; {
+		prev := el.Prev()
+		e := el.Value.(*vhostEntry)
+		if e.active == 0 && (mp.lru.Len() > mp.maxVHosts || now.Sub(e.lastUsed) >= mp.idleTimeout) {
+			delete(mp.vhosts, e.vhost)
+			mp.lru.Remove(el)
+			evicted = append(evicted, e)
+		}
+		el = prev
+	}
+	return evicted
+}
+
+// evictIdle periodically closes the pools of idle vhosts until the pool is closed.
+func (mp *MultiVHostPool) evictIdle() {
+	defer mp.wg.Done()
+
+	interval := mp.idleTimeout / 2
+	if interval <= 0 {
+		interval = mp.idleTimeout
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-mp.ctx.Done():
+			return
+		case now := <-ticker.C:
+			mp.mu.Lock()
+			evicted := mp.evict(now)
+			mp.mu.Unlock()
+
+			for _, e := range evicted {
+				mp.info(fmt.Sprintf("evicted idle vhost %q", e.vhost))
+				e.close()
+			}
+		}
+	}
+}
+
+// vhostURL returns the connect url of the vhost.
+func (mp *MultiVHostPool) vhostURL(vhost string) string {
+	return setVHost(*mp.url, vhost)
+}
+
+func (mp *MultiVHostPool) info(a ...any) {
+	mp.log.WithField("multiVHostPool", mp.url.Hostname()).Info(a...)
+}
+
+func (mp *MultiVHostPool) debug(a ...any) {
+	mp.log.WithField("multiVHostPool", mp.url.Hostname()).Debug(a...)
+}
@@ -0,0 +1,78 @@
+package pool
+
+import (
+	"time"
+
+	"github.com/jxsl13/amqpx/logging"
+)
+
+type multiVHostPoolOption struct {
+	ConnectionsPerVHost int
+	SessionsPerVHost    int
+	MaxVHosts           int
+	IdleTimeout         time.Duration
+
+	PoolOptions []Option
+
+	Logger logging.Logger
+}
+
+type MultiVHostPoolOption func(*multiVHostPoolOption)
+
+// MultiVHostPoolWithConnectionsPerVHost sets the number of cached connections of every vhost, default 1.
+func MultiVHostPoolWithConnectionsPerVHost(numConns int) MultiVHostPoolOption {
+	if numConns < 1 {
+		numConns = 1
+	}
+	return func(po *multiVHostPoolOption) {
+		po.ConnectionsPerVHost = numConns
+	}
+}
+
+// MultiVHostPoolWithSessionsPerVHost sets the number of cached sessions of every vhost.
+// Defaults to the number of connections per vhost.
+func MultiVHostPoolWithSessionsPerVHost(numSessions int) MultiVHostPoolOption {
+	return func(po *multiVHostPoolOption) {
+		po.SessionsPerVHost = numSessions
+	}
+}
+
+// MultiVHostPoolWithMaxVHosts limits the number of vhosts with open connections, default 16.
+// In case the limit is exceeded, the least recently used vhosts that are not in use are evicted.
+// Vhosts that are in use are never evicted, which is why the limit may be exceeded temporarily.
+func MultiVHostPoolWithMaxVHosts(maxVHosts int) MultiVHostPoolOption {
+	if maxVHosts < 1 {
+		maxVHosts = 1
+	}
+	return func(po *multiVHostPoolOption) {
+		po.MaxVHosts = maxVHosts
+	}
+}
+
+// MultiVHostPoolWithIdleTimeout sets the duration after which the connections of a vhost
+// that is not in use are closed, default 5 minutes.
+func MultiVHostPoolWithIdleTimeout(timeout time.Duration) MultiVHostPoolOption {
+	if timeout <= 0 {
+		timeout = 5 * time.Minute
+	}
+	return func(po *multiVHostPoolOption) {
+		po.IdleTimeout = timeout
+	}
+}
+
+// MultiVHostPoolWithPoolOptions sets the options that are shared by the pools of all vhosts,
+// e.g. WithTLS, WithHeartbeatInterval or WithConfirms.
+// The name of every vhost pool is suffixed with its vhost.
+func MultiVHostPoolWithPoolOptions(options ...Option) MultiVHostPoolOption {
+	return func(po *multiVHostPoolOption) {
+		po.PoolOptions = append(po.PoolOptions, options...)
+	}
+}
+
+// MultiVHostPoolWithLogger allows to set a custom logger.
+// The logger is also passed to the pools of all vhosts unless overridden via MultiVHostPoolWithPoolOptions.
+func MultiVHostPoolWithLogger(logger logging.Logger) MultiVHostPoolOption {
+	return func(po *multiVHostPoolOption) {
+		po.Logger = logger
+	}
+}
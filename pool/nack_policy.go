@@ -0,0 +1,52 @@
+package pool
+
+import "errors"
+
+// NackAction defines what happens to a message that could not be processed by a handler.
+type NackAction int
+
+const (
+	// NackActionRequeue puts the message back into its queue in order to be processed again.
+	NackActionRequeue NackAction = iota
+	// NackActionDeadLetter rejects the message without requeuing it.
+	// The broker routes the message to the dead letter exchange of the queue or drops it in case
+	// the queue has no dead letter exchange.
+	NackActionDeadLetter
+)
+
+func (a NackAction) String() string {
+	switch a {
+	case NackActionRequeue:
+		return "requeue"
+	case NackActionDeadLetter:
+		return "dead letter"
+	default:
+		return "unknown"
+	}
+}
+
+// NackPolicy decides based on the error returned by a handler whether a message is requeued or dead lettered.
+//
+// Be aware that requeuing a message that can never be processed successfully leads to an infinite
+// redelivery loop. Persistent failures (e.g. invalid message payloads) should be dead lettered.
+type NackPolicy func(err error) NackAction
+
+// NackPolicyAlways returns a policy that applies the same action to all handler errors.
+func NackPolicyAlways(action NackAction) NackPolicy {
+	return func(error) NackAction {
+		return action
+	}
+}
+
+// nackAction returns the action for a message whose handler returned a non-nil error.
+// ErrReject and ErrRejectSingle always dead letter the message, independent of the policy.
+// Without a policy, messages are requeued.
+func nackAction(policy NackPolicy, err error) NackAction {
+	if errors.Is(err, ErrReject) || errors.Is(err, ErrRejectSingle) {
+		return NackActionDeadLetter
+	}
+	if policy == nil {
+		return NackActionRequeue
+	}
+	return policy(err)
+}
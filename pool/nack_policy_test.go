@@ -0,0 +1,38 @@
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNackAction(t *testing.T) {
+	t.Parallel()
+
+	var (
+		errTemporary = errors.New("temporary")
+		errPoison    = errors.New("poison")
+		policy       = func(err error) NackAction {
+			if errors.Is(err, errPoison) {
+				return NackActionDeadLetter
+			}
+			return NackActionRequeue
+		}
+	)
+
+	// default: requeue
+	assert.Equal(t, NackActionRequeue, nackAction(nil, errTemporary))
+	assert.Equal(t, NackActionDeadLetter, nackAction(nil, ErrReject))
+	assert.Equal(t, NackActionDeadLetter, nackAction(nil, fmt.Errorf("wrapped: %w", ErrRejectSingle)))
+
+	assert.Equal(t, NackActionDeadLetter, nackAction(NackPolicyAlways(NackActionDeadLetter), errTemporary))
+
+	// rejecting errors cannot be overridden by a policy
+	assert.Equal(t, NackActionDeadLetter, nackAction(NackPolicyAlways(NackActionRequeue), ErrReject))
+
+	// dynamic policy
+	assert.Equal(t, NackActionRequeue, nackAction(policy, errTemporary))
+	assert.Equal(t, NackActionDeadLetter, nackAction(policy, fmt.Errorf("invalid payload: %w", errPoison)))
+}
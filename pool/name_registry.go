@@ -0,0 +1,65 @@
+package pool
+
+import (
+	"fmt"
+	"sync"
+)
+
+// NameUniqueness decides what happens when a connection pool is created with the name of another connection pool
+// of the same process that was not closed yet.
+// Duplicate names lead to connections that cannot be distinguished in the management UI and to clashing metric labels.
+type NameUniqueness int
+
+const (
+	// NameUniquenessNone allows multiple pools with the same name (default).
+	// Such pools are not taken into account by pools that enforce unique names.
+	NameUniquenessNone NameUniqueness = iota
+	// NameUniquenessError fails the creation of a pool with a name that is already in use with ErrDuplicatePoolName.
+	NameUniquenessError
+	// NameUniquenessSuffix appends a numeric suffix to the name of a pool in case its name is already in use,
+	// e.g. "name-2".
+	NameUniquenessSuffix
+)
+
+// poolNames contains the names of all open connection pools that enforce unique names.
+var poolNames = struct {
+	mu    sync.Mutex
+	names map[string]*ConnectionPool
+}{
+	names: make(map[string]*ConnectionPool),
+}
+
+// registerPoolName reserves the name for the connection pool and returns the name that the pool must use.
+// Pools that do not enforce unique names are neither registered nor checked.
+func registerPoolName(cp *ConnectionPool, name string, uniqueness NameUniqueness) (string, error) {
+	if uniqueness == NameUniquenessNone {
+		return name, nil
+	}
+
+	poolNames.mu.Lock()
+	defer poolNames.mu.Unlock()
+
+	unique := name
+	for i := 2; ; i++ {
+		if _, ok := poolNames.names[unique]; !ok {
+			break
+		}
+		if uniqueness == NameUniquenessError {
+			return "", fmt.Errorf("%w: %s", ErrDuplicatePoolName, name)
+		}
+		unique = fmt.Sprintf("%s-%d", name, i)
+	}
+
+	poolNames.names[unique] = cp
+	return unique, nil
+}
+
+// unregisterPoolName releases the name of the connection pool.
+func unregisterPoolName(cp *ConnectionPool) {
+	poolNames.mu.Lock()
+	defer poolNames.mu.Unlock()
+
+	if poolNames.names[cp.name] == cp {
+		delete(poolNames.names, cp.name)
+	}
+}
@@ -0,0 +1,49 @@
+package pool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterPoolName(t *testing.T) {
+	t.Parallel()
+
+	var (
+		name = "TestRegisterPoolName"
+		cp1  = &ConnectionPool{}
+		cp2  = &ConnectionPool{}
+		cp3  = &ConnectionPool{}
+	)
+
+	// no enforcement
+	unique, err := registerPoolName(cp1, name, NameUniquenessNone)
+	require.NoError(t, err)
+	assert.Equal(t, name, unique)
+
+	cp1.name, err = registerPoolName(cp1, name, NameUniquenessError)
+	require.NoError(t, err)
+	assert.Equal(t, name, cp1.name)
+
+	_, err = registerPoolName(cp2, name, NameUniquenessError)
+	assert.ErrorIs(t, err, ErrDuplicatePoolName)
+
+	cp2.name, err = registerPoolName(cp2, name, NameUniquenessSuffix)
+	require.NoError(t, err)
+	assert.Equal(t, name+"-2", cp2.name)
+
+	cp3.name, err = registerPoolName(cp3, name, NameUniquenessSuffix)
+	require.NoError(t, err)
+	assert.Equal(t, name+"-3", cp3.name)
+
+	// names are released when pools are closed
+	unregisterPoolName(cp1)
+	unregisterPoolName(cp1)
+	cp1.name, err = registerPoolName(cp1, name, NameUniquenessError)
+	require.NoError(t, err)
+
+	for _, cp := range []*ConnectionPool{cp1, cp2, cp3} {
+		unregisterPoolName(cp)
+	}
+}
@@ -0,0 +1,69 @@
+package pool
+
+import (
+	"context"
+	"sync"
+)
+
+// OutboxEntry is a message that is persisted by the OutboxPublisher before it is published.
+type OutboxEntry struct {
+	// ID uniquely identifies the entry, it is also used as message id in case the message has none.
+	ID         string
+	Exchange   string
+	RoutingKey string
+	Publishing
+}
+
+// Outbox persists messages until the broker confirmed them, which allows to replay messages
+// that could not be published, e.g. due to a broker outage or a restart of the application.
+// Implementations must be safe for concurrent use.
+type Outbox interface {
+	// Store persists the entry before it is published.
+	Store(ctx context.Context, entry OutboxEntry) error
+	// LoadPending returns all entries that were not marked as confirmed yet, oldest first.
+	LoadPending(ctx context.Context) ([]OutboxEntry, error)
+	// MarkConfirmed removes the entry from the pending entries.
+	MarkConfirmed(ctx context.Context, id string) error
+}
+
+var _ Outbox = (*MemoryOutbox)(nil)
+
+// MemoryOutbox is an Outbox that keeps pending entries in memory.
+// Pending entries survive broker outages, but are lost when the application is restarted.
+type MemoryOutbox struct {
+	mu      sync.Mutex
+	entries []OutboxEntry
+}
+
+// NewMemoryOutbox creates a new empty in-memory outbox.
+func NewMemoryOutbox() *MemoryOutbox {
+	return &MemoryOutbox{}
+}
+
+func (o *MemoryOutbox) Store(_ context.Context, entry OutboxEntry) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.entries = append(o.entries, entry)
+	return nil
+}
+
+func (o *MemoryOutbox) LoadPending(_ context.Context) ([]OutboxEntry, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return append([]OutboxEntry(nil), o.entries...), nil
+}
+
+func (o *MemoryOutbox) MarkConfirmed(_ context.Context, id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for i, e := range o.entries {
+		if e.ID == id {
+			o.entries = append(o.entries[:i], o.entries[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,288 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jxsl13/amqpx/logging"
+)
+
+// OutboxPublisher persists every message in an outbox before publishing it and removes it from the outbox
+// as soon as the broker confirmed it. Messages that could not be published, e.g. due to a broker outage,
+// are replayed periodically until the pool recovered and the broker confirmed them.
+// Pending messages of a previous run of the application are replayed as well, in case the outbox is persistent.
+// Replayed messages may lead to duplicate messages (at least once delivery).
+type OutboxPublisher struct {
+	sp     *SessionPool
+	outbox Outbox
+
+	replayInterval time.Duration
+
+	// whether entries are stored and confirmed under the message id of their message
+	correlateByMessageID bool
+
+	// ids of entries that are currently being published, which must not be replayed concurrently
+	mu       sync.Mutex
+	inflight map[string]struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	log logging.Logger
+}
+
+// NewOutboxPublisher creates a new publisher which persists messages in an outbox before publishing them
+// using sessions of the passed session pool.
+// The session pool should be created with confirms enabled, otherwise messages are removed from the outbox
+// as soon as they were sent.
+func NewOutboxPublisher(sp *SessionPool, options ...OutboxPublisherOption) *OutboxPublisher {
+	if sp == nil {
+		panic("nil session pool passed")
+	}
+
+	// sane defaults
+	option := outboxPublisherOption{
+		Ctx: sp.ctx,
+
+		ReplayInterval: 5 * time.Second,
+
+		Logger: sp.log, // derive logger from session pool
+	}
+
+	for _, o := range options {
+		o(&option)
+	}
+
+	if option.Outbox == nil {
+		option.Outbox = NewMemoryOutbox()
+	}
+
+	ctx, cc := context.WithCancelCause(option.Ctx)
+	cancel := toCancelFunc(fmt.Errorf("outbox publisher %w", ErrClosed), cc)
+
+	op := &OutboxPublisher{
+		sp:     sp,
+		outbox: option.Outbox,
+
+		replayInterval:       option.ReplayInterval,
+		correlateByMessageID: option.CorrelateByMessageID,
+
+		inflight: make(map[string]struct{}),
+
+		ctx:    ctx,
+		cancel: cancel,
+
+		log: option.Logger,
+	}
+
+	op.wg.Add(1)
+	go op.run()
+
+	op.info("outbox publisher initialized")
+	return op
+}
+
+// Publish persists the message in the outbox and publishes it.
+// Publish returns nil as soon as the message was persisted and either confirmed by the broker
+// or left in the outbox in order to be replayed later on, e.g. because the broker is not reachable.
+// Messages that can never be published, e.g. because they are too large, are removed from the outbox
+// and their error is returned.
+// Messages without a message id get the id of their outbox entry assigned, which allows consumers to detect duplicates.
+// See OutboxPublisherWithMessageIDCorrelation in order to store messages under their own message id.
+func (op *OutboxPublisher) Publish(ctx context.Context, exchange string, routingKey string, msg Publishing) error {
+	select {
+	case <-op.ctx.Done():
+		return fmt.Errorf("failed to publish message: %w", context.Cause(op.ctx))
+	default:
+	}
+
+	entry := op.newEntry(exchange, routingKey, msg)
+
+	op.acquire(entry.ID)
+	defer op.release(entry.ID)
+
+	err := op.outbox.Store(ctx, entry)
+	if err != nil {
+		return fmt.Errorf("failed to store message in outbox: %w", err)
+	}
+
+	err = op.publish(ctx, entry)
+	if err == nil || !errors.Is(err, ErrMessageTooLarge) && !errors.Is(err, ErrInvalidHeader) {
+		return nil
+	}
+
+	if merr := op.outbox.MarkConfirmed(ctx, entry.ID); merr != nil {
+		return errors.Join(err, fmt.Errorf("failed to remove message from outbox: %w", merr))
+	}
+	return err
+}
+
+// newEntry creates the outbox entry of a message that is about to be published.
+func (op *OutboxPublisher) newEntry(exchange string, routingKey string, msg Publishing) OutboxEntry {
+	entry := OutboxEntry{
+		ID:         newMessageID(),
+		Exchange:   exchange,
+		RoutingKey: routingKey,
+		Publishing: msg,
+	}
+	if op.correlateByMessageID && entry.MessageId != "" {
+		entry.ID = entry.MessageId
+	}
+	if entry.MessageId == "" {
+		entry.MessageId = entry.ID
+	}
+	return entry
+}
+
+// Close stops replaying pending messages.
+// Messages that are still pending remain in the outbox.
+func (op *OutboxPublisher) Close() {
+	op.debug("closing outbox publisher...")
+	defer op.info("closed")
+
+	op.cancel()
+	op.wg.Wait()
+}
+
+func (op *OutboxPublisher) run() {
+	defer op.wg.Done()
+
+	ticker := time.NewTicker(op.replayInterval)
+	defer ticker.Stop()
+
+	for {
+		// replay messages of a previous run right away
+		op.replay()
+
+		select {
+		case <-ticker.C:
+		case <-op.ctx.Done():
+			return
+		}
+	}
+}
+
+// replay publishes all pending messages in their order until the first message fails.
+func (op *OutboxPublisher) replay() {
+	entries, err := op.outbox.LoadPending(op.ctx)
+	if err != nil {
+		op.warn(err, "failed to load pending messages from outbox")
+		return
+	}
+
+	replayed := 0
+	for _, entry := range entries {
+		if !op.tryAcquire(entry.ID) {
+			// currently published by Publish
+			continue
+		}
+		err := op.publish(op.ctx, entry)
+		op.release(entry.ID)
+		if err != nil {
+			op.warn(err, fmt.Sprintf("failed to replay messages, %d messages pending", len(entries)-replayed))
+			return
+		}
+		replayed++
+	}
+
+	if replayed > 0 {
+		op.info(fmt.Sprintf("replayed %d messages", replayed))
+	}
+}
+
+// publish publishes the entry and removes it from the outbox as soon as it was confirmed.
+func (op *OutboxPublisher) publish(ctx context.Context, entry OutboxEntry) (err error) {
+	s, err := op.sp.GetSession(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		op.sp.ReturnSession(s, err)
+	}()
+
+	// entries that were stored without message id correlation, e.g. by a previous run, are confirmed by their id
+	if op.correlateByMessageID && s.IsConfirmable() && entry.ID == entry.MessageId {
+		return op.publishCorrelated(ctx, s, entry)
+	}
+
+	tag, err := s.Publish(ctx, entry.Exchange, entry.RoutingKey, entry.Publishing)
+	if err != nil {
+		return err
+	}
+
+	if s.IsConfirmable() {
+		err = s.AwaitConfirm(ctx, tag)
+		if err != nil {
+			return err
+		}
+	}
+
+	op.markConfirmed(ctx, entry.ID)
+	return nil
+}
+
+// publishCorrelated publishes the entry and marks it as confirmed by its message id, as soon as the
+// channel it was published on confirmed it.
+func (op *OutboxPublisher) publishCorrelated(ctx context.Context, s *Session, entry OutboxEntry) error {
+	tag, epoch, err := s.publish(ctx, entry.Exchange, entry.RoutingKey, entry.Publishing)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	err = s.awaitEpochConfirm(ctx, epoch, tag)
+	s.mu.Unlock()
+	if err != nil {
+		// in case the confirm was lost, the entry is replayed with the same message id
+		return err
+	}
+
+	op.markConfirmed(ctx, entry.ID)
+	return nil
+}
+
+func (op *OutboxPublisher) markConfirmed(ctx context.Context, id string) {
+	err := op.outbox.MarkConfirmed(ctx, id)
+	if err != nil {
+		// the message was published, but will be replayed
+		op.warn(err, "failed to mark message as confirmed")
+	}
+}
+
+func (op *OutboxPublisher) acquire(id string) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	op.inflight[id] = struct{}{}
+}
+
+func (op *OutboxPublisher) tryAcquire(id string) bool {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	if _, ok := op.inflight[id]; ok {
+		return false
+	}
+	op.inflight[id] = struct{}{}
+	return true
+}
+
+func (op *OutboxPublisher) release(id string) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	delete(op.inflight, id)
+}
+
+func (op *OutboxPublisher) info(a ...any) {
+	op.log.WithField("outboxPublisher", op.sp.pool.Name()).Info(a...)
+}
+
+func (op *OutboxPublisher) debug(a ...any) {
+	op.log.WithField("outboxPublisher", op.sp.pool.Name()).Debug(a...)
+}
+
+func (op *OutboxPublisher) warn(err error, a ...any) {
+	op.log.WithField("outboxPublisher", op.sp.pool.Name()).WithField("error", err.Error()).Warn(a...)
+}
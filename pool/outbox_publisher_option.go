@@ -0,0 +1,64 @@
+package pool
+
+import (
+	"context"
+	"time"
+
+	"github.com/jxsl13/amqpx/logging"
+)
+
+type outboxPublisherOption struct {
+	Ctx context.Context
+
+	Outbox               Outbox
+	ReplayInterval       time.Duration
+	CorrelateByMessageID bool
+
+	Logger logging.Logger
+}
+
+type OutboxPublisherOption func(*outboxPublisherOption)
+
+func OutboxPublisherWithContext(ctx context.Context) OutboxPublisherOption {
+	return func(opo *outboxPublisherOption) {
+		opo.Ctx = ctx
+	}
+}
+
+func OutboxPublisherWithLogger(logger logging.Logger) OutboxPublisherOption {
+	return func(opo *outboxPublisherOption) {
+		opo.Logger = logger
+	}
+}
+
+// OutboxPublisherWithOutbox allows to persist messages in a custom outbox, e.g. in a database table.
+// Messages are kept in memory by default, see MemoryOutbox.
+func OutboxPublisherWithOutbox(outbox Outbox) OutboxPublisherOption {
+	return func(opo *outboxPublisherOption) {
+		opo.Outbox = outbox
+	}
+}
+
+// OutboxPublisherWithReplayInterval sets the interval in which pending messages are replayed.
+// The interval MUST be >= 1 * time.Millisecond.
+func OutboxPublisherWithReplayInterval(interval time.Duration) OutboxPublisherOption {
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	return func(opo *outboxPublisherOption) {
+		opo.ReplayInterval = interval
+	}
+}
+
+// OutboxPublisherWithMessageIDCorrelation correlates broker confirms with outbox entries by the message id
+// that is set by the client instead of an id that is generated by the publisher.
+// Messages that carry a message id are stored under that id, which allows a custom outbox to use the
+// message id as primary key, and are marked as confirmed by that id.
+// Confirms are only accepted for the channel a message was published on. In case the session is recovered
+// between publishing a message and receiving its confirm, the message stays pending and is replayed with the same message id.
+// Message ids must be unique among the pending messages.
+func OutboxPublisherWithMessageIDCorrelation() OutboxPublisherOption {
+	return func(opo *outboxPublisherOption) {
+		opo.CorrelateByMessageID = true
+	}
+}
@@ -0,0 +1,224 @@
+package pool_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jxsl13/amqpx/internal/testutils"
+	"github.com/jxsl13/amqpx/logging"
+	"github.com/jxsl13/amqpx/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutboxPublisher(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx          = context.TODO()
+		log          = logging.NewTestLogger(t)
+		poolName     = testutils.FuncName()
+		nextConnName = testutils.ConnectionNameGenerator()
+		numMsgs      = 20
+	)
+
+	hs, hsclose := NewSession(t, ctx, testutils.HealthyConnectURL, nextConnName())
+	defer hsclose()
+
+	cp, err := pool.NewConnectionPool(ctx, testutils.HealthyConnectURL, 1,
+		pool.ConnectionPoolWithName(poolName),
+		pool.ConnectionPoolWithLogger(log),
+	)
+	require.NoError(t, err)
+	defer cp.Close()
+
+	sp, err := pool.NewSessionPool(cp, 1, pool.SessionPoolWithConfirms(true))
+	require.NoError(t, err)
+	defer sp.Close()
+
+	var (
+		nextExchangeName = testutils.ExchangeNameGenerator(hs.Name())
+		nextQueueName    = testutils.QueueNameGenerator(hs.Name())
+		exchangeName     = nextExchangeName()
+		queueName        = nextQueueName()
+	)
+	cleanup := DeclareExchangeQueue(t, ctx, hs, exchangeName, queueName)
+	defer cleanup()
+
+	var (
+		nextConsumerName = testutils.ConsumerNameGenerator(queueName)
+		publisherMsgGen  = testutils.MessageGenerator(queueName)
+		consumerMsgGen   = testutils.MessageGenerator(queueName)
+		outbox           = pool.NewMemoryOutbox()
+		wg               sync.WaitGroup
+	)
+
+	op := pool.NewOutboxPublisher(sp, pool.OutboxPublisherWithOutbox(outbox))
+	defer op.Close()
+
+	ConsumeAsyncN(t, ctx, &wg, hs, queueName, nextConsumerName(), consumerMsgGen, numMsgs, false)
+
+	for i := 0; i < numMsgs; i++ {
+		err := op.Publish(ctx, exchangeName, "", pool.Publishing{
+			ContentType: "text/plain",
+			Body:        []byte(publisherMsgGen()),
+		})
+		require.NoError(t, err)
+
+		// confirmed messages are removed from the outbox
+		pending, err := outbox.LoadPending(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+	}
+
+	wg.Wait()
+}
+
+func TestOutboxPublisherReplayAfterOutage(t *testing.T) {
+	t.Parallel()
+
+	var (
+		proxyName, connectURL, _ = testutils.NextConnectURL()
+		ctx                      = context.TODO()
+		log                      = logging.NewTestLogger(t)
+		poolName                 = testutils.FuncName()
+		nextConnName             = testutils.ConnectionNameGenerator()
+		numMsgs                  = 5
+	)
+
+	hs, hsclose := NewSession(t, ctx, testutils.HealthyConnectURL, nextConnName())
+	defer hsclose()
+
+	cp, err := pool.NewConnectionPool(ctx, connectURL, 1,
+		pool.ConnectionPoolWithName(poolName),
+		pool.ConnectionPoolWithLogger(log),
+	)
+	require.NoError(t, err)
+	defer cp.Close()
+
+	sp, err := pool.NewSessionPool(cp, 1, pool.SessionPoolWithConfirms(true))
+	require.NoError(t, err)
+	defer sp.Close()
+
+	var (
+		nextExchangeName = testutils.ExchangeNameGenerator(hs.Name())
+		nextQueueName    = testutils.QueueNameGenerator(hs.Name())
+		exchangeName     = nextExchangeName()
+		queueName        = nextQueueName()
+	)
+	cleanup := DeclareExchangeQueue(t, ctx, hs, exchangeName, queueName)
+	defer cleanup()
+
+	var (
+		nextConsumerName = testutils.ConsumerNameGenerator(queueName)
+		publisherMsgGen  = testutils.MessageGenerator(queueName)
+		consumerMsgGen   = testutils.MessageGenerator(queueName)
+		outbox           = pool.NewMemoryOutbox()
+		wg               sync.WaitGroup
+	)
+
+	op := pool.NewOutboxPublisher(sp,
+		pool.OutboxPublisherWithOutbox(outbox),
+		pool.OutboxPublisherWithReplayInterval(100*time.Millisecond),
+	)
+	defer op.Close()
+
+	started, stopped := Disconnect(t, proxyName, 3*time.Second)
+	started()
+
+	// messages are persisted while the broker is not reachable
+	for i := 0; i < numMsgs; i++ {
+		publishCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		err := op.Publish(publishCtx, exchangeName, "", pool.Publishing{
+			ContentType: "text/plain",
+			Body:        []byte(publisherMsgGen()),
+		})
+		cancel()
+		require.NoError(t, err)
+	}
+
+	pending, err := outbox.LoadPending(ctx)
+	require.NoError(t, err)
+	assert.Len(t, pending, numMsgs)
+
+	// pending messages are replayed in order after the pool recovered
+	ConsumeAsyncN(t, ctx, &wg, hs, queueName, nextConsumerName(), consumerMsgGen, numMsgs, true)
+	stopped()
+	wg.Wait()
+
+	assert.Eventually(t, func() bool {
+		pending, err := outbox.LoadPending(ctx)
+		return err == nil && len(pending) == 0
+	}, 10*time.Second, 100*time.Millisecond)
+}
+
+func TestOutboxPublisherMessageIDCorrelation(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx          = context.TODO()
+		log          = logging.NewTestLogger(t)
+		poolName     = testutils.FuncName()
+		nextConnName = testutils.ConnectionNameGenerator()
+		messageID    = poolName + "-order-1"
+	)
+
+	hs, hsclose := NewSession(t, ctx, testutils.HealthyConnectURL, nextConnName())
+	defer hsclose()
+
+	cp, err := pool.NewConnectionPool(ctx, testutils.HealthyConnectURL, 1,
+		pool.ConnectionPoolWithName(poolName),
+		pool.ConnectionPoolWithLogger(log),
+	)
+	require.NoError(t, err)
+	defer cp.Close()
+
+	sp, err := pool.NewSessionPool(cp, 1, pool.SessionPoolWithConfirms(true))
+	require.NoError(t, err)
+	defer sp.Close()
+
+	var (
+		nextExchangeName = testutils.ExchangeNameGenerator(hs.Name())
+		nextQueueName    = testutils.QueueNameGenerator(hs.Name())
+		exchangeName     = nextExchangeName()
+		queueName        = nextQueueName()
+		outbox           = pool.NewMemoryOutbox()
+	)
+
+	op := pool.NewOutboxPublisher(sp,
+		pool.OutboxPublisherWithOutbox(outbox),
+		pool.OutboxPublisherWithReplayInterval(100*time.Millisecond),
+		pool.OutboxPublisherWithMessageIDCorrelation(),
+	)
+	defer op.Close()
+
+	// the exchange does not exist yet, which closes the channel before the message is confirmed
+	// and recovers the session before the message is replayed
+	err = op.Publish(ctx, exchangeName, "", pool.Publishing{
+		MessageId:   messageID,
+		ContentType: "text/plain",
+		Body:        []byte("order"),
+	})
+	require.NoError(t, err)
+
+	pending, err := outbox.LoadPending(ctx)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, messageID, pending[0].ID)
+
+	cleanup := DeclareExchangeQueue(t, ctx, hs, exchangeName, queueName)
+	defer cleanup()
+
+	// the replayed message is confirmed by its message id
+	assert.Eventually(t, func() bool {
+		pending, err := outbox.LoadPending(ctx)
+		return err == nil && len(pending) == 0
+	}, 10*time.Second, 100*time.Millisecond)
+
+	msg, ok, err := hs.Get(ctx, queueName, true)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, messageID, msg.MessageId)
+}
@@ -0,0 +1,57 @@
+package pool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryOutbox(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx    = context.TODO()
+		outbox = NewMemoryOutbox()
+	)
+
+	for _, id := range []string{"a", "b", "c"} {
+		require.NoError(t, outbox.Store(ctx, OutboxEntry{ID: id, RoutingKey: id}))
+	}
+	require.NoError(t, outbox.MarkConfirmed(ctx, "b"))
+	// unknown entries are ignored
+	require.NoError(t, outbox.MarkConfirmed(ctx, "d"))
+
+	pending, err := outbox.LoadPending(ctx)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	assert.Equal(t, "a", pending[0].ID)
+	assert.Equal(t, "c", pending[1].ID)
+
+	// the returned entries are a copy
+	require.NoError(t, outbox.MarkConfirmed(ctx, "a"))
+	assert.Equal(t, "a", pending[0].ID)
+}
+
+func TestOutboxPublisherNewEntry(t *testing.T) {
+	t.Parallel()
+
+	var (
+		generated  = &OutboxPublisher{}
+		correlated = &OutboxPublisher{correlateByMessageID: true}
+	)
+
+	entry := generated.newEntry("exchange", "key", Publishing{MessageId: "order-1"})
+	assert.NotEqual(t, "order-1", entry.ID)
+	assert.Equal(t, "order-1", entry.MessageId)
+
+	entry = correlated.newEntry("exchange", "key", Publishing{MessageId: "order-1"})
+	assert.Equal(t, "order-1", entry.ID)
+	assert.Equal(t, "order-1", entry.MessageId)
+
+	// messages without a message id get the generated id assigned
+	entry = correlated.newEntry("exchange", "key", Publishing{})
+	assert.NotEmpty(t, entry.ID)
+	assert.Equal(t, entry.ID, entry.MessageId)
+}
@@ -4,8 +4,6 @@ import (
 	"context"
 	"fmt"
 	"time"
-
-	"github.com/jxsl13/amqpx/logging"
 )
 
 var (
@@ -28,7 +26,7 @@ func New(ctx context.Context, connectUrl string, numConns, numSessions int, opti
 		numSessions = numConns
 	}
 
-	logger := logging.NewNoOpLogger()
+	logger := defaultLogger()
 
 	// use sane defaults
 	option := poolOption{
@@ -90,6 +88,24 @@ func (p *Pool) GetTransientSession(ctx context.Context) (*Session, error) {
 	return p.sp.GetTransientSession(ctx)
 }
 
+// GetTransientSessionForVHost returns a transient session which is connected to the passed vhost
+// instead of the vhost of the pool.
+// The session does also use a transient connection which is closed when the transient session is closed.
+func (p *Pool) GetTransientSessionForVHost(ctx context.Context, vhost string) (*Session, error) {
+	return p.sp.GetTransientSessionForVHost(ctx, vhost)
+}
+
+// PublishToVHosts publishes one message per vhost, see SessionPool.PublishToVHosts.
+func (p *Pool) PublishToVHosts(ctx context.Context, publishings map[string]VHostPublishing) error {
+	return p.sp.PublishToVHosts(ctx, publishings)
+}
+
+// PreWarmedConsumer returns the session and the deliveries of the consumer that was registered for the queue
+// upon pool creation, see SessionPool.PreWarmedConsumer.
+func (p *Pool) PreWarmedConsumer(queue string) (*Session, <-chan Delivery, error) {
+	return p.sp.PreWarmedConsumer(queue)
+}
+
 // ReturnSession returns a Session back to the pool.
 // If the session was returned due to an error, erred should be set to true, otherwise
 // erred should be set to false.
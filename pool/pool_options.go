@@ -38,6 +38,21 @@ func WithNamePrefix(prefix string) Option {
 	}
 }
 
+// WithNameUniqueness enforces that no other open connection pool of the process uses the same name.
+func WithNameUniqueness(uniqueness NameUniqueness) Option {
+	return func(po *poolOption) {
+		ConnectionPoolWithNameUniqueness(uniqueness)(&po.cpo)
+	}
+}
+
+// WithTransientIDStrategy defines how the ids of transient connections are generated,
+// which are part of their names. Ids are assigned sequentially by default.
+func WithTransientIDStrategy(strategy TransientIDStrategy) Option {
+	return func(po *poolOption) {
+		ConnectionPoolWithTransientIDStrategy(strategy)(&po.cpo)
+	}
+}
+
 // WithLogger allows to set a custom logger for the connection AND session pool
 func WithLogger(logger logging.Logger) Option {
 	return func(po *poolOption) {
@@ -60,6 +75,30 @@ func WithConnectionTimeout(timeout time.Duration) Option {
 	}
 }
 
+// WithTCPConnectTimeout bounds establishing the TCP connections of the pool separately from the handshakes,
+// see ConnectionPoolWithTCPConnectTimeout.
+func WithTCPConnectTimeout(timeout time.Duration) Option {
+	return func(po *poolOption) {
+		ConnectionPoolWithTCPConnectTimeout(timeout)(&po.cpo)
+	}
+}
+
+// WithInitialDialTimeout bounds the initial connect of every cached connection while the pool is created,
+// see ConnectionPoolWithInitialDialTimeout.
+func WithInitialDialTimeout(timeout time.Duration) Option {
+	return func(po *poolOption) {
+		ConnectionPoolWithInitialDialTimeout(timeout)(&po.cpo)
+	}
+}
+
+// WithIOTimeout sets a deadline for every read and write on the sockets of the pool's connections
+// in order to detect stalled sockets, see ConnectionWithIOTimeout. A timeout <= 0 disables the deadlines (default).
+func WithIOTimeout(timeout time.Duration) Option {
+	return func(po *poolOption) {
+		ConnectionPoolWithIOTimeout(timeout)(&po.cpo)
+	}
+}
+
 // WithTLS allows to configure tls connectivity.
 func WithTLS(config *tls.Config) Option {
 	return func(po *poolOption) {
@@ -67,6 +106,127 @@ func WithTLS(config *tls.Config) Option {
 	}
 }
 
+// WithServerName allows to override the server name that is used to verify the broker's certificate.
+func WithServerName(serverName string) Option {
+	return func(po *poolOption) {
+		ConnectionPoolWithServerName(serverName)(&po.cpo)
+	}
+}
+
+// WithConnectionProperties sets the client properties that all connections of the pool advertise
+// to the broker whenever they are (re)established, see ConnectionWithProperties.
+func WithConnectionProperties(properties Table) Option {
+	return func(po *poolOption) {
+		ConnectionPoolWithConnectionProperties(properties)(&po.cpo)
+	}
+}
+
+// WithExternalAuth authenticates with the SASL EXTERNAL mechanism instead of the credentials of the connection url.
+// In case fallbackToPlain is set, rejected EXTERNAL authentications are retried once with PLAIN authentication.
+func WithExternalAuth(fallbackToPlain bool) Option {
+	return func(po *poolOption) {
+		ConnectionPoolWithExternalAuth(fallbackToPlain)(&po.cpo)
+	}
+}
+
+// WithAddressFamily forces the usage of a specific network when dialing the broker, e.g. "tcp4" or "tcp6".
+func WithAddressFamily(network string) Option {
+	return func(po *poolOption) {
+		ConnectionPoolWithAddressFamily(network)(&po.cpo)
+	}
+}
+
+// WithBrokerWeights distributes the cached connections of the pool across multiple brokers proportionally to their weights.
+func WithBrokerWeights(brokers []WeightedURL) Option {
+	return func(po *poolOption) {
+		ConnectionPoolWithBrokerWeights(brokers)(&po.cpo)
+	}
+}
+
+// WithMaxLifetime reconnects cached connections that are older than the passed lifetime when they are acquired,
+// see ConnectionPoolWithMaxLifetime.
+func WithMaxLifetime(lifetime time.Duration) Option {
+	return func(po *poolOption) {
+		ConnectionPoolWithMaxLifetime(lifetime)(&po.cpo)
+	}
+}
+
+// WithIdleTimeout closes the underlying connections of cached connections that were not used for the passed timeout,
+// see ConnectionPoolWithIdleTimeout.
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(po *poolOption) {
+		ConnectionPoolWithIdleTimeout(timeout)(&po.cpo)
+	}
+}
+
+// WithMinIdle sets the number of idle cached connections that are kept open, see ConnectionPoolWithMinIdle.
+// It has no effect without WithIdleTimeout.
+func WithMinIdle(min int) Option {
+	return func(po *poolOption) {
+		ConnectionPoolWithMinIdle(min)(&po.cpo)
+	}
+}
+
+// WithHealthCheck enables a background check that pings all idle cached connections once per interval
+// and flags the ones that failed, see ConnectionPoolWithHealthCheck.
+func WithHealthCheck(interval time.Duration) Option {
+	return func(po *poolOption) {
+		ConnectionPoolWithHealthCheck(interval)(&po.cpo)
+	}
+}
+
+// WithLivenessInterval enables a background check that pings all idle cached connections once per interval.
+func WithLivenessInterval(interval time.Duration) Option {
+	return func(po *poolOption) {
+		ConnectionPoolWithLivenessInterval(interval)(&po.cpo)
+	}
+}
+
+// WithMaxConcurrentRecoveries limits the number of connections of the pool that recover concurrently.
+func WithMaxConcurrentRecoveries(n int) Option {
+	return func(po *poolOption) {
+		ConnectionPoolWithMaxConcurrentRecoveries(n)(&po.cpo)
+	}
+}
+
+// WithSerialRecovery makes connections reconnect strictly one at a time during their recovery.
+func WithSerialRecovery() Option {
+	return func(po *poolOption) {
+		ConnectionPoolWithSerialRecovery()(&po.cpo)
+	}
+}
+
+// WithSlowAcquisitionThreshold logs a warning as soon as GetConnection or GetSession blocks longer than the threshold.
+// A threshold <= 0 disables the warning (default).
+func WithSlowAcquisitionThreshold(threshold time.Duration) Option {
+	return func(po *poolOption) {
+		ConnectionPoolWithSlowAcquisitionThreshold(threshold)(&po.cpo)
+		SessionPoolWithSlowAcquisitionThreshold(threshold)(&po.spo)
+	}
+}
+
+// WithOnBlocked sets a callback that is called once when the broker starts blocking the first connection of the pool.
+func WithOnBlocked(callback PoolBlockedCallback) Option {
+	return func(po *poolOption) {
+		ConnectionPoolWithOnBlocked(callback)(&po.cpo)
+	}
+}
+
+// WithOnUnblocked sets a callback that is called once when the broker stops blocking the last blocked connection of the pool.
+func WithOnUnblocked(callback PoolBlockedCallback) Option {
+	return func(po *poolOption) {
+		ConnectionPoolWithOnUnblocked(callback)(&po.cpo)
+	}
+}
+
+// WithMetricsCollector allows to export connection and session pool metrics, e.g. to Prometheus.
+func WithMetricsCollector(collector MetricsCollector) Option {
+	return func(po *poolOption) {
+		ConnectionPoolWithMetricsCollector(collector)(&po.cpo)
+		SessionPoolWithMetricsCollector(collector)(&po.spo)
+	}
+}
+
 // WithBufferCapacity allows to configurethe size of
 // the confirmation, error & blocker buffers of all sessions
 func WithBufferCapacity(size int) Option {
@@ -75,6 +235,55 @@ func WithBufferCapacity(size int) Option {
 	}
 }
 
+// WithTransientSessionTTL closes transient sessions that are still open after the passed ttl,
+// see SessionPoolWithTransientTTL.
+func WithTransientSessionTTL(ttl time.Duration) Option {
+	return func(po *poolOption) {
+		SessionPoolWithTransientTTL(ttl)(&po.spo)
+	}
+}
+
+// WithPublishRateLimit limits the publishings of all sessions of the pool to perSecond messages per second
+// with bursts of up to burst messages, see SessionPoolWithPublishRateLimit.
+func WithPublishRateLimit(perSecond float64, burst int) Option {
+	return func(po *poolOption) {
+		SessionPoolWithPublishRateLimit(perSecond, burst)(&po.spo)
+	}
+}
+
+// WithNonBlockingPublishRateLimit makes publishing return ErrRateLimited instead of blocking
+// in case the publish rate limit is exceeded, see SessionPoolWithNonBlockingPublishRateLimit.
+func WithNonBlockingPublishRateLimit(nonBlocking bool) Option {
+	return func(po *poolOption) {
+		SessionPoolWithNonBlockingPublishRateLimit(nonBlocking)(&po.spo)
+	}
+}
+
+// WithLeakDetection logs a warning as soon as an acquired session was not returned to the pool within the threshold,
+// see SessionPoolWithLeakDetection.
+func WithLeakDetection(threshold time.Duration, captureStacks bool) Option {
+	return func(po *poolOption) {
+		SessionPoolWithLeakDetection(threshold, captureStacks)(&po.spo)
+	}
+}
+
+// WithMaxMessageSize limits the body size of published messages in bytes.
+// Bigger messages are rejected with ErrMessageTooLarge before they hit the broker.
+// A size of 0 or less disables the limit, which is the default.
+func WithMaxMessageSize(bytes int) Option {
+	return func(po *poolOption) {
+		SessionPoolWithMaxMessageSize(bytes)(&po.spo)
+	}
+}
+
+// WithPreWarmedConsumers registers one consumer per queue on dedicated sessions while the pool is created,
+// see SessionPoolWithPreWarmedConsumers.
+func WithPreWarmedConsumers(consumers map[string]ConsumeOptions) Option {
+	return func(po *poolOption) {
+		SessionPoolWithPreWarmedConsumers(consumers)(&po.spo)
+	}
+}
+
 // WithConfirms requires all messages from sessions to be acked.
 func WithConfirms(requirePublishConfirms bool) Option {
 	return func(po *poolOption) {
@@ -82,6 +291,14 @@ func WithConfirms(requirePublishConfirms bool) Option {
 	}
 }
 
+// WithConfirmNoWait enables confirm mode of the sessions without awaiting the acknowledgement of the broker,
+// see SessionPoolWithConfirmNoWait.
+func WithConfirmNoWait(noWait bool) Option {
+	return func(po *poolOption) {
+		SessionPoolWithConfirmNoWait(noWait)(&po.spo)
+	}
+}
+
 // WithConnectionRecoverCallback allows to set a custom connection recovery callback
 func WithConnectionRecoverCallback(callback ConnectionRecoverCallback) Option {
 	return func(po *poolOption) {
@@ -89,6 +306,14 @@ func WithConnectionRecoverCallback(callback ConnectionRecoverCallback) Option {
 	}
 }
 
+// WithConnectionMaxBackoffCallback sets a callback that is called whenever the recovery backoff of a connection
+// reaches its maximum and when such a connection was recovered, see ConnectionPoolWithMaxBackoffCallback.
+func WithConnectionMaxBackoffCallback(callback ConnectionMaxBackoffCallback) Option {
+	return func(po *poolOption) {
+		ConnectionPoolWithMaxBackoffCallback(callback)(&po.cpo)
+	}
+}
+
 // WithSessionRecoverCallback allows to set a custom session recovery callback
 func WithSessionRecoverCallback(callback SessionRetryCallback) Option {
 	return func(po *poolOption) {
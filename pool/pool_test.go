@@ -2,6 +2,7 @@ package pool_test
 
 import (
 	"context"
+	"runtime"
 	"sync"
 	"testing"
 	"time"
@@ -10,6 +11,7 @@ import (
 	"github.com/jxsl13/amqpx/logging"
 	"github.com/jxsl13/amqpx/pool"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMain(m *testing.M) {
@@ -62,3 +64,216 @@ func TestNewPool(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestMultiVHostPool(t *testing.T) {
+	t.Parallel()
+	var (
+		ctx         = context.TODO()
+		poolName    = testutils.FuncName()
+		idleTimeout = time.Second
+		vhost       = "/"
+	)
+
+	mp, err := pool.NewMultiVHostPool(
+		ctx,
+		testutils.HealthyConnectURL,
+		pool.MultiVHostPoolWithIdleTimeout(idleTimeout),
+		pool.MultiVHostPoolWithLogger(logging.NewTestLogger(t)),
+		pool.MultiVHostPoolWithPoolOptions(pool.WithName(poolName)),
+	)
+	require.NoError(t, err)
+	defer mp.Close()
+
+	// connections are established lazily
+	assert.Empty(t, mp.VHosts())
+
+	s, err := mp.GetSession(ctx, vhost)
+	require.NoError(t, err)
+	assert.Equal(t, []string{vhost}, mp.VHosts())
+	first := s
+	mp.ReturnSession(s, nil)
+
+	// the cached session of the vhost is reused
+	s, err = mp.GetSession(ctx, vhost)
+	require.NoError(t, err)
+	assert.Same(t, first, s)
+
+	// vhosts in use are not evicted
+	time.Sleep(2 * idleTimeout)
+	assert.Equal(t, []string{vhost}, mp.VHosts())
+	mp.ReturnSession(s, nil)
+
+	// idle vhosts are evicted
+	assert.Eventually(t, func() bool {
+		return len(mp.VHosts()) == 0
+	}, 3*idleTimeout, idleTimeout/10)
+
+	// and recreated upon the next use
+	s, err = mp.GetSession(ctx, vhost)
+	require.NoError(t, err)
+	assert.Equal(t, []string{vhost}, mp.VHosts())
+	mp.ReturnSession(s, nil)
+}
+
+// not parallel, as the goroutines of other tests would distort the goroutine count
+func TestPoolCloseDoesNotLeakGoroutines(t *testing.T) {
+	var (
+		ctx      = context.TODO()
+		poolName = testutils.FuncName()
+		cycles   = 10
+	)
+
+	cycle := func() {
+		p, err := pool.New(ctx, testutils.HealthyConnectURL, 2, 4,
+			pool.WithName(poolName),
+			pool.WithLogger(logging.NewTestLogger(t)),
+			pool.WithLivenessInterval(10*time.Millisecond),
+		)
+		require.NoError(t, err)
+		defer p.Close()
+
+		s, err := p.GetSession(ctx)
+		require.NoError(t, err)
+
+		queueName := testutils.QueueNameGenerator(poolName)()
+		_, err = s.QueueDeclare(ctx, queueName)
+		require.NoError(t, err)
+		defer func() {
+			_, err := s.QueueDelete(ctx, queueName)
+			assert.NoError(t, err)
+			p.ReturnSession(s, err)
+		}()
+
+		// starts a forwarding goroutine which must terminate upon close
+		_, err = s.Consume(queueName, pool.ConsumeOptions{ConsumerTag: testutils.ConsumerNameGenerator(queueName)()})
+		require.NoError(t, err)
+
+		// let the liveness check run concurrently
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// warm up, e.g. lazily started runtime goroutines
+	cycle()
+	baseline := runtime.NumGoroutine()
+
+	for i := 0; i < cycles; i++ {
+		cycle()
+	}
+
+	// the goroutines of the underlying connections terminate asynchronously
+	assert.Eventually(t, func() bool {
+		return runtime.NumGoroutine() <= baseline
+	}, 10*time.Second, 50*time.Millisecond, "goroutine count grew above %d", baseline)
+}
+
+func TestPoolPublishToVHosts(t *testing.T) {
+	t.Parallel()
+	var (
+		ctx          = context.TODO()
+		poolName     = testutils.FuncName()
+		vhost        = "/"
+		missingVHost = "missing-vhost"
+	)
+
+	p, err := pool.New(ctx, testutils.HealthyConnectURL, 1, 1,
+		pool.WithName(poolName),
+		pool.WithLogger(logging.NewTestLogger(t)),
+	)
+	require.NoError(t, err)
+	defer p.Close()
+
+	ts, err := p.GetTransientSession(ctx)
+	require.NoError(t, err)
+	defer p.ReturnSession(ts, nil)
+
+	queueName := testutils.QueueNameGenerator(poolName)()
+	_, err = ts.QueueDeclare(ctx, queueName)
+	require.NoError(t, err)
+	defer func() {
+		_, err := ts.QueueDelete(ctx, queueName)
+		assert.NoError(t, err)
+	}()
+
+	// connections to vhosts that do not exist are retried until the context is done
+	tctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err = p.PublishToVHosts(tctx, map[string]pool.VHostPublishing{
+		vhost:        {RoutingKey: queueName, Publishing: pool.Publishing{Body: []byte("saga event")}},
+		missingVHost: {RoutingKey: queueName, Publishing: pool.Publishing{Body: []byte("saga event")}},
+	})
+	var vpe *pool.VHostPublishError
+	require.ErrorAs(t, err, &vpe)
+	assert.Equal(t, []string{vhost}, vpe.Published)
+	assert.Len(t, vpe.Failed, 1)
+	assert.ErrorIs(t, vpe.Failed[missingVHost], context.DeadlineExceeded)
+
+	// the message of the healthy vhost was published
+	msg, ok, err := ts.Get(ctx, queueName, true)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "saga event", string(msg.Body))
+}
+
+func TestTopologerVerify(t *testing.T) {
+	t.Parallel()
+	var (
+		ctx      = context.TODO()
+		poolName = testutils.FuncName()
+	)
+
+	p, err := pool.New(ctx, testutils.HealthyConnectURL, 1, 1,
+		pool.WithName(poolName),
+		pool.WithLogger(logging.NewTestLogger(t)),
+	)
+	require.NoError(t, err)
+	defer p.Close()
+
+	top := pool.NewTopologer(p)
+
+	nextName := testutils.QueueNameGenerator(poolName)
+	existingQueue, missingQueue := nextName(), nextName()
+	_, err = top.QueueDeclare(ctx, existingQueue)
+	require.NoError(t, err)
+	defer func() {
+		_, err := top.QueueDelete(ctx, existingQueue)
+		assert.NoError(t, err)
+	}()
+
+	topology := pool.Topology{
+		Exchanges: []pool.TopologyExchange{
+			{Name: "amq.topic", Kind: pool.ExchangeKindTopic},
+		},
+		Queues: []pool.TopologyQueue{
+			{Name: missingQueue},
+			{Name: existingQueue},
+		},
+	}
+
+	// the missing queue is reported and the remaining resources are checked on a reset channel
+	err = top.Verify(ctx, topology)
+	var te *pool.TopologyError
+	require.ErrorAs(t, err, &te)
+	require.Len(t, te.Mismatches, 1)
+	assert.Equal(t, "queue", te.Mismatches[0].Resource)
+	assert.Equal(t, missingQueue, te.Mismatches[0].Name)
+	assert.True(t, te.Mismatches[0].Missing)
+	assert.ErrorIs(t, err, pool.ErrNotFound)
+
+	// verification does not create missing resources
+	_, err = top.QueueDeclarePassive(ctx, missingQueue)
+	assert.ErrorIs(t, err, pool.ErrNotFound)
+
+	// incompatible settings are reported as well
+	topology.Queues = []pool.TopologyQueue{
+		{Name: existingQueue, Options: &pool.QueueDeclareOptions{Durable: false}},
+	}
+	err = top.Verify(ctx, topology)
+	require.ErrorAs(t, err, &te)
+	require.Len(t, te.Mismatches, 1)
+	assert.Equal(t, existingQueue, te.Mismatches[0].Name)
+	assert.False(t, te.Mismatches[0].Missing)
+
+	topology.Queues = []pool.TopologyQueue{{Name: existingQueue}}
+	assert.NoError(t, top.Verify(ctx, topology))
+}
@@ -4,14 +4,21 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/jxsl13/amqpx/logging"
+	"github.com/rabbitmq/amqp091-go"
 )
 
 type Publisher struct {
 	pool          *Pool
 	autoClosePool bool
 
+	autoMessageID bool
+	autoTimestamp bool
+	appID         string
+	transient     bool
+
 	ctx    context.Context
 	cancel context.CancelFunc
 
@@ -52,6 +59,10 @@ func NewPublisher(p *Pool, options ...PublisherOption) *Publisher {
 	pub := &Publisher{
 		pool:          p,
 		autoClosePool: option.AutoClosePool,
+		autoMessageID: option.AutoMessageID,
+		autoTimestamp: option.AutoTimestamp,
+		appID:         option.AppID,
+		transient:     option.Transient,
 		ctx:           ctx,
 		cancel:        cancel,
 
@@ -64,7 +75,9 @@ func NewPublisher(p *Pool, options ...PublisherOption) *Publisher {
 
 // Publish a message to a specific exchange with a given routingKey.
 // You may set exchange to "" and routingKey to your queue name in order to publish directly to a queue.
-func (p *Publisher) Publish(ctx context.Context, exchange string, routingKey string, msg Publishing) error {
+// The passed options modify the message before it is published.
+func (p *Publisher) Publish(ctx context.Context, exchange string, routingKey string, msg Publishing, options ...PublishOption) error {
+	msg = p.populate(msg, options...)
 
 	for {
 		err := p.publish(ctx, exchange, routingKey, msg)
@@ -115,6 +128,27 @@ func (p *Publisher) publish(ctx context.Context, exchange string, routingKey str
 	return s.AwaitConfirm(ctx, tag)
 }
 
+// populate applies the publish options and sets the message properties that are configured to be set automatically.
+// Properties that were set by the caller are never overwritten.
+func (p *Publisher) populate(msg Publishing, options ...PublishOption) Publishing {
+	for _, o := range options {
+		o(&msg)
+	}
+	if p.appID != "" && msg.AppId == "" {
+		msg.AppId = p.appID
+	}
+	if p.autoMessageID && msg.MessageId == "" {
+		msg.MessageId = newMessageID()
+	}
+	if p.autoTimestamp && msg.Timestamp.IsZero() {
+		msg.Timestamp = time.Now()
+	}
+	if p.transient && msg.DeliveryMode == 0 {
+		msg.DeliveryMode = amqp091.Transient
+	}
+	return msg
+}
+
 // Get is only supposed to be used for testing, do not use get for polling any broker queues.
 func (p *Publisher) Get(ctx context.Context, queue string, autoAck bool) (msg Delivery, ok bool, err error) {
 	s, err := p.pool.GetSession(ctx)
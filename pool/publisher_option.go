@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/jxsl13/amqpx/logging"
+	"github.com/rabbitmq/amqp091-go"
 )
 
 type publisherOption struct {
@@ -11,6 +12,12 @@ type publisherOption struct {
 
 	AutoClosePool bool
 
+	AutoMessageID bool
+	AutoTimestamp bool
+
+	AppID     string
+	Transient bool
+
 	Logger logging.Logger
 }
 
@@ -33,3 +40,76 @@ func PublisherWithAutoClosePool(autoClose bool) PublisherOption {
 		po.AutoClosePool = autoClose
 	}
 }
+
+// PublisherWithAutoMessageID sets a random UUID as MessageId of every published message
+// that does not have a MessageId yet.
+// The MessageId is kept when a publish is retried, which allows consumers to deduplicate messages.
+func PublisherWithAutoMessageID() PublisherOption {
+	return func(po *publisherOption) {
+		po.AutoMessageID = true
+	}
+}
+
+// PublisherWithAutoTimestamp sets the current time as Timestamp of every published message
+// that does not have a Timestamp yet.
+func PublisherWithAutoTimestamp() PublisherOption {
+	return func(po *publisherOption) {
+		po.AutoTimestamp = true
+	}
+}
+
+// PublisherWithAppID sets the passed application id as AppId of every published message
+// that does not have an AppId yet, e.g. the name of the service.
+func PublisherWithAppID(appID string) PublisherOption {
+	return func(po *publisherOption) {
+		po.AppID = appID
+	}
+}
+
+// PublisherWithPersistent sets the delivery mode of every published message that does not set its delivery mode
+// explicitly, see WithPersistent. Messages are persistent by default.
+// Transient messages are faster, as the broker does not write them to disk, which suits e.g. low value telemetry.
+func PublisherWithPersistent(persistent bool) PublisherOption {
+	return func(po *publisherOption) {
+		po.Transient = !persistent
+	}
+}
+
+// PublishOption modifies the properties of a single message that is published with Publisher.Publish.
+// Publish options take precedence over the properties that are set by the publisher automatically.
+type PublishOption func(*Publishing)
+
+// WithAppID sets the AppId of the published message.
+func WithAppID(appID string) PublishOption {
+	return func(msg *Publishing) {
+		msg.AppId = appID
+	}
+}
+
+// WithType sets the Type of the published message, e.g. the name of its schema,
+// which allows consumers to route messages by their type, see RouteByType.
+func WithType(typ string) PublishOption {
+	return func(msg *Publishing) {
+		msg.Type = typ
+	}
+}
+
+// WithPersistent sets the delivery mode of the published message, which takes precedence over the
+// default of the publisher, see PublisherWithPersistent.
+// Durability is a property of both the queue and the message: only persistent messages in durable queues
+// survive a broker restart. A transient message that is routed to a durable queue is still confirmed by the broker,
+// but a confirm does not guarantee that the message was written to disk, so it may be lost in case the broker restarts.
+func WithPersistent(persistent bool) PublishOption {
+	return func(msg *Publishing) {
+		if persistent {
+			msg.DeliveryMode = amqp091.Persistent
+		} else {
+			msg.DeliveryMode = amqp091.Transient
+		}
+	}
+}
+
+// WithTransient publishes the message as transient message, see WithPersistent.
+func WithTransient() PublishOption {
+	return WithPersistent(false)
+}
@@ -0,0 +1,94 @@
+package pool
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublisherPopulate(t *testing.T) {
+	t.Parallel()
+
+	var (
+		uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+		callerTime  = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	)
+
+	// disabled by default
+	msg := (&Publisher{}).populate(Publishing{})
+	assert.Empty(t, msg.MessageId)
+	assert.True(t, msg.Timestamp.IsZero())
+
+	p := &Publisher{autoMessageID: true, autoTimestamp: true}
+
+	before := time.Now()
+	msg = p.populate(Publishing{})
+	assert.Regexp(t, uuidPattern, msg.MessageId)
+	assert.False(t, msg.Timestamp.Before(before))
+	assert.False(t, msg.Timestamp.After(time.Now()))
+
+	// every message gets its own id
+	assert.NotEqual(t, msg.MessageId, p.populate(Publishing{}).MessageId)
+
+	// caller provided values are not overwritten
+	msg = p.populate(Publishing{MessageId: "caller-id", Timestamp: callerTime})
+	assert.Equal(t, "caller-id", msg.MessageId)
+	assert.Equal(t, callerTime, msg.Timestamp)
+}
+
+func TestPublisherPopulateAppIDAndType(t *testing.T) {
+	t.Parallel()
+
+	// no default
+	msg := (&Publisher{}).populate(Publishing{}, WithType("order.created"))
+	assert.Empty(t, msg.AppId)
+	assert.Equal(t, "order.created", msg.Type)
+
+	p := &Publisher{appID: "order-service"}
+
+	msg = p.populate(Publishing{})
+	assert.Equal(t, "order-service", msg.AppId)
+	assert.Empty(t, msg.Type)
+
+	// caller provided values are not overwritten
+	msg = p.populate(Publishing{AppId: "caller-app"})
+	assert.Equal(t, "caller-app", msg.AppId)
+
+	// publish options take precedence over the default
+	msg = p.populate(Publishing{Type: "order.created"}, WithAppID("billing-service"), WithType("order.paid"))
+	assert.Equal(t, "billing-service", msg.AppId)
+	assert.Equal(t, "order.paid", msg.Type)
+}
+
+func TestPublisherPopulateDeliveryMode(t *testing.T) {
+	t.Parallel()
+
+	// persistent by default, which is applied by the session
+	msg := (&Publisher{}).populate(Publishing{})
+	assert.Equal(t, uint8(0), msg.DeliveryMode)
+	assert.Equal(t, uint8(2), (&Session{}).publishing("", "", msg).DeliveryMode)
+
+	msg = (&Publisher{}).populate(Publishing{}, WithTransient())
+	assert.Equal(t, uint8(1), msg.DeliveryMode)
+	assert.Equal(t, uint8(1), (&Session{}).publishing("", "", msg).DeliveryMode)
+
+	p := &Publisher{transient: true}
+
+	msg = p.populate(Publishing{})
+	assert.Equal(t, uint8(1), msg.DeliveryMode)
+
+	// per message options take precedence over the default
+	msg = p.populate(Publishing{}, WithPersistent(true))
+	assert.Equal(t, uint8(2), msg.DeliveryMode)
+	assert.Equal(t, uint8(2), (&Session{}).publishing("", "", msg).DeliveryMode)
+
+	// caller provided values are not overwritten
+	msg = p.populate(Publishing{DeliveryMode: 2})
+	assert.Equal(t, uint8(2), msg.DeliveryMode)
+
+	var option publisherOption
+	PublisherWithPersistent(false)(&option)
+	assert.True(t, option.Transient)
+}
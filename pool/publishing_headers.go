@@ -0,0 +1,127 @@
+package pool
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// HeaderCC contains additional routing keys that a message is routed with (reference: https://www.rabbitmq.com/sender-selected.html).
+	HeaderCC = "CC"
+	// HeaderBCC contains additional routing keys that a message is routed with.
+	// In contrast to HeaderCC, the header is removed by the broker before the message is delivered.
+	HeaderBCC = "BCC"
+	// HeaderDelay contains the delay in milliseconds that a delayed message exchange holds back a message before routing it.
+	HeaderDelay = "x-delay"
+
+	// maxDelay is the maximum delay that fits into the integer range of the delay header.
+	maxDelay = math.MaxInt32 * time.Millisecond
+)
+
+// PublishingHeaderOption modifies the headers of a publishing.
+type PublishingHeaderOption func(Table)
+
+// PublishingHeaders creates a new header table that can be passed to Publishing.Headers.
+func PublishingHeaders(options ...PublishingHeaderOption) Table {
+	headers := Table{}
+	for _, o := range options {
+		o(headers)
+	}
+	return headers
+}
+
+// PublishingWithCC routes a copy of the message with each of the passed routing keys
+// in addition to the routing key of the publishing, e.g. to fan out messages to audit queues.
+// The routing keys are visible to consumers.
+func PublishingWithCC(routingKeys ...string) PublishingHeaderOption {
+	return func(t Table) {
+		setRoutingKeys(t, HeaderCC, routingKeys)
+	}
+}
+
+// PublishingWithBCC routes a copy of the message with each of the passed routing keys
+// in addition to the routing key of the publishing.
+// The broker removes the routing keys before the message is delivered to consumers.
+func PublishingWithBCC(routingKeys ...string) PublishingHeaderOption {
+	return func(t Table) {
+		setRoutingKeys(t, HeaderBCC, routingKeys)
+	}
+}
+
+// PublishingWithDelay delays the routing of the message by the passed duration with millisecond precision.
+// The message must be published to a delayed message exchange (see ExchangeKindDelayedMessage),
+// other exchanges ignore the delay.
+// The delay must not be negative and must not exceed 2^31-1 milliseconds (about 24 days),
+// otherwise publishing the message fails with ErrInvalidHeader.
+func PublishingWithDelay(d time.Duration) PublishingHeaderOption {
+	return func(t Table) {
+		t[HeaderDelay] = d.Milliseconds()
+	}
+}
+
+func setRoutingKeys(t Table, header string, routingKeys []string) {
+	if len(routingKeys) == 0 {
+		delete(t, header)
+		return
+	}
+
+	// the broker expects an array of strings
+	keys := make([]any, 0, len(routingKeys))
+	for _, key := range routingKeys {
+		keys = append(keys, key)
+	}
+	t[header] = keys
+}
+
+// validateRoutingHeaders checks that the sender selected distribution headers are arrays of strings,
+// otherwise the broker ignores them and the message is silently not routed to the additional routing keys.
+func validateRoutingHeaders(headers Table) error {
+	for _, header := range []string{HeaderCC, HeaderBCC} {
+		v, ok := headers[header]
+		if !ok {
+			continue
+		}
+
+		keys, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("%w: %s header must be an array of strings, got %T", ErrInvalidHeader, header, v)
+		}
+		for _, key := range keys {
+			if _, ok := key.(string); !ok {
+				return fmt.Errorf("%w: %s header must only contain strings, got %T", ErrInvalidHeader, header, key)
+			}
+		}
+	}
+	return nil
+}
+
+// validateDelayHeader checks that the delay header is an integer within the supported range,
+// otherwise the broker rejects or ignores the delay.
+func validateDelayHeader(headers Table) error {
+	v, ok := headers[HeaderDelay]
+	if !ok {
+		return nil
+	}
+
+	var ms int64
+	switch d := v.(type) {
+	case int8:
+		ms = int64(d)
+	case int16:
+		ms = int64(d)
+	case int32:
+		ms = int64(d)
+	case int64:
+		ms = d
+	case int:
+		ms = int64(d)
+	default:
+		return fmt.Errorf("%w: %s header must be an integer, got %T", ErrInvalidHeader, HeaderDelay, v)
+	}
+
+	if ms < 0 || ms > maxDelay.Milliseconds() {
+		return fmt.Errorf("%w: %s header must be between 0 and %d milliseconds, got %d", ErrInvalidHeader, HeaderDelay, maxDelay.Milliseconds(), ms)
+	}
+	return nil
+}
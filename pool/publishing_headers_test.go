@@ -0,0 +1,57 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublishingHeaders(t *testing.T) {
+	t.Parallel()
+
+	headers := PublishingHeaders(
+		PublishingWithCC("audit", "archive"),
+		PublishingWithBCC("secret"),
+	)
+	assert.Equal(t, Table{
+		HeaderCC:  []any{"audit", "archive"},
+		HeaderBCC: []any{"secret"},
+	}, headers)
+	assert.NoError(t, validateRoutingHeaders(headers))
+
+	// no routing keys, no header
+	assert.Equal(t, Table{}, PublishingHeaders(PublishingWithCC(), PublishingWithBCC()))
+	assert.NoError(t, validateRoutingHeaders(nil))
+
+	// the broker expects arrays of strings
+	assert.ErrorIs(t, validateRoutingHeaders(Table{HeaderCC: "audit"}), ErrInvalidHeader)
+	assert.ErrorIs(t, validateRoutingHeaders(Table{HeaderCC: []string{"audit"}}), ErrInvalidHeader)
+	assert.ErrorIs(t, validateRoutingHeaders(Table{HeaderBCC: []any{"audit", int32(1)}}), ErrInvalidHeader)
+}
+
+func TestPublishingWithDelay(t *testing.T) {
+	t.Parallel()
+
+	headers := PublishingHeaders(PublishingWithDelay(1500 * time.Millisecond))
+	assert.Equal(t, Table{HeaderDelay: int64(1500)}, headers)
+	assert.NoError(t, validateDelayHeader(headers))
+
+	assert.NoError(t, validateDelayHeader(nil))
+	assert.NoError(t, validateDelayHeader(PublishingHeaders(PublishingWithDelay(0))))
+	assert.NoError(t, validateDelayHeader(PublishingHeaders(PublishingWithDelay(maxDelay))))
+	assert.NoError(t, validateDelayHeader(Table{HeaderDelay: int32(10)}))
+
+	// out of range
+	assert.ErrorIs(t, validateDelayHeader(PublishingHeaders(PublishingWithDelay(-time.Second))), ErrInvalidHeader)
+	assert.ErrorIs(t, validateDelayHeader(PublishingHeaders(PublishingWithDelay(maxDelay+time.Millisecond))), ErrInvalidHeader)
+
+	// not an integer
+	assert.ErrorIs(t, validateDelayHeader(Table{HeaderDelay: "1000"}), ErrInvalidHeader)
+
+	// rejected before hitting the broker
+	s := &Session{}
+	_, err := s.Publish(context.Background(), "", "queue", Publishing{Headers: PublishingHeaders(PublishingWithDelay(-time.Second))})
+	assert.ErrorIs(t, err, ErrInvalidHeader)
+}
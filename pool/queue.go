@@ -12,3 +12,81 @@ import "github.com/rabbitmq/amqp091-go"
 Queue captures the current server state of the queue on the server returned from Channel.QueueDeclare or Channel.QueueInspect.
 */
 type Queue amqp091.Queue
+
+const (
+	// QueueKeyMaxPriority is the queue argument that turns a queue into a priority queue (reference: https://www.rabbitmq.com/priority.html)
+	QueueKeyMaxPriority = "x-max-priority"
+
+	// QueueKeySingleActiveConsumer is the queue argument that only allows a single consumer to receive messages at a time (reference: https://www.rabbitmq.com/consumers.html#single-active-consumer)
+	QueueKeySingleActiveConsumer = "x-single-active-consumer"
+)
+
+// QueueArgOption modifies the arguments of a queue declaration.
+type QueueArgOption func(Table)
+
+// QueueArgs creates a new argument table that can be passed to QueueDeclareOptions.Args.
+func QueueArgs(options ...QueueArgOption) Table {
+	args := Table{}
+	for _, o := range options {
+		o(args)
+	}
+	return args
+}
+
+// QueueWithMaxPriority declares a priority queue which supports message priorities from 0 up to maxPriority.
+// The broker treats messages with a higher priority as if they were published with maxPriority.
+// Sessions that declare a priority queue clamp the priority of messages that are published
+// to that queue via the default exchange and log a warning.
+// RabbitMQ recommends a maximum priority between 1 and 10.
+// Priorities are only supported by classic queues.
+func QueueWithMaxPriority(maxPriority uint8) QueueArgOption {
+	return func(t Table) {
+		// RabbitMQ expects int32 for integer values.
+		t[QueueKeyMaxPriority] = int32(maxPriority)
+	}
+}
+
+// QueueWithSingleActiveConsumer declares a queue whose messages are only delivered to a single consumer at a time,
+// which allows ordered processing with automatic failover.
+// All other consumers of the queue are standby consumers, which do not receive any messages until the active consumer
+// is canceled or its channel is closed, in which case the broker activates one of them. Standby consumers are idle
+// and do not fail. As the broker does not notify consumers about their activation, subscribers log their
+// activation as soon as they receive their first message.
+func QueueWithSingleActiveConsumer(enabled bool) QueueArgOption {
+	return func(t Table) {
+		t[QueueKeySingleActiveConsumer] = enabled
+	}
+}
+
+// maxPriority returns the maximum priority of a queue based on its declaration arguments.
+func maxPriority(args Table) (uint8, bool) {
+	var v int64
+	switch p := args[QueueKeyMaxPriority].(type) {
+	case uint8:
+		v = int64(p)
+	case int8:
+		v = int64(p)
+	case int16:
+		v = int64(p)
+	case int32:
+		v = int64(p)
+	case int64:
+		v = p
+	case int:
+		v = int64(p)
+	default:
+		return 0, false
+	}
+	if v < 0 || v > 255 {
+		return 0, false
+	}
+	return uint8(v), true
+}
+
+// clampPriority limits the priority to the maximum priority of a queue.
+func clampPriority(priority, maxPriority uint8) uint8 {
+	if priority > maxPriority {
+		return maxPriority
+	}
+	return priority
+}
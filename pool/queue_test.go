@@ -0,0 +1,37 @@
+package pool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueueWithMaxPriority(t *testing.T) {
+	t.Parallel()
+
+	args := QueueArgs(QueueWithMaxPriority(5))
+	assert.Equal(t, Table{QueueKeyMaxPriority: int32(5)}, args)
+	assert.NoError(t, args.Validate())
+
+	limit, ok := maxPriority(args)
+	assert.True(t, ok)
+	assert.Equal(t, uint8(5), limit)
+
+	_, ok = maxPriority(QuorumQueue)
+	assert.False(t, ok)
+
+	_, ok = maxPriority(Table{QueueKeyMaxPriority: int64(256)})
+	assert.False(t, ok)
+
+	assert.Equal(t, uint8(5), clampPriority(9, limit))
+	assert.Equal(t, uint8(5), clampPriority(5, limit))
+	assert.Equal(t, uint8(3), clampPriority(3, limit))
+}
+
+func TestQueueWithSingleActiveConsumer(t *testing.T) {
+	t.Parallel()
+
+	args := QueueArgs(QueueWithSingleActiveConsumer(true), QueueWithMaxPriority(5))
+	assert.Equal(t, Table{QueueKeySingleActiveConsumer: true, QueueKeyMaxPriority: int32(5)}, args)
+	assert.NoError(t, args.Validate())
+}
@@ -0,0 +1,98 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket that limits the number of publishings per second.
+// It is shared by all sessions of a session pool.
+type rateLimiter struct {
+	mu          sync.Mutex
+	rate        float64 // tokens per second
+	burst       float64 // maximum number of tokens
+	tokens      float64 // may become negative for reserved tokens of waiting publishers
+	last        time.Time
+	nonBlocking bool
+}
+
+// newRateLimiter returns nil in case perSecond is not positive, which disables the limit.
+func newRateLimiter(perSecond float64, burst int, nonBlocking bool) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rate:        perSecond,
+		burst:       float64(burst),
+		tokens:      float64(burst),
+		last:        time.Now(),
+		nonBlocking: nonBlocking,
+	}
+}
+
+// wait takes a token from the bucket and blocks until the token is available or ctx is done.
+// A non-blocking limiter returns ErrRateLimited instead of blocking.
+// A nil limiter does not limit at all.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	delay, err := l.reserve()
+	if err != nil {
+		return err
+	}
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	drained := false
+	defer closeTimer(timer, &drained)
+
+	select {
+	case <-ctx.Done():
+		l.cancel()
+		return fmt.Errorf("failed to wait for publish rate limit: %w", ctx.Err())
+	case <-timer.C:
+		drained = true
+		return nil
+	}
+}
+
+// reserve takes a token and returns the duration until the token is available.
+func (l *rateLimiter) reserve() (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0, nil
+	}
+
+	if l.nonBlocking {
+		return 0, fmt.Errorf("%w: more than %g publishings per second", ErrRateLimited, l.rate)
+	}
+
+	l.tokens--
+	return time.Duration(-l.tokens / l.rate * float64(time.Second)), nil
+}
+
+// cancel returns a reserved token that was not used.
+func (l *rateLimiter) cancel() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens++
+}
@@ -0,0 +1,75 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx       = context.Background()
+		perSecond = 100.0
+		burst     = 10
+		publishes = 60
+		l         = newRateLimiter(perSecond, burst, false)
+		wg        sync.WaitGroup
+	)
+
+	// shared by concurrent publishers
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < publishes/3; j++ {
+				assert.NoError(t, l.wait(ctx))
+			}
+		}()
+	}
+	wg.Wait()
+
+	// the burst is available immediately, the rest is limited
+	elapsed := time.Since(start)
+	expected := time.Duration(float64(publishes-burst) / perSecond * float64(time.Second))
+	assert.GreaterOrEqual(t, elapsed, expected-10*time.Millisecond)
+	assert.Less(t, elapsed, 2*expected)
+}
+
+func TestRateLimiterNonBlocking(t *testing.T) {
+	t.Parallel()
+
+	l := newRateLimiter(1, 2, true)
+	require.NoError(t, l.wait(context.Background()))
+	require.NoError(t, l.wait(context.Background()))
+
+	err := l.wait(context.Background())
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.False(t, recoverable(err))
+}
+
+func TestRateLimiterContext(t *testing.T) {
+	t.Parallel()
+
+	l := newRateLimiter(1, 1, false)
+	require.NoError(t, l.wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, l.wait(ctx), context.DeadlineExceeded)
+
+	// the canceled reservation must be returned
+	l.mu.Lock()
+	assert.Greater(t, l.tokens, -1.0)
+	l.mu.Unlock()
+
+	// disabled
+	assert.Nil(t, newRateLimiter(0, 1, false))
+	assert.NoError(t, (*rateLimiter)(nil).wait(context.Background()))
+}
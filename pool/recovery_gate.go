@@ -0,0 +1,112 @@
+package pool
+
+import (
+	"context"
+	"sync"
+)
+
+// recoveryGate limits the number of connections that are in their recovery loop at the same time and
+// counts the recoveries in flight. It is shared by all connections of a pool.
+// In contrast to the recovery limiter, which limits concurrent reconnect attempts, a connection holds its slot
+// for its whole recovery including the backoff between its reconnect attempts.
+type recoveryGate struct {
+	mu       sync.Mutex
+	limit    int // 0 is unlimited
+	inFlight int
+
+	// queued recoveries, urgent ones are preferred
+	urgent []chan struct{}
+	queued []chan struct{}
+}
+
+// newRecoveryGate creates a gate that admits at most limit concurrent recoveries, limit <= 0 admits any number.
+func newRecoveryGate(limit int) *recoveryGate {
+	if limit < 0 {
+		limit = 0
+	}
+	return &recoveryGate{limit: limit}
+}
+
+// acquire blocks until the recovery may start or until ctx or shutdown is done, in which case false is returned.
+// Urgent recoveries, e.g. of connections that a caller waits for, are admitted before all other queued recoveries.
+// release must be called exactly once in case true is returned.
+// A nil gate admits all recoveries.
+func (g *recoveryGate) acquire(ctx context.Context, shutdown <-chan struct{}, urgent bool) (release func(), ok bool) {
+	if g == nil {
+		return func() {}, true
+	}
+
+	g.mu.Lock()
+	if g.limit == 0 || g.inFlight < g.limit {
+		g.inFlight++
+		g.mu.Unlock()
+		return g.release, true
+	}
+
+	ready := make(chan struct{})
+	if urgent {
+		g.urgent = append(g.urgent, ready)
+	} else {
+		g.queued = append(g.queued, ready)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-ready:
+		return g.release, true
+	case <-ctx.Done():
+	case <-shutdown:
+	}
+
+	g.mu.Lock()
+	removed := g.dequeue(ready)
+	g.mu.Unlock()
+	if !removed {
+		// the slot was handed over concurrently, pass it on
+		g.release()
+	}
+	return nil, false
+}
+
+// release hands the slot of a finished recovery over to the next queued recovery.
+func (g *recoveryGate) release() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var next chan struct{}
+	switch {
+	case len(g.urgent) > 0:
+		next, g.urgent = g.urgent[0], g.urgent[1:]
+	case len(g.queued) > 0:
+		next, g.queued = g.queued[0], g.queued[1:]
+	default:
+		g.inFlight--
+		return
+	}
+	// the number of recoveries in flight does not change
+	close(next)
+}
+
+// dequeue removes a queued recovery and returns false in case it was not queued anymore.
+// not threadsafe
+func (g *recoveryGate) dequeue(ready chan struct{}) bool {
+	for _, queue := range []*[]chan struct{}{&g.urgent, &g.queued} {
+		for i, c := range *queue {
+			if c == ready {
+				*queue = append((*queue)[:i], (*queue)[i+1:]...)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// active returns the number of recoveries in flight, queued recoveries are not taken into account.
+func (g *recoveryGate) active() int {
+	if g == nil {
+		return 0
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.inFlight
+}
@@ -0,0 +1,105 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoveryGateLimit(t *testing.T) {
+	t.Parallel()
+
+	var (
+		g       = newRecoveryGate(2)
+		wg      sync.WaitGroup
+		current atomic.Int64
+		peak    atomic.Int64
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, ok := g.acquire(context.Background(), nil, false)
+			if !assert.True(t, ok) {
+				return
+			}
+			defer release()
+
+			n := current.Add(1)
+			defer current.Add(-1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			assert.LessOrEqual(t, g.active(), 2)
+			time.Sleep(10 * time.Millisecond)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(2), peak.Load(), "concurrency cap must be honored")
+	assert.Equal(t, 0, g.active())
+}
+
+func TestRecoveryGateUrgent(t *testing.T) {
+	t.Parallel()
+
+	g := newRecoveryGate(1)
+	release, ok := g.acquire(context.Background(), nil, false)
+	require.True(t, ok)
+	assert.Equal(t, 1, g.active())
+
+	admitted := make(chan string, 2)
+	queue := func(name string, urgent bool) {
+		go func() {
+			release, ok := g.acquire(context.Background(), nil, urgent)
+			if !ok {
+				return
+			}
+			admitted <- name
+			release()
+		}()
+		// wait until the recovery is queued
+		require.Eventually(t, func() bool {
+			g.mu.Lock()
+			defer g.mu.Unlock()
+			return len(g.urgent)+len(g.queued) > 0 && (name != "urgent" || len(g.urgent) > 0)
+		}, time.Second, time.Millisecond)
+	}
+	queue("queued", false)
+	queue("urgent", true)
+
+	// canceled recoveries leave the queue
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, ok = g.acquire(ctx, nil, true)
+	assert.False(t, ok)
+
+	release()
+	assert.Equal(t, "urgent", <-admitted, "urgent recoveries must be admitted first")
+	assert.Equal(t, "queued", <-admitted)
+	assert.Eventually(t, func() bool { return g.active() == 0 }, time.Second, time.Millisecond)
+
+	// unlimited gates only count recoveries in flight
+	g = newRecoveryGate(0)
+	r1, _ := g.acquire(context.Background(), nil, false)
+	r2, _ := g.acquire(context.Background(), nil, false)
+	assert.Equal(t, 2, g.active())
+	r1()
+	r2()
+	assert.Equal(t, 0, g.active())
+
+	// nil gates admit everything
+	var none *recoveryGate
+	r, ok := none.acquire(context.Background(), nil, false)
+	assert.True(t, ok)
+	r()
+	assert.Equal(t, 0, none.active())
+}
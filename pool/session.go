@@ -22,17 +22,47 @@ type Session struct {
 	cached         bool
 	flagged        bool
 	confirmable    bool
+	confirmNoWait  bool // whether confirm mode is enabled without awaiting the acknowledgement of the broker
 	bufferCapacity int
+	maxMessageSize int // maximum body size of published messages, 0 is unlimited
 
 	channel  *amqp091.Channel
 	returned chan amqp091.Return
 	confirms chan amqp091.Confirmation
 	errors   chan *amqp091.Error
 
+	// closed as soon as the confirmations of the current channel are not needed anymore
+	confirmsDone chan struct{}
+	// channels that were returned by NotifyConfirm, guarded by notifyMu, as confirmations are forwarded
+	// while AwaitConfirm holds the session lock
+	notifyMu         sync.Mutex
+	confirmListeners []chan Confirmation
+	notifyClosed     bool
+
 	conn          *Connection
 	autoCloseConn bool
 
-	consumers map[string]bool // saves consumer names in order to cancel them upon session closure
+	// a cached session that was returned to its pool must not be used until it is acquired again
+	idle bool
+
+	consumers map[string]*sessionConsumer // active consumers which are restored upon recovery and canceled upon session closure
+
+	// channel settings which are re-applied to every new channel upon recovery
+	qos           map[bool]qosSetting // prefetch limits by global flag
+	flowPaused    bool
+	transactional bool
+
+	// number of channels that were opened by this session, used to distinguish delivery tags of different channels
+	channels uint64
+
+	// number of publishings of the current channel whose confirmation was not received via the confirms channel, yet.
+	pendingConfirms int
+	// notified about every received ack or nack of the broker, e.g. in order to count them for the session pool
+	confirmObserver func(ack bool)
+	// limits the rate of publishings, nil is unlimited
+	publishLimiter *rateLimiter
+
+	maxPriorities map[string]uint8 // maximum priorities of priority queues declared by this session
 
 	// a session should not be used in a multithreaded context
 	// but only one session per goroutine. That is why we keep this
@@ -84,7 +114,7 @@ func NewSession(conn *Connection, name string, options ...SessionOption) (*Sessi
 
 	// default values
 	option := sessionOption{
-		Logger:         conn.log, // derive logger form connection
+		Logger:         conn.logger(), // derive logger form connection
 		Cached:         false,
 		Confirmable:    false,
 		BufferCapacity: 10,
@@ -106,17 +136,24 @@ func NewSession(conn *Connection, name string, options ...SessionOption) (*Sessi
 		name:           name,
 		cached:         option.Cached,
 		confirmable:    option.Confirmable,
+		confirmNoWait:  option.ConfirmNoWait,
 		bufferCapacity: option.BufferCapacity,
+		maxMessageSize: option.MaxMessageSize,
 
-		consumers: map[string]bool{},
-		channel:   nil, // will be created on connect
-		errors:    nil, // will be created on connect
-		confirms:  nil, // will be created on connect
-		returned:  nil, // will be created on connect
+		consumers:     map[string]*sessionConsumer{},
+		qos:           map[bool]qosSetting{},
+		maxPriorities: map[string]uint8{},
+		channel:       nil, // will be created on connect
+		errors:        nil, // will be created on connect
+		confirms:      nil, // will be created on connect
+		returned:      nil, // will be created on connect
 
 		conn:          conn,
 		autoCloseConn: option.AutoCloseConn,
 
+		confirmObserver: option.confirmObserver,
+		publishLimiter:  option.publishLimiter,
+
 		ctx:    ctx,
 		cancel: cancel,
 
@@ -162,6 +199,34 @@ func (s *Session) Flag(err error) {
 	}
 }
 
+// borrow marks a returned session as acquired from its pool.
+func (s *Session) borrow() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.idle = false
+}
+
+// release marks the session as returned to its pool.
+// Returns false in case the session was already returned.
+func (s *Session) release() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.idle {
+		return false
+	}
+	s.idle = true
+	return true
+}
+
+// borrowed returns ErrSessionReturned in case the session was returned to its pool.
+// not threadsafe
+func (s *Session) borrowed() error {
+	if s.idle {
+		return fmt.Errorf("%w: %s", ErrSessionReturned, s.name)
+	}
+	return nil
+}
+
 // IsFlagged returns whether the session is flagged.
 func (s *Session) IsFlagged() bool {
 	s.mu.Lock()
@@ -169,6 +234,18 @@ func (s *Session) IsFlagged() bool {
 	return s.flagged
 }
 
+// healthy returns true in case the session can be used without recovering it first.
+// Pending errors flag the session, so that it is recovered by its next user.
+func (s *Session) healthy() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.error(); err != nil {
+		s.flagged = true
+	}
+	return !s.flagged && s.channel != nil && !s.channel.IsClosed()
+}
+
 // Close closes the session completely.
 // Do not use this method in case you have acquired the session
 // from a connection pool.
@@ -194,6 +271,7 @@ func (s *Session) Close() (err error) {
 	}
 	s.debug("closing session context...")
 	s.cancel()
+	defer s.closeConfirmListeners()
 	return s.close()
 }
 
@@ -201,7 +279,11 @@ func (s *Session) close() (err error) {
 	defer func() {
 		s.debug("flushing channels...")
 		flush(s.errors)
-		flush(s.confirms)
+		if s.confirmsDone != nil {
+			close(s.confirmsDone)
+			s.confirmsDone = nil
+		}
+		s.discardConfirms()
 		flush(s.returned)
 
 		if s.channel != nil {
@@ -266,31 +348,157 @@ func (s *Session) connect() (err error) {
 	channel.NotifyClose(s.errors)
 
 	if s.confirmable {
-		s.confirms = make(chan amqp091.Confirmation, s.bufferCapacity)
-		channel.NotifyPublish(s.confirms)
-		err = channel.Confirm(false)
+		err = s.confirm(channel)
 		if err != nil {
 			return err
 		}
-
-		s.returned = make(chan amqp091.Return, s.bufferCapacity)
-		channel.NotifyReturn(s.returned)
 	}
 
-	// reset consumer tracking upon reconnect
-	s.consumers = map[string]bool{}
+	s.channels++
 	s.channel = channel
 
+	return s.restore(channel)
+}
+
+// restore re-applies all recorded settings and consumers of the session to a newly opened channel.
+// Prefetch limits must be applied before the consumers are restored, as they only affect new consumers.
+// not threadsafe
+func (s *Session) restore(channel *amqp091.Channel) error {
+	for _, global := range []bool{true, false} {
+		q, ok := s.qos[global]
+		if !ok {
+			continue
+		}
+		err := channel.Qos(q.prefetchCount, q.prefetchSize, global)
+		if err != nil {
+			return fmt.Errorf("failed to restore qos: %w", err)
+		}
+	}
+
+	if s.flowPaused {
+		err := channel.Flow(false)
+		if err != nil {
+			return fmt.Errorf("failed to restore flow: %w", err)
+		}
+	}
+
+	if s.transactional {
+		err := channel.Tx()
+		if err != nil {
+			return fmt.Errorf("failed to restore transaction mode: %w", err)
+		}
+	}
+
+	err := s.restoreConsumers(channel, s.generation())
+	if err != nil {
+		return fmt.Errorf("failed to restore consumers: %w", err)
+	}
+	return nil
+}
+
+// generation returns the generation of the current channel, starting with 0 for the first channel.
+// not threadsafe
+func (s *Session) generation() uint64 {
+	if s.channels == 0 {
+		return 0
+	}
+	return s.channels - 1
+}
+
+// confirm puts the channel into confirm mode.
+func (s *Session) confirm(channel *amqp091.Channel) error {
+	confirms := make(chan amqp091.Confirmation, s.bufferCapacity)
+	s.confirms = confirms
+	s.confirmsDone = make(chan struct{})
+	// confirmations of previous channels are lost
+	s.pendingConfirms = 0
+	// the confirmations are fanned out to the confirm listeners, see NotifyConfirm
+	go s.forwardConfirms(channel.NotifyPublish(make(chan amqp091.Confirmation)), confirms, s.confirmsDone)
+	// in case of no wait, a rejected confirm mode closes the channel asynchronously,
+	// which closes the confirms channel and fails the next operation, which recovers the session.
+	err := channel.Confirm(s.confirmNoWait)
+	if err != nil {
+		return err
+	}
+
+	s.returned = make(chan amqp091.Return, s.bufferCapacity)
+	channel.NotifyReturn(s.returned)
 	return nil
+}
+
+// EnableConfirms puts an already open session into confirm mode.
+// Published messages must be confirmed afterwards with AwaitConfirm.
+// Confirm mode is one-way: AMQP does not allow to disable it once it has been enabled on a channel.
+// The session stays confirmable for its whole lifetime, including recoveries and
+// after being returned to a session pool.
+// Enabling confirms on a session that is already confirmable is a no-op.
+// The acknowledgement of the broker is not awaited in case of SessionWithConfirmNoWait.
+func (s *Session) EnableConfirms() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.confirmable {
+		return nil
+	}
 
+	if s.channel == nil || s.channel.IsClosed() {
+		return fmt.Errorf("failed to enable confirms: channel %w", ErrClosed)
+	}
+
+	err := s.confirm(s.channel)
+	if err != nil {
+		return fmt.Errorf("failed to enable confirms: %w", err)
+	}
+	s.confirmable = true
+	return nil
 }
 
+// Recover recreates the channel of the session in case it was flagged or closed due to an error.
+// The connection is recovered as well, if necessary.
+// All settings of the session are re-applied to the new channel: confirm mode, prefetch limits (Qos),
+// flow control, transaction mode and all active consumers.
+// The delivery channels that were returned by Consume or ConsumeWithContext stay open and receive
+// the deliveries of the restored consumers.
+// Deliveries that were received before the recovery cannot be (n)acked anymore, as the broker requeues them.
 func (s *Session) Recover(ctx context.Context) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	return s.recover(ctx)
 }
 
+// Reset recreates the channel of the session on its current connection without recovering the connection.
+// This is a fast path for channel level errors that do not affect the connection, e.g. a failed passive declaration,
+// as no connection recovery with backoff is involved.
+// All settings of the session are re-applied to the new channel, see Recover.
+// Reset fails in case the connection is closed or flagged, in which case Recover must be used instead.
+func (s *Session) Reset(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reset(ctx)
+}
+
+func (s *Session) reset(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-s.catchShutdown():
+		return fmt.Errorf("failed to reset session: %w", s.shutdownErr())
+	default:
+		// check if context was closed before resetting the channel.
+	}
+
+	if s.conn.IsClosed() || s.conn.IsFlagged() {
+		return fmt.Errorf("failed to reset session: %w: connection must be recovered", ErrConnectionFailed)
+	}
+
+	err := s.connect() // Creates a new channel and flushes internal buffers automatically.
+	if err != nil {
+		return fmt.Errorf("failed to reset session: %w", err)
+	}
+	s.flagged = false
+	return nil
+}
+
 func (s *Session) tryRecover(ctx context.Context, err error) error {
 	if err == nil {
 		return nil
@@ -315,9 +523,12 @@ func (s *Session) recover(ctx context.Context) error {
 
 	// check if session/channel needs to be recovered
 	err := s.error()
-	if err == nil {
+	if err == nil && !s.flagged && s.channel != nil && !s.channel.IsClosed() {
 		return nil
 	}
+	if err == nil {
+		err = fmt.Errorf("session flagged or channel %w", ErrClosed)
+	}
 	s.warnf(err, "recovering session due to error: %v", err)
 
 	// necessary for cleanup and to cleanup potentially dangling open sessions
@@ -331,7 +542,20 @@ func (s *Session) recover(ctx context.Context) error {
 			// allow a user to hook into the recovery process of a session
 			// this is expected to not be called often, as the connection should be the main cause
 			// of errors and not necessarily the session.
-			s.recoverCB(s.conn.Name(), s.name, try, err)
+			s.notifyRetry(s.recoverCB, try, err)
+			if !s.flagged && s.channel != nil && !s.channel.IsClosed() {
+				// recovered by another goroutine while the lock was released
+				return nil
+			}
+
+			// the session might have been closed while the lock was released
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-s.catchShutdown():
+				return fmt.Errorf("failed to recover session: %w", s.shutdownErr())
+			default:
+			}
 		}
 
 		err = s.conn.Recover(ctx) // recovers connection with a backoff mechanism
@@ -358,11 +582,35 @@ func (s *Session) recover(ctx context.Context) error {
 func (s *Session) AwaitConfirm(ctx context.Context, expectedTag uint64) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	return s.awaitConfirm(ctx, expectedTag)
+}
+
+// awaitEpochConfirm awaits the confirmation of a message that was published on the channel of the passed epoch.
+// In case the channel was recovered in the meantime, the confirmation is lost and ErrConfirmLost is returned.
+// not threadsafe
+func (s *Session) awaitEpochConfirm(ctx context.Context, epoch, expectedTag uint64) error {
+	if s.generation() != epoch {
+		return fmt.Errorf("%w: channel of session %s was recovered", ErrConfirmLost, s.Name())
+	}
 
+	err := s.awaitConfirm(ctx, expectedTag)
+	if err != nil {
+		return fmt.Errorf("failed to await confirm of session %s: %w", s.Name(), err)
+	}
+	return nil
+}
+
+// not threadsafe
+func (s *Session) awaitConfirm(ctx context.Context, expectedTag uint64) error {
 	if !s.confirmable {
 		return ErrNoConfirms
 	}
 
+	err := s.borrowed()
+	if err != nil {
+		return err
+	}
+
 	select {
 	// TODO: this might lead to problems when a single session is used
 	// in a multithreaded context. That way we might received out of order confirmations
@@ -375,6 +623,8 @@ func (s *Session) AwaitConfirm(ctx context.Context, expectedTag uint64) error {
 			}
 			return fmt.Errorf("await confirm failed: confirms channel %w", ErrClosed)
 		}
+		s.confirmed(1)
+		s.observeConfirm(confirm.Ack)
 		if !confirm.Ack {
 			// in case the server did not accept the message, it might be due to resource problems.
 			// TODO: do we want to pause here upon flow control messages
@@ -412,6 +662,83 @@ func (s *Session) AwaitConfirm(ctx context.Context, expectedTag uint64) error {
 	}
 }
 
+// OutstandingConfirms returns the number of published messages whose confirmation was not received from the broker, yet.
+// Confirmations that were received but not awaited via AwaitConfirm are not outstanding.
+// Confirmations of messages that were published on a channel that was recovered in the meantime are lost and
+// thus not outstanding anymore.
+func (s *Session) OutstandingConfirms() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.outstandingConfirms()
+}
+
+// not threadsafe
+func (s *Session) outstandingConfirms() int {
+	n := s.pendingConfirms - len(s.confirms)
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// WaitConfirms blocks until the broker confirmed all published messages or until the context is canceled.
+// The received confirmations are not consumed, they must still be awaited via AwaitConfirm or discarded via Flush.
+// As the broker cannot deliver more confirmations than the buffer capacity of the session can hold,
+// at most that many messages should be published without awaiting their confirmations.
+// An error is returned in case the channel is closed, as the outstanding confirmations are lost in that case.
+func (s *Session) WaitConfirms(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		s.mu.Lock()
+		n := s.outstandingConfirms()
+		closed := s.channel != nil && s.channel.IsClosed()
+		s.mu.Unlock()
+
+		if n == 0 {
+			return nil
+		}
+		if closed {
+			return fmt.Errorf("failed to wait for %d confirms: channel %w", n, ErrClosed)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return fmt.Errorf("failed to wait for %d confirms: %w", n, ctx.Err())
+		case <-s.catchShutdown():
+			return fmt.Errorf("failed to wait for %d confirms: %w", n, s.shutdownErr())
+		}
+	}
+}
+
+// discardConfirms drops all received confirmations that were not awaited.
+// not threadsafe
+func (s *Session) discardConfirms() {
+	confirms := flush(s.confirms)
+	s.confirmed(len(confirms))
+	for _, c := range confirms {
+		s.observeConfirm(c.Ack)
+	}
+}
+
+// observeConfirm reports a received confirmation to the confirm observer, if any.
+func (s *Session) observeConfirm(ack bool) {
+	if s.confirmObserver != nil {
+		s.confirmObserver(ack)
+	}
+}
+
+// confirmed marks n confirmations as received.
+// not threadsafe
+func (s *Session) confirmed(n int) {
+	s.pendingConfirms -= n
+	if s.pendingConfirms < 0 {
+		s.pendingConfirms = 0
+	}
+}
+
 // Publishing captures the client message sent to the server.  The fields
 // outside of the Headers table included in this struct mirror the underlying
 // fields in the content frame.  They use native types for convenience and
@@ -456,19 +783,27 @@ type Publishing struct {
 // Publishing delivery tags and their corresponding confirmations start at 1. Exit when all publishings are confirmed.
 // When Publish does not return an error and the channel is in confirm mode, the internal counter for DeliveryTags with the first confirmation starts at 1.
 func (s *Session) Publish(ctx context.Context, exchange string, routingKey string, msg Publishing) (deliveryTag uint64, err error) {
+	deliveryTag, _, err = s.publish(ctx, exchange, routingKey, msg)
+	return deliveryTag, err
+}
+
+// publish publishes the message and returns its delivery tag as well as the epoch of the channel
+// on which it was published.
+func (s *Session) publish(ctx context.Context, exchange string, routingKey string, msg Publishing) (deliveryTag, epoch uint64, err error) {
+	// do not block other users of the session while waiting for the rate limit
+	err = s.publishLimiter.wait(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to publish message: %w", err)
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// we want to have a persistent messages by default
-	// this allows to even in a disaster case where the rabbitmq node is restarted or crashes
-	// to still have our messages persisted to disk.
-	// https://www.rabbitmq.com/persistence-conf.html#how-it-works
-	var amqpDeliverMode uint8
-	if msg.DeliveryMode == 1 {
-		amqpDeliverMode = 1 // transient (purged upon rabbitmq restart)
-	} else {
-		amqpDeliverMode = 2 // persistent (persisted to disk upon arrival in queue)
+	err = s.validatePublishing(msg)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to publish message: %w", err)
 	}
+	publishing := s.publishing(exchange, routingKey, msg)
 
 	err = s.retry(ctx, s.publishRetryCB, func() error {
 		deliveryTag = 0
@@ -482,22 +817,7 @@ func (s *Session) Publish(ctx context.Context, exchange string, routingKey strin
 			routingKey,
 			msg.Mandatory,
 			msg.Immediate,
-			amqp091.Publishing{
-				Headers:         msg.Headers,
-				ContentType:     msg.ContentType,
-				ContentEncoding: msg.ContentEncoding,
-				DeliveryMode:    amqpDeliverMode,
-				Priority:        msg.Priority,
-				CorrelationId:   msg.CorrelationId,
-				ReplyTo:         msg.ReplyTo,
-				Expiration:      msg.Expiration,
-				MessageId:       msg.MessageId,
-				Timestamp:       msg.Timestamp,
-				Type:            msg.Type,
-				UserId:          msg.UserId,
-				AppId:           msg.AppId,
-				Body:            msg.Body,
-			},
+			publishing,
 		)
 		if err != nil {
 			return err
@@ -505,9 +825,64 @@ func (s *Session) Publish(ctx context.Context, exchange string, routingKey strin
 		return nil
 	})
 	if err != nil {
-		return 0, err
+		return 0, 0, err
+	}
+	if s.confirmable {
+		s.pendingConfirms++
+	}
+	return deliveryTag, s.generation(), nil
+}
+
+// validatePublishing rejects messages that the broker would not accept or route as expected,
+// before they are sent to the broker.
+// not threadsafe
+func (s *Session) validatePublishing(msg Publishing) error {
+	if s.maxMessageSize > 0 && len(msg.Body) > s.maxMessageSize {
+		return fmt.Errorf("%w: body of %d bytes exceeds the maximum message size of %d bytes", ErrMessageTooLarge, len(msg.Body), s.maxMessageSize)
+	}
+	err := validateRoutingHeaders(msg.Headers)
+	if err != nil {
+		return err
+	}
+	return validateDelayHeader(msg.Headers)
+}
+
+// publishing converts the message into an amqp publishing.
+// not threadsafe
+func (s *Session) publishing(exchange string, routingKey string, msg Publishing) amqp091.Publishing {
+	// we want to have a persistent messages by default
+	// this allows to even in a disaster case where the rabbitmq node is restarted or crashes
+	// to still have our messages persisted to disk.
+	// https://www.rabbitmq.com/persistence-conf.html#how-it-works
+	var amqpDeliverMode uint8
+	if msg.DeliveryMode == 1 {
+		amqpDeliverMode = 1 // transient (purged upon rabbitmq restart)
+	} else {
+		amqpDeliverMode = 2 // persistent (persisted to disk upon arrival in queue)
+	}
+
+	// only publishings via the default exchange can be associated with a queue
+	if limit, ok := s.maxPriorities[routingKey]; ok && exchange == "" && msg.Priority > limit {
+		s.slog().Warnf("publishing priority %d exceeds the maximum priority %d of queue %s, clamping priority to %d", msg.Priority, limit, routingKey, limit)
+		msg.Priority = clampPriority(msg.Priority, limit)
+	}
+
+	return amqp091.Publishing{
+		Headers:         msg.Headers,
+		ContentType:     msg.ContentType,
+		ContentEncoding: msg.ContentEncoding,
+		DeliveryMode:    amqpDeliverMode,
+		Priority:        msg.Priority,
+		CorrelationId:   msg.CorrelationId,
+		ReplyTo:         msg.ReplyTo,
+		Expiration:      msg.Expiration,
+		MessageId:       msg.MessageId,
+		Timestamp:       msg.Timestamp,
+		Type:            msg.Type,
+		UserId:          msg.UserId,
+		AppId:           msg.AppId,
+		Body:            msg.Body,
 	}
-	return deliveryTag, nil
 }
 
 // Get is only supposed to be used for testing purposes, do not us eit to poll the queue periodically.
@@ -525,9 +900,42 @@ func (s *Session) Get(ctx context.Context, queue string, autoAck bool) (msg Deli
 	if err != nil {
 		return Delivery{}, false, err
 	}
+	if ok {
+		msg.DeliveryTag = toSessionDeliveryTag(s.generation(), msg.DeliveryTag)
+		msg.Acknowledger = sessionAcknowledger{s}
+	}
 	return msg, ok, nil
 }
 
+// GetOne fetches a single message from the queue.
+// In contrast to Get a nil delivery is returned in case the queue is empty.
+// Messages that are not auto acked must be (n)acked on the same channel, see Epoch and DeliveryEpoch
+// in order to detect whether the channel was recovered in the meantime.
+func (s *Session) GetOne(ctx context.Context, queue string, autoAck bool) (*Delivery, error) {
+	msg, ok, err := s.Get(ctx, queue, autoAck)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, nil
+	}
+	return &msg, nil
+}
+
+// Epoch returns the epoch of the current channel of the session, which is incremented every time the channel is recovered.
+// Deliveries with a different epoch cannot be (n)acked anymore, see DeliveryEpoch.
+func (s *Session) Epoch() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.generation()
+}
+
+// DeliveryEpoch returns the epoch of the channel on which the message with the passed delivery tag was delivered.
+func DeliveryEpoch(deliveryTag uint64) uint64 {
+	generation, _ := fromSessionDeliveryTag(deliveryTag)
+	return generation
+}
+
 // Nack rejects the message.
 // In case the underlying channel dies, you cannot send a nack for the processed message.
 // You might receive the message again from the broker, as it expects a n/ack
@@ -535,7 +943,16 @@ func (s *Session) Nack(deliveryTag uint64, multiple bool, requeue bool) (err err
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	return s.channel.Nack(deliveryTag, multiple, requeue)
+	err = s.borrowed()
+	if err != nil {
+		return fmt.Errorf("failed to nack message: %w", err)
+	}
+
+	tag, err := s.channelDeliveryTag(deliveryTag)
+	if err != nil {
+		return fmt.Errorf("failed to nack message: %w", err)
+	}
+	return s.channel.Nack(tag, multiple, requeue)
 }
 
 // Ack confirms the processing of the message.
@@ -545,7 +962,51 @@ func (s *Session) Ack(deliveryTag uint64, multiple bool) (err error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	return s.channel.Ack(deliveryTag, multiple)
+	err = s.borrowed()
+	if err != nil {
+		return fmt.Errorf("failed to ack message: %w", err)
+	}
+
+	tag, err := s.channelDeliveryTag(deliveryTag)
+	if err != nil {
+		return fmt.Errorf("failed to ack message: %w", err)
+	}
+	return s.channel.Ack(tag, multiple)
+}
+
+// Reject rejects a single message, which is requeued or, in case requeue is false, discarded or dead-lettered
+// in case the queue has a dead letter exchange.
+// In contrast to Nack, Reject cannot reject multiple messages at once.
+// Messages that were delivered on a previous channel of the session cannot be rejected anymore, see ErrStaleDeliveryTag.
+func (s *Session) Reject(deliveryTag uint64, requeue bool) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err = s.borrowed()
+	if err != nil {
+		return fmt.Errorf("failed to reject message: %w", err)
+	}
+
+	tag, err := s.channelDeliveryTag(deliveryTag)
+	if err != nil {
+		return fmt.Errorf("failed to reject message: %w", err)
+	}
+	return s.channel.Reject(tag, requeue)
+}
+
+// channelDeliveryTag translates a delivery tag of this session into a delivery tag of the current channel.
+// The delivery tag 0, which (n)acks all outstanding messages in combination with multiple,
+// always refers to the current channel.
+// not threadsafe
+func (s *Session) channelDeliveryTag(deliveryTag uint64) (uint64, error) {
+	if deliveryTag == 0 {
+		return 0, nil
+	}
+	generation, tag := fromSessionDeliveryTag(deliveryTag)
+	if generation != s.generation() {
+		return 0, ErrStaleDeliveryTag
+	}
+	return tag, nil
 }
 
 type ConsumeOptions struct {
@@ -553,6 +1014,10 @@ type ConsumeOptions struct {
 	// An empty string will cause the library to generate a unique identity.
 	// The consumer identity will be included in every Delivery in the ConsumerTag field
 	ConsumerTag string
+	// ConsumerTagPrefix is prepended to the consumer tag that is generated in case ConsumerTag is empty,
+	// e.g. the pod or service name, which allows to identify the instance that holds a consumer in the management UI.
+	// Generated tags are kept when the consumer is restored upon recovery.
+	ConsumerTagPrefix string
 	// When AutoAck (also known as noAck) is true, the server will acknowledge deliveries to this consumer prior to writing the delivery to the network. When autoAck is true, the consumer should not call Delivery.Ack.
 	// Automatically acknowledging deliveries means that some deliveries may get lost if the consumer is unable to process them after the server delivers them. See http://www.rabbitmq.com/confirms.html for more details.
 	AutoAck bool
@@ -566,12 +1031,33 @@ type ConsumeOptions struct {
 	NoWait bool
 	// Args are aditional implementation dependent parameters.
 	Args Table
+	// NackPolicy decides whether a message is requeued or dead lettered in case the handler
+	// of a Subscriber returns an error. By default messages are requeued.
+	// ErrReject and ErrRejectSingle always dead letter messages.
+	// The policy is only used by the Subscriber and has no effect on AutoAck consumers.
+	NackPolicy NackPolicy
+	// Topology is re-applied by the Subscriber before it restarts a consumer whose deliveries were closed,
+	// e.g. because the broker canceled the consumer during a failover of its queue.
+	// Auto-delete or transient queues may lose their bindings in that case, which would otherwise
+	// leave the restarted consumer on a queue that does not receive any messages.
+	// The topology is only used by the Subscriber.
+	Topology TopologyFunc
+	// Backpressure adapts the prefetch count of the consumer to the load of its downstream dependencies.
+	// Backpressure is disabled in case it is nil (default). It is only used by the Subscriber.
+	Backpressure *Backpressure
+	// Prefetch is the prefetch count of this consumer. It is applied per consumer (QosOptions.Global false)
+	// right before the consumer is started or restored and does not change the limits of other consumers
+	// that share the session. Limits that are shared by all consumers of the session (QosOptions.Global true)
+	// still apply. A prefetch count <= 0 uses the limits of the session, see Session.Qos.
+	Prefetch int
 }
 
 // Consume immediately starts delivering queued messages.
 //
 // Begin receiving on the returned chan Delivery before any other operation on the Connection or Channel.
-// Continues deliveries to the returned chan Delivery until Channel.Cancel, Connection.Close, Channel.Close, or an AMQP exception occurs.
+// Continues deliveries to the returned chan Delivery until Session.Cancel, Session.Close or until the session cannot be recovered anymore.
+// In case the channel is closed due to an error, the session is recovered and the consumer is restored on the new channel
+// without closing the returned chan Delivery.
 // Consumers must range over the chan to ensure all deliveries are received.
 //
 // Unreceived deliveries will block all methods on the same connection.
@@ -599,27 +1085,16 @@ func (s *Session) Consume(queue string, option ...ConsumeOptions) (<-chan Delive
 		o = option[0]
 	}
 
-	if o.ConsumerTag == "" {
-		// use our own consumer naming
-		o.ConsumerTag = s.Name()
-
+	tag, err := s.consumerTag(o)
+	if err != nil {
+		return nil, err
 	}
+	o.ConsumerTag = tag
 
-	var (
-		c   <-chan Delivery
-		err error
-	)
+	var c <-chan Delivery
 	// retries to connect and attempts to start a consumer
 	err = s.retry(s.ctx, s.consumeRetryCB, func() error {
-		c, err = s.channel.Consume(
-			queue,
-			o.ConsumerTag,
-			o.AutoAck,
-			o.Exclusive,
-			o.NoLocal,
-			o.NoWait,
-			o.Args,
-		)
+		c, err = s.consume(context.Background(), s.channel, queue, o)
 		if err != nil {
 			return err
 		}
@@ -628,15 +1103,16 @@ func (s *Session) Consume(queue string, option ...ConsumeOptions) (<-chan Delive
 	if err != nil {
 		return nil, err
 	}
-	s.consumers[o.ConsumerTag] = true
 
-	return c, nil
+	return s.addConsumer(s.ctx, queue, o, c), nil
 }
 
 // Consume immediately starts delivering queued messages.
 //
 // Begin receiving on the returned chan Delivery before any other operation on the Connection or Channel.
-// Continues deliveries to the returned chan Delivery until Channel.Cancel, Connection.Close, Channel.Close, or an AMQP exception occurs.
+// Continues deliveries to the returned chan Delivery until Session.Cancel, Session.Close or until the session cannot be recovered anymore.
+// In case the channel is closed due to an error, the session is recovered and the consumer is restored on the new channel
+// without closing the returned chan Delivery.
 // Consumers must range over the chan to ensure all deliveries are received.
 //
 // Unreceived deliveries will block all methods on the same connection.
@@ -664,28 +1140,16 @@ func (s *Session) ConsumeWithContext(ctx context.Context, queue string, option .
 		o = option[0]
 	}
 
-	if o.ConsumerTag == "" {
-		// use our own consumer naming
-		o.ConsumerTag = s.Name()
-
+	tag, err := s.consumerTag(o)
+	if err != nil {
+		return nil, err
 	}
+	o.ConsumerTag = tag
 
-	var (
-		c   <-chan Delivery
-		err error
-	)
+	var c <-chan Delivery
 	// retries to connect and attempts to start a consumer
 	err = s.retry(ctx, s.consumeContextRetryCB, func() error {
-		c, err = s.channel.ConsumeWithContext(
-			ctx,
-			queue,
-			o.ConsumerTag,
-			o.AutoAck,
-			o.Exclusive,
-			o.NoLocal,
-			o.NoWait,
-			o.Args,
-		)
+		c, err = s.consume(ctx, s.channel, queue, o)
 		if err != nil {
 			return err
 		}
@@ -694,21 +1158,61 @@ func (s *Session) ConsumeWithContext(ctx context.Context, queue string, option .
 	if err != nil {
 		return nil, err
 	}
-	s.consumers[o.ConsumerTag] = true
 
-	return c, nil
+	return s.addConsumer(ctx, queue, o, c), nil
+}
+
+// Cancel stops deliveries to the consumer chan that was returned by Consume or ConsumeWithContext
+// and that is identified by the consumerTag.
+// Other consumers of this session are not affected.
+//
+// Deliveries that were already sent by the server before the cancellation are still delivered
+// to the consumer chan, which is closed afterwards. Consumers should keep ranging over the chan
+// until it is closed in order to (n)ack those in-flight deliveries.
+//
+// When noWait is true, do not wait for the server to acknowledge the cancel.
+// Only use this when you are certain there are no deliveries in flight that
+// require an acknowledgment, otherwise they will arrive and be dropped in the
+// client without an ack, and will not be redelivered to other consumers.
+func (s *Session) Cancel(consumerTag string, noWait bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.borrowed()
+	if err != nil {
+		return fmt.Errorf("failed to cancel consumer %s: %w", consumerTag, err)
+	}
+
+	// a canceled consumer must not be restored upon recovery
+	if c, ok := s.consumers[consumerTag]; ok {
+		s.removeConsumer(c)
+	}
+
+	if s.channel == nil || s.channel.IsClosed() {
+		return fmt.Errorf("failed to cancel consumer %s: channel %w", consumerTag, ErrClosed)
+	}
+
+	err = s.channel.Cancel(consumerTag, noWait)
+	if err != nil {
+		return fmt.Errorf("failed to cancel consumer %s: %w", consumerTag, err)
+	}
+	return nil
 }
 
 func (s *Session) retry(ctx context.Context, cb sessionRetryCallback, f func() error) error {
+	err := s.borrowed()
+	if err != nil {
+		return err
+	}
 
 	for try := 0; ; try++ {
-		err := f()
+		err = f()
 		if err == nil {
 			return nil
 		}
 
 		if cb != nil {
-			cb(s.conn.Name(), s.name, try, err)
+			s.notifyRetry(cb, try, err)
 		}
 		err = s.tryRecover(ctx, err)
 		if err != nil {
@@ -717,6 +1221,20 @@ func (s *Session) retry(ctx context.Context, cb sessionRetryCallback, f func() e
 	}
 }
 
+// notifyRetry calls a retry or recover callback without holding the session lock,
+// which allows the callback to use the session without deadlocking.
+// not threadsafe, must be called while holding the lock
+func (s *Session) notifyRetry(cb sessionRetryCallback, try int, err error) {
+	connName, log := s.conn.Name(), s.slog()
+	s.mu.Unlock()
+	defer s.mu.Lock()
+
+	_ = callSafe(log, "session retry callback", ErrCallbackPanic, func() error {
+		cb(connName, s.name, try, err)
+		return nil
+	})
+}
+
 type ExchangeDeclareOptions struct {
 	// Durable and Non-Auto-Deleted exchanges will survive server restarts and remain
 	// declared when there are no remaining bindings.  This is the best lifetime for
@@ -760,7 +1278,8 @@ type ExchangeDeclareOptions struct {
 	AutoDelete bool
 	// Exchanges declared as `internal` do not accept accept publishings. Internal
 	// exchanges are useful when you wish to implement inter-exchange topologies
-	// that should not be exposed to users of the broker.
+	// that should not be exposed to users of the broker, see ExchangeWithInternal.
+	// Publishing to an internal exchange closes the channel with ACCESS_REFUSED.
 	Internal bool
 	// When NoWait is true, declare without waiting for a confirmation from the server.
 	// The channel may be closed as a result of an error.  Add a NotifyClose listener
@@ -992,6 +1511,10 @@ func (s *Session) QueueDeclare(ctx context.Context, name string, option ...Queue
 		return Queue{}, err
 	}
 
+	if limit, ok := maxPriority(o.Args); ok {
+		s.maxPriorities[queue.Name] = limit
+	}
+
 	return Queue(queue), nil
 }
 
@@ -1085,6 +1608,7 @@ func (s *Session) QueueDelete(ctx context.Context, name string, option ...QueueD
 	if err != nil {
 		return 0, err
 	}
+	delete(s.maxPriorities, name)
 	return purgedMsgs, nil
 }
 
@@ -1187,6 +1711,9 @@ type QueuePurgeOptions struct {
 // Messages that have been delivered but have not yet been acknowledged will not be removed.
 // When successful, returns the number of messages purged.
 func (s *Session) QueuePurge(ctx context.Context, name string, options ...QueuePurgeOptions) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	opt := QueuePurgeOptions{
 		NoWait: false,
 	}
@@ -1334,16 +1861,52 @@ than two times your network round trip time, you will see significant
 throughput improvements starting with a prefetch count of 2 or slightly
 greater as described by benchmarks on RabbitMQ.
 
+By default the prefetch limits are applied to each consumer of this session individually,
+even if multiple consumers are registered on the same session (channel).
+Consumers that are started before calling Qos are not affected by the new limits.
+Use QosOptions.Global in order to share the limits between all consumers of this session
+and ConsumeOptions.Prefetch in order to give a single consumer its own limit.
+
 http://www.rabbitmq.com/blog/2012/04/25/rabbitmq-performance-measurements-part-2/
 */
-func (s *Session) Qos(ctx context.Context, prefetchCount int, prefetchSize int) error {
+func (s *Session) Qos(ctx context.Context, prefetchCount int, prefetchSize int, option ...QosOptions) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	return s.retry(ctx, s.qosRetryCB, func() error {
+	// defaults
+	o := QosOptions{
+		Global: false,
+	}
+	if len(option) > 0 {
+		o = option[0]
+	}
+
+	err := s.retry(ctx, s.qosRetryCB, func() error {
 		// session quos should not affect new sessions of the same connection
-		return s.channel.Qos(prefetchCount, prefetchSize, false)
+		return s.channel.Qos(prefetchCount, prefetchSize, o.Global)
 	})
+	if err != nil {
+		return err
+	}
+
+	// re-applied upon recovery
+	s.qos[o.Global] = qosSetting{prefetchCount: prefetchCount, prefetchSize: prefetchSize}
+	return nil
+}
+
+type qosSetting struct {
+	prefetchCount int
+	prefetchSize  int
+}
+
+type QosOptions struct {
+	// When Global is false (default), the prefetch limits are applied separately to each new consumer on this session.
+	// When Global is true, the prefetch limits are shared across all consumers on this session.
+	// In both cases the limits never affect other sessions of the same connection.
+	//
+	// RabbitMQ does not implement the AMQP 0-9-1 semantics of the global flag (per connection limits).
+	// See https://www.rabbitmq.com/consumer-prefetch.html for more details.
+	Global bool
 }
 
 // Flow allows to enable or disable flow from the message broker
@@ -1371,9 +1934,16 @@ func (s *Session) Flow(ctx context.Context, active bool) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	return s.retry(ctx, s.flowRetryCB, func() error {
+	err := s.retry(ctx, s.flowRetryCB, func() error {
 		return s.channel.Flow(active)
 	})
+	if err != nil {
+		return err
+	}
+
+	// re-applied upon recovery
+	s.flowPaused = !active
+	return nil
 }
 
 // Tx puts the channel into transaction mode on the server. All publishings and acknowledgments following this method
@@ -1390,7 +1960,14 @@ func (s *Session) Tx() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	return s.channel.Tx()
+	err := s.channel.Tx()
+	if err != nil {
+		return err
+	}
+
+	// re-applied upon recovery
+	s.transactional = true
+	return nil
 }
 
 // TxCommit atomically commits all publishings and acknowledgments for a single queue and immediately start a new transaction.
@@ -1471,6 +2048,8 @@ func (s *Session) IsCached() bool {
 
 // IsConfirmable returns true in case this session requires that after Publishing a message you also MUST Await its confirmation
 func (s *Session) IsConfirmable() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.confirmable
 }
 
@@ -1514,7 +2093,7 @@ func (s *Session) Flush() {
 	// as it i sneeded for checking whether a session recovery is needed
 
 	flush(s.errors)
-	flush(s.confirms)
+	s.discardConfirms()
 	flush(s.returned)
 }
 
@@ -0,0 +1,91 @@
+package pool
+
+import (
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// Confirmation is a publisher confirm of the broker, see Session.NotifyConfirm.
+type Confirmation struct {
+	// DeliveryTag is the publish sequence number of the confirmed message, which is returned by Session.Publish.
+	// Sequence numbers restart at 1 whenever the channel of the session is recovered.
+	DeliveryTag uint64
+	// Ack is false in case the broker rejected the message (nack).
+	Ack bool
+	// ReceivedAt is the point in time at which the confirmation was received.
+	ReceivedAt time.Time
+}
+
+// NotifyConfirm returns a channel that receives every confirmation of the broker as soon as it arrives,
+// in addition to the confirmations that are awaited via AwaitConfirm, WaitConfirms or a ConfirmGroup.
+// This allows to build custom confirm tracking, e.g. for an outbox or for metrics.
+// The channel outlives recoveries of the session and is closed when the session is closed.
+// It has the buffer capacity of the session and MUST be consumed, as the broker does not deliver further
+// confirmations to the session until there is room for them.
+// The session must be confirmable, see SessionWithConfirms and EnableConfirms.
+func (s *Session) NotifyConfirm() <-chan Confirmation {
+	s.notifyMu.Lock()
+	defer s.notifyMu.Unlock()
+
+	c := make(chan Confirmation, s.bufferCapacity)
+	if s.notifyClosed {
+		close(c)
+		return c
+	}
+	s.confirmListeners = append(s.confirmListeners, c)
+	return c
+}
+
+// forwardConfirms passes the confirmations of a single channel to the confirms channel of the session, which is
+// consumed by AwaitConfirm, and to all confirm listeners. The confirms channel is closed when the amqp channel is closed.
+// done is closed as soon as the session stopped using the channel, so that remaining confirmations are dropped.
+func (s *Session) forwardConfirms(raw <-chan amqp091.Confirmation, confirms chan<- amqp091.Confirmation, done <-chan struct{}) {
+	defer close(confirms)
+
+	for c := range raw {
+		select {
+		case confirms <- c:
+		case <-done:
+			// drain, otherwise the amqp library blocks
+			for range raw {
+			}
+			return
+		}
+		s.notifyConfirm(Confirmation{
+			DeliveryTag: c.DeliveryTag,
+			Ack:         c.Ack,
+			ReceivedAt:  time.Now(),
+		}, done)
+	}
+}
+
+func (s *Session) notifyConfirm(c Confirmation, done <-chan struct{}) {
+	s.notifyMu.Lock()
+	defer s.notifyMu.Unlock()
+
+	for _, l := range s.confirmListeners {
+		select {
+		case l <- c:
+		case <-done:
+			return
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// closeConfirmListeners closes all channels that were returned by NotifyConfirm.
+func (s *Session) closeConfirmListeners() {
+	s.notifyMu.Lock()
+	defer s.notifyMu.Unlock()
+
+	if s.notifyClosed {
+		return
+	}
+	s.notifyClosed = true
+	for _, l := range s.confirmListeners {
+		close(l)
+	}
+	s.confirmListeners = nil
+}
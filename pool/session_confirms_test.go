@@ -0,0 +1,148 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionOutstandingConfirms(t *testing.T) {
+	t.Parallel()
+
+	var (
+		numMsgs  = 5
+		ctx, cc  = context.WithCancel(context.Background())
+		confirms = make(chan amqp091.Confirmation, numMsgs)
+	)
+	defer cc()
+
+	s := &Session{
+		confirmable:     true,
+		confirms:        confirms,
+		pendingConfirms: numMsgs,
+		ctx:             ctx,
+	}
+	assert.Equal(t, numMsgs, s.OutstandingConfirms())
+
+	// slow broker
+	go func() {
+		for tag := 1; tag <= numMsgs; tag++ {
+			time.Sleep(20 * time.Millisecond)
+			confirms <- amqp091.Confirmation{DeliveryTag: uint64(tag), Ack: true}
+		}
+	}()
+
+	previous := numMsgs
+	assert.Eventually(t, func() bool {
+		n := s.OutstandingConfirms()
+		require.LessOrEqual(t, n, previous, "outstanding confirms must only decrease")
+		previous = n
+		return n < numMsgs
+	}, time.Second, time.Millisecond)
+
+	require.NoError(t, s.WaitConfirms(ctx))
+	assert.Equal(t, 0, s.OutstandingConfirms())
+
+	// received confirms are not consumed by WaitConfirms
+	assert.Len(t, confirms, numMsgs)
+
+	// flushing keeps the count accurate
+	s.Flush()
+	assert.Equal(t, 0, s.pendingConfirms)
+	assert.Equal(t, 0, s.OutstandingConfirms())
+
+	// context expiry
+	s.pendingConfirms = 1
+	tctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, s.WaitConfirms(tctx), context.DeadlineExceeded)
+}
+
+func TestSessionForwardConfirms(t *testing.T) {
+	t.Parallel()
+
+	var (
+		numMsgs  = 5
+		ctx, cc  = context.WithCancel(context.Background())
+		raw      = make(chan amqp091.Confirmation)
+		confirms = make(chan amqp091.Confirmation, numMsgs)
+		done     = make(chan struct{})
+	)
+	defer cc()
+
+	s := &Session{
+		bufferCapacity: numMsgs,
+		ctx:            ctx,
+	}
+	listeners := []<-chan Confirmation{s.NotifyConfirm(), s.NotifyConfirm()}
+
+	forwarded := make(chan struct{})
+	go func() {
+		defer close(forwarded)
+		s.forwardConfirms(raw, confirms, done)
+	}()
+
+	for i := 1; i <= numMsgs; i++ {
+		raw <- amqp091.Confirmation{DeliveryTag: uint64(i), Ack: i != 3}
+	}
+	close(raw)
+	<-forwarded
+
+	// the session still receives every confirmation for AwaitConfirm
+	received := flush(confirms)
+	require.Len(t, received, numMsgs)
+
+	s.closeConfirmListeners()
+	for _, l := range listeners {
+		events := flush(l)
+		require.Len(t, events, numMsgs, "every confirmation must be notified exactly once")
+		for i, e := range events {
+			assert.Equal(t, uint64(i+1), e.DeliveryTag)
+			assert.Equal(t, i+1 != 3, e.Ack)
+			assert.False(t, e.ReceivedAt.IsZero())
+		}
+	}
+
+	// channels that are requested after the session was closed are closed
+	_, ok := <-s.NotifyConfirm()
+	assert.False(t, ok)
+}
+
+func TestSessionForwardConfirmsDone(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx, cc  = context.WithCancel(context.Background())
+		raw      = make(chan amqp091.Confirmation)
+		confirms = make(chan amqp091.Confirmation) // nobody awaits the confirmations
+		done     = make(chan struct{})
+	)
+	defer cc()
+
+	s := &Session{ctx: ctx}
+
+	forwarded := make(chan struct{})
+	go func() {
+		defer close(forwarded)
+		s.forwardConfirms(raw, confirms, done)
+	}()
+
+	raw <- amqp091.Confirmation{DeliveryTag: 1, Ack: true}
+	close(done)
+
+	// the amqp library must not be blocked by confirmations of a channel that is not used anymore
+	select {
+	case raw <- amqp091.Confirmation{DeliveryTag: 2, Ack: true}:
+	case <-time.After(time.Second):
+		require.FailNow(t, "forwarding blocked the amqp channel")
+	}
+	close(raw)
+
+	<-forwarded
+	_, ok := <-confirms
+	assert.False(t, ok)
+}
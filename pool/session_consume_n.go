@@ -0,0 +1,84 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+)
+
+// ConsumeN consumes messages from the queue and passes them to the handler until n messages were handled successfully,
+// e.g. for deterministic integration tests or bounded batch jobs.
+// Successfully handled messages are acked. Messages whose handler returned an error are nacked according to
+// the NackPolicy of the consume options and do not count towards n.
+// The consumer is canceled as soon as n messages were handled or ctx is done, in which case ctx.Err() is returned.
+// Messages that were prefetched but not handled anymore are requeued.
+// Messages are always acked manually, which is why ConsumeOptions.AutoAck is ignored.
+func (s *Session) ConsumeN(ctx context.Context, queue string, n int, handler HandlerFunc, option ...ConsumeOptions) error {
+	if n < 1 {
+		return fmt.Errorf("failed to consume messages from queue %s: invalid number of messages: %d", queue, n)
+	}
+	if handler == nil {
+		panic("handler must not be nil")
+	}
+
+	o := ConsumeOptions{}
+	if len(option) > 0 {
+		o = option[0]
+	}
+	o.AutoAck = false
+	tag, err := s.consumerTag(o)
+	if err != nil {
+		return fmt.Errorf("failed to consume messages from queue %s: %w", queue, err)
+	}
+	o.ConsumerTag = tag
+
+	// deliveries must still be forwarded while prefetched messages are requeued after ctx is done
+	consumeCtx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	deliveries, err := s.ConsumeWithContext(consumeCtx, queue, o)
+	if err != nil {
+		return err
+	}
+
+	handled := 0
+	for handled < n {
+		select {
+		case <-ctx.Done():
+			s.stopConsumeN(o.ConsumerTag, deliveries)
+			return fmt.Errorf("failed to consume %d messages from queue %s, consumed %d: %w", n, queue, handled, ctx.Err())
+		case msg, ok := <-deliveries:
+			if !ok {
+				return fmt.Errorf("failed to consume %d messages from queue %s, consumed %d: %w", n, queue, handled, ErrDeliveryClosed)
+			}
+
+			herr := callSafe(s.slog(), "handler", ErrHandlerPanic, func() error {
+				return handler(ctx, msg)
+			})
+			if herr != nil {
+				_ = s.Nack(msg.DeliveryTag, false, nackAction(o.NackPolicy, herr) == NackActionRequeue)
+				continue
+			}
+
+			// messages that cannot be acked, e.g. due to a channel recovery, are redelivered by the broker
+			if s.Ack(msg.DeliveryTag, false) == nil {
+				handled++
+			}
+		}
+	}
+
+	s.stopConsumeN(o.ConsumerTag, deliveries)
+	return nil
+}
+
+// stopConsumeN cancels the consumer and requeues all messages that were delivered before the cancellation.
+func (s *Session) stopConsumeN(consumerTag string, deliveries <-chan Delivery) {
+	err := s.Cancel(consumerTag, false)
+	if err != nil {
+		s.warn(err, "failed to cancel consumer")
+	}
+
+	for msg := range deliveries {
+		// ignore errors, unacked messages are requeued by the broker as soon as the channel is closed
+		_ = s.Nack(msg.DeliveryTag, false, true)
+	}
+}
@@ -0,0 +1,286 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	// delivery tags are scoped to a single channel and restart at 1 for every new channel.
+	// The upper bits of the delivery tags that are handed out by a session contain the generation of the channel
+	// on which the message was delivered, which allows to detect (n)acks of messages that were delivered
+	// on a channel that does not exist anymore.
+	deliveryTagGenerationShift = 40
+	deliveryTagMask            = 1<<deliveryTagGenerationShift - 1
+)
+
+// toSessionDeliveryTag converts a channel delivery tag into a session delivery tag.
+func toSessionDeliveryTag(generation, channelTag uint64) uint64 {
+	return generation<<deliveryTagGenerationShift | channelTag&deliveryTagMask
+}
+
+// fromSessionDeliveryTag splits a session delivery tag into the channel generation and the channel delivery tag.
+func fromSessionDeliveryTag(tag uint64) (generation, channelTag uint64) {
+	return tag >> deliveryTagGenerationShift, tag & deliveryTagMask
+}
+
+// maxConsumerTagLength is the maximum length of a consumer tag, which is an AMQP short string.
+const maxConsumerTagLength = 255
+
+// consumerTag returns the consumer tag of the passed options.
+// An empty tag defaults to the session name, prefixed with the consumer tag prefix, if any.
+func (s *Session) consumerTag(o ConsumeOptions) (string, error) {
+	tag := o.ConsumerTag
+	if tag == "" {
+		// use our own consumer naming
+		tag = withConsumerTagPrefix(o.ConsumerTagPrefix, s.Name())
+	}
+	if len(tag) > maxConsumerTagLength {
+		return "", fmt.Errorf("%w: %q exceeds %d bytes", ErrInvalidConsumerTag, tag, maxConsumerTagLength)
+	}
+	return tag, nil
+}
+
+func withConsumerTagPrefix(prefix, tag string) string {
+	if prefix == "" {
+		return tag
+	}
+	return prefix + "-" + tag
+}
+
+// sessionConsumer is a consumer whose subscription is restored whenever the channel of its session is recovered.
+// Its deliveries are forwarded to a channel that outlives the underlying amqp channels.
+type sessionConsumer struct {
+	queue string
+	opts  ConsumeOptions
+	ctx   context.Context
+
+	out chan Delivery
+	// delivery channels of restored subscriptions, only the latest one is kept.
+	// closed when the consumer is removed from its session.
+	sources chan consumerSource
+}
+
+type consumerSource struct {
+	deliveries <-chan amqp091.Delivery
+	generation uint64
+}
+
+// attach passes the deliveries of a new subscription to the forwarding goroutine.
+// not threadsafe, must be called while holding the session lock.
+func (c *sessionConsumer) attach(deliveries <-chan amqp091.Delivery, generation uint64) {
+	// replace a source that was not picked up yet, its channel is already closed
+	select {
+	case <-c.sources:
+	default:
+	}
+	c.sources <- consumerSource{deliveries: deliveries, generation: generation}
+}
+
+// consume starts a subscription on the passed channel.
+// A consumer with its own prefetch count is started with a per consumer limit, which is reset to the
+// per consumer limit of the session afterwards, so that other consumers of the channel are not affected.
+// not threadsafe
+func (s *Session) consume(ctx context.Context, channel *amqp091.Channel, queue string, o ConsumeOptions) (<-chan amqp091.Delivery, error) {
+	if o.Prefetch > 0 {
+		err := channel.Qos(o.Prefetch, 0, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply prefetch count %d of consumer %s: %w", o.Prefetch, o.ConsumerTag, err)
+		}
+	}
+
+	deliveries, err := channel.ConsumeWithContext(
+		ctx,
+		queue,
+		o.ConsumerTag,
+		o.AutoAck,
+		o.Exclusive,
+		o.NoLocal,
+		o.NoWait,
+		o.Args,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.Prefetch > 0 {
+		// no limit in case the session has no per consumer limit
+		q := s.qos[false]
+		err = channel.Qos(q.prefetchCount, q.prefetchSize, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reset prefetch count after starting consumer %s: %w", o.ConsumerTag, err)
+		}
+	}
+	return deliveries, nil
+}
+
+// addConsumer registers a consumer and starts forwarding its deliveries.
+// not threadsafe
+func (s *Session) addConsumer(ctx context.Context, queue string, opts ConsumeOptions, deliveries <-chan amqp091.Delivery) <-chan Delivery {
+	c := &sessionConsumer{
+		queue:   queue,
+		opts:    opts,
+		ctx:     ctx,
+		out:     make(chan Delivery),
+		sources: make(chan consumerSource, 1),
+	}
+	s.consumers[opts.ConsumerTag] = c
+
+	go s.forward(c, consumerSource{deliveries: deliveries, generation: s.generation()})
+	return c.out
+}
+
+// removeConsumer unregisters the consumer, which prevents it from being restored.
+// not threadsafe
+func (s *Session) removeConsumer(c *sessionConsumer) bool {
+	if s.consumers[c.opts.ConsumerTag] != c {
+		return false
+	}
+	delete(s.consumers, c.opts.ConsumerTag)
+	close(c.sources)
+	return true
+}
+
+// dropConsumer unregisters the consumer and cancels a subscription that might have been restored in the meantime.
+func (s *Session) dropConsumer(c *sessionConsumer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.removeConsumer(c) {
+		return
+	}
+	if s.channel != nil && !s.channel.IsClosed() {
+		// ignore error, the consumer might not exist anymore on the broker side
+		_ = s.channel.Cancel(c.opts.ConsumerTag, false)
+	}
+}
+
+// restoreConsumers subscribes all registered consumers on a new channel.
+// Consumers that cannot be restored due to a channel exception are removed,
+// e.g. because their queue was deleted, otherwise the session could never be recovered.
+// Only the consumers that were actually resumed on the new channel are reported as restored.
+// not threadsafe
+func (s *Session) restoreConsumers(channel *amqp091.Channel, generation uint64) error {
+	restored := make([]string, 0, len(s.consumers))
+	defer func() {
+		if len(restored) == 0 {
+			return
+		}
+		sort.Strings(restored)
+		s.info(fmt.Sprintf("restored consumers: %s", strings.Join(restored, ", ")))
+	}()
+
+	for tag, c := range s.consumers {
+		if c.ctx.Err() != nil {
+			s.removeConsumer(c)
+			continue
+		}
+
+		opts := c.opts
+		opts.ConsumerTag = tag
+		deliveries, err := s.consume(c.ctx, channel, c.queue, opts)
+		if err != nil {
+			s.warnf(err, "failed to restore consumer %s of queue %s", tag, c.queue)
+			ae := &amqp091.Error{}
+			if errors.As(err, &ae) && ae.Server {
+				// channel exceptions, e.g. the queue was deleted.
+				// other errors, e.g. due to a connection loss, are retried with the consumer.
+				s.removeConsumer(c)
+			}
+			return err
+		}
+		c.attach(deliveries, generation)
+		restored = append(restored, tag)
+	}
+	return nil
+}
+
+// forward passes the deliveries of all (restored) subscriptions of the consumer to its output channel.
+// The output channel is closed as soon as the consumer is canceled, its context is done,
+// the session is closed or the session cannot be recovered anymore.
+func (s *Session) forward(c *sessionConsumer, source consumerSource) {
+	defer close(c.out)
+
+	for {
+		if !s.forwardDeliveries(c, source) {
+			s.dropConsumer(c)
+			return
+		}
+
+		next, ok := s.nextSource(c)
+		if !ok {
+			return
+		}
+		source = next
+	}
+}
+
+func (s *Session) forwardDeliveries(c *sessionConsumer, source consumerSource) bool {
+	for d := range source.deliveries {
+		d.DeliveryTag = toSessionDeliveryTag(source.generation, d.DeliveryTag)
+		d.Acknowledger = sessionAcknowledger{s}
+
+		select {
+		case c.out <- d:
+		case <-c.ctx.Done():
+			return false
+		case <-s.catchShutdown():
+			return false
+		}
+	}
+	return true
+}
+
+// nextSource waits for the subscription to be restored.
+// In case nobody else is recovering the session, the session is recovered here.
+func (s *Session) nextSource(c *sessionConsumer) (consumerSource, bool) {
+	select {
+	case source, ok := <-c.sources:
+		return source, ok
+	default:
+	}
+
+	if c.ctx.Err() != nil || s.shutdownErr() != nil {
+		s.dropConsumer(c)
+		return consumerSource{}, false
+	}
+
+	err := s.Recover(c.ctx)
+	if err != nil {
+		s.warnf(err, "consumer %s closed: failed to recover session", c.opts.ConsumerTag)
+		s.dropConsumer(c)
+		return consumerSource{}, false
+	}
+
+	select {
+	case source, ok := <-c.sources:
+		return source, ok
+	default:
+		// the channel is healthy, but the consumer was canceled, e.g. by the broker after its queue was deleted
+		s.dropConsumer(c)
+		return consumerSource{}, false
+	}
+}
+
+// sessionAcknowledger (n)acks deliveries via their session, which translates
+// session delivery tags into delivery tags of the current channel.
+type sessionAcknowledger struct {
+	s *Session
+}
+
+func (a sessionAcknowledger) Ack(tag uint64, multiple bool) error {
+	return a.s.Ack(tag, multiple)
+}
+
+func (a sessionAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	return a.s.Nack(tag, multiple, requeue)
+}
+
+func (a sessionAcknowledger) Reject(tag uint64, requeue bool) error {
+	return a.s.Reject(tag, requeue)
+}
@@ -0,0 +1,172 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jxsl13/amqpx/logging"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionDeliveryTag(t *testing.T) {
+	t.Parallel()
+
+	// delivery tags of the first channel are not modified
+	assert.Equal(t, uint64(42), toSessionDeliveryTag(0, 42))
+
+	tag := toSessionDeliveryTag(3, 42)
+	assert.NotEqual(t, uint64(42), tag)
+
+	generation, channelTag := fromSessionDeliveryTag(tag)
+	assert.Equal(t, uint64(3), generation)
+	assert.Equal(t, uint64(42), channelTag)
+	assert.Equal(t, uint64(3), DeliveryEpoch(tag))
+
+	s := &Session{channels: 4} // generation 3
+	assert.Equal(t, uint64(3), s.Epoch())
+	channelTag, err := s.channelDeliveryTag(tag)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(42), channelTag)
+
+	// messages that were delivered on a previous channel cannot be (n)acked anymore
+	_, err = s.channelDeliveryTag(toSessionDeliveryTag(2, 42))
+	assert.ErrorIs(t, err, ErrStaleDeliveryTag)
+	_, err = s.channelDeliveryTag(42)
+	assert.ErrorIs(t, err, ErrStaleDeliveryTag)
+
+	// all outstanding messages of the current channel
+	channelTag, err = s.channelDeliveryTag(0)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), channelTag)
+}
+
+func TestSessionConsumerTag(t *testing.T) {
+	t.Parallel()
+
+	s := &Session{name: "pool-cached-connection-0-cached-session-1"}
+
+	tag, err := s.consumerTag(ConsumeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "pool-cached-connection-0-cached-session-1", tag)
+
+	tag, err = s.consumerTag(ConsumeOptions{ConsumerTagPrefix: "worker-pod-abc"})
+	require.NoError(t, err)
+	assert.Equal(t, "worker-pod-abc-pool-cached-connection-0-cached-session-1", tag)
+
+	// explicit tags are not prefixed
+	tag, err = s.consumerTag(ConsumeOptions{ConsumerTag: "explicit", ConsumerTagPrefix: "worker-pod-abc"})
+	require.NoError(t, err)
+	assert.Equal(t, "explicit", tag)
+
+	// consumer tags are AMQP short strings
+	_, err = s.consumerTag(ConsumeOptions{ConsumerTagPrefix: strings.Repeat("p", maxConsumerTagLength)})
+	assert.ErrorIs(t, err, ErrInvalidConsumerTag)
+	assert.False(t, recoverable(err))
+	_, err = s.consumerTag(ConsumeOptions{ConsumerTag: strings.Repeat("t", maxConsumerTagLength+1)})
+	assert.ErrorIs(t, err, ErrInvalidConsumerTag)
+}
+
+func TestSessionRestoreConsumers(t *testing.T) {
+	t.Parallel()
+
+	addr, _ := newFakeBroker(t, "PLAIN")
+	conn, err := NewConnection(context.TODO(), fmt.Sprintf("amqp://admin:password@%s/", addr), "restore-consumers",
+		ConnectionWithLogger(logging.NewNoOpLogger()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	log := newInfoLogger()
+	s, err := NewSession(conn, "restore-consumers", SessionWithLogger(log))
+	require.NoError(t, err)
+	defer s.Close()
+
+	_, err = s.Consume("orders", ConsumeOptions{ConsumerTag: "orders-consumer"})
+	require.NoError(t, err)
+	_, err = s.Consume("payments", ConsumeOptions{ConsumerTag: "payments-consumer", Prefetch: 2})
+	require.NoError(t, err)
+
+	s.mu.Lock()
+	err = s.restoreConsumers(s.channel, s.generation())
+	s.mu.Unlock()
+	require.NoError(t, err)
+	infos := log.Infos()
+	assert.Equal(t, "restored consumers: orders-consumer, payments-consumer", infos[len(infos)-1])
+	assert.Empty(t, log.Warns())
+
+	// the queue of a consumer was deleted in the meantime
+	deleted := make(chan amqp091.Delivery)
+	close(deleted)
+	s.mu.Lock()
+	missing := s.addConsumer(s.ctx, fakeBrokerMissingQueue, ConsumeOptions{ConsumerTag: "missing-consumer"}, deleted)
+	err = s.restoreConsumers(s.channel, s.generation())
+	s.mu.Unlock()
+
+	ae := &amqp091.Error{}
+	require.True(t, errors.As(err, &ae))
+	assert.Equal(t, amqp091.NotFound, ae.Code)
+	assert.Equal(t, []string{"failed to restore consumer missing-consumer of queue missing"}, log.Warns())
+	for _, info := range log.Infos()[len(infos):] {
+		// consumers that were not resumed before the channel was closed are not reported either
+		assert.NotContains(t, info, "missing-consumer")
+	}
+
+	// the consumer is removed, otherwise the session could never be recovered
+	select {
+	case _, ok := <-missing:
+		assert.False(t, ok)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "the consumer of the missing queue was not removed")
+	}
+}
+
+func TestConsumerActivation(t *testing.T) {
+	t.Parallel()
+
+	var a consumerActivation
+	assert.True(t, a.activated(Delivery{DeliveryTag: toSessionDeliveryTag(0, 1)}))
+	assert.False(t, a.activated(Delivery{DeliveryTag: toSessionDeliveryTag(0, 2)}))
+
+	// the consumer was restored on a new channel after a recovery of its session
+	assert.True(t, a.activated(Delivery{DeliveryTag: toSessionDeliveryTag(1, 1)}))
+	assert.False(t, a.activated(Delivery{DeliveryTag: toSessionDeliveryTag(1, 2)}))
+}
+
+func TestSessionCloseDuringRecoverCallback(t *testing.T) {
+	t.Parallel()
+
+	addr, _ := newFakeBroker(t, "PLAIN")
+	conn, err := NewConnection(context.TODO(), fmt.Sprintf("amqp://admin:password@%s/", addr), "close-during-recovery",
+		ConnectionWithLogger(logging.NewNoOpLogger()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var s *Session
+	s, err = NewSession(conn, "close-during-recovery",
+		SessionWithLogger(logging.NewNoOpLogger()),
+		SessionWithRecoverCallback(func(operation, connName, sessionName string, retry int, err error) {
+			// the session lock is released while the callback is called
+			assert.NoError(t, s.Close())
+		}),
+	)
+	require.NoError(t, err)
+
+	_, err = s.Consume("orders", ConsumeOptions{ConsumerTag: "orders-consumer"})
+	require.NoError(t, err)
+
+	s.Flag(ErrConnectionFailed)
+	err = s.Recover(context.TODO())
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// no channel was opened on the closed session
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	assert.Nil(t, s.channel)
+}
@@ -10,7 +10,9 @@ type sessionOption struct {
 	Logger         logging.Logger
 	Cached         bool
 	Confirmable    bool
+	ConfirmNoWait  bool
 	BufferCapacity int
+	MaxMessageSize int
 	Ctx            context.Context
 	AutoCloseConn  bool
 
@@ -32,6 +34,11 @@ type sessionOption struct {
 	ExchangeUnbindRetryCallback         SessionRetryCallback
 	QoSRetryCallback                    SessionRetryCallback
 	FlowRetryCallback                   SessionRetryCallback
+
+	// notified about every received ack or nack, shared by all sessions of a pool
+	confirmObserver func(ack bool)
+	// limits the rate of publishings, shared by all sessions of a pool
+	publishLimiter *rateLimiter
 }
 
 type SessionOption func(*sessionOption)
@@ -70,7 +77,18 @@ func SessionWithConfirms(requiresPublishConfirms bool) SessionOption {
 	}
 }
 
-// SessionWithBufferSize allows to customize the size of th einternal channel buffers.
+// SessionWithConfirmNoWait enables confirm mode without awaiting the acknowledgement of the broker,
+// which saves a round trip whenever the channel of a confirmable session is opened, e.g. upon recovery.
+// In case the broker does not accept confirm mode, it closes the channel asynchronously, which is why the error
+// surfaces later on, e.g. as a failed Publish or AwaitConfirm, after which the session is recovered as usual.
+// Only has an effect on confirmable sessions, see SessionWithConfirms.
+func SessionWithConfirmNoWait(noWait bool) SessionOption {
+	return func(so *sessionOption) {
+		so.ConfirmNoWait = noWait
+	}
+}
+
+// SessionWithBufferCapacity allows to customize the size of the internal channel buffers.
 // all buffers/channels are initialized with this size. (e.g. error or confirm channels)
 func SessionWithBufferCapacity(capacity int) SessionOption {
 	return func(so *sessionOption) {
@@ -78,6 +96,15 @@ func SessionWithBufferCapacity(capacity int) SessionOption {
 	}
 }
 
+// SessionWithMaxMessageSize limits the body size of published messages in bytes.
+// Publishing a bigger message returns ErrMessageTooLarge without sending it to the broker.
+// A size of 0 or less disables the limit, which is the default.
+func SessionWithMaxMessageSize(bytes int) SessionOption {
+	return func(so *sessionOption) {
+		so.MaxMessageSize = bytes
+	}
+}
+
 // SessionWithAutoCloseConnection is important for transient sessions
 // which, as they allow to create sessions that close their internal connections
 // automatically upon closing themselves.
@@ -113,6 +140,21 @@ func SessionWithRetryCallback(callback SessionRetryCallback) SessionOption {
 	}
 }
 
+// sessionWithConfirmObserver notifies the observer about every ack or nack that is received by the session.
+func sessionWithConfirmObserver(observer func(ack bool)) SessionOption {
+	return func(so *sessionOption) {
+		so.confirmObserver = observer
+	}
+}
+
+// sessionWithPublishRateLimiter limits the rate of publishings of the session.
+// The limiter must be shared by all sessions that are limited together.
+func sessionWithPublishRateLimiter(limiter *rateLimiter) SessionOption {
+	return func(so *sessionOption) {
+		so.publishLimiter = limiter
+	}
+}
+
 // SessionWithRecoverCallback allows to set a custom recover callback.
 // The callback should not interact with anything that may lead to any kind of errors.
 // It should preferrably delegate its work to a separate goroutine.
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/jxsl13/amqpx/logging"
 )
@@ -17,12 +18,41 @@ type SessionPool struct {
 
 	capacity       int
 	bufferCapacity int
+	maxMessageSize int
+	publishLimiter *rateLimiter // shared by all sessions, nil is unlimited
 	confirmable    bool
+	confirmNoWait  bool
 	sessions       chan *Session
+	// all cached sessions, whether idle or in use, including the sessions of pre-warmed consumers,
+	// immutable after initialization
+	cached []*Session
+
+	// consumers that were registered on dedicated cached sessions upon creation, immutable afterwards
+	preWarmed map[string]preWarmedConsumer
 
 	ctx    context.Context
 	cancel context.CancelFunc
 
+	// prevents closing the session pool twice
+	closeMu sync.Mutex
+	closed  bool
+
+	metrics      MetricsCollector
+	acquisitions acquisitionCounter
+	confirms     confirmCounter
+	// GetSession calls that block longer than this are logged, 0 is disabled
+	slowAcquisition time.Duration
+
+	// acquired sessions that were not returned, yet
+	leasesMu sync.Mutex
+	leases   map[*Session]sessionLease
+	// sessions that are held longer than this are logged, 0 is disabled
+	leakThreshold time.Duration
+	// whether the stack of the acquisition is logged with leaked sessions
+	leakStacks bool
+	// transient sessions are closed after this lifetime, 0 is unlimited
+	transientTTL time.Duration
+
 	log logging.Logger
 
 	RecoverCallback                     SessionRetryCallback
@@ -55,8 +85,11 @@ func NewSessionPool(pool *ConnectionPool, numSessions int, options ...SessionPoo
 		AutoClosePool:  false, // caller owns the connection pool by default
 		Capacity:       numSessions,
 		Confirmable:    false,
-		BufferCapacity: 10,       // fault tolerance over throughput
-		Logger:         pool.log, // derive logger from connection pool
+		BufferCapacity: 10,            // fault tolerance over throughput
+		Logger:         pool.logger(), // derive logger from connection pool
+
+		MetricsCollector: pool.metrics,         // derive metrics collector from connection pool
+		SlowAcquisition:  pool.slowAcquisition, // derive threshold from connection pool
 	}
 
 	for _, o := range options {
@@ -78,7 +111,10 @@ func newSessionPoolFromOption(pool *ConnectionPool, ctx context.Context, option
 		autoCloseConnPool: option.AutoClosePool,
 
 		bufferCapacity: option.BufferCapacity,
+		maxMessageSize: option.MaxMessageSize,
+		publishLimiter: newRateLimiter(option.PublishRate, option.PublishBurst, option.NonBlockingPublishRate),
 		confirmable:    option.Confirmable,
+		confirmNoWait:  option.ConfirmNoWait,
 		capacity:       option.Capacity,
 		sessions:       make(chan *Session, option.Capacity),
 
@@ -87,6 +123,14 @@ func newSessionPoolFromOption(pool *ConnectionPool, ctx context.Context, option
 
 		log: option.Logger,
 
+		metrics:         option.MetricsCollector,
+		slowAcquisition: option.SlowAcquisition,
+
+		leases:        make(map[*Session]sessionLease),
+		leakThreshold: option.LeakThreshold,
+		leakStacks:    option.LeakStacks,
+		transientTTL:  option.TransientTTL,
+
 		RecoverCallback:                     option.RecoverCallback,
 		PublishRetryCallback:                option.PublishRetryCallback,
 		GetRetryCallback:                    option.GetRetryCallback,
@@ -116,28 +160,41 @@ func newSessionPoolFromOption(pool *ConnectionPool, ctx context.Context, option
 		}
 	}()
 
-	err = sessionPool.initCachedSessions()
+	// number of cached sessions (channels) per connection
+	channels := make(map[*Connection]int, pool.Capacity())
+
+	err = sessionPool.initCachedSessions(channels)
 	if err != nil {
 		return nil, err
 	}
 
+	err = sessionPool.initPreWarmedConsumers(option.PreWarmedConsumers, channels)
+	if err != nil {
+		sessionPool.close()
+		return nil, err
+	}
+
 	return sessionPool, nil
 }
 
-func (sp *SessionPool) initCachedSessions() error {
+func (sp *SessionPool) initCachedSessions(channels map[*Connection]int) error {
 	for i := 0; i < sp.capacity; i++ {
-		session, err := sp.initCachedSession(i)
+		session, err := sp.initCachedSession(i, channels)
 		if err != nil {
 			return err
 		}
+		sp.cached = append(sp.cached, session)
 		sp.sessions <- session
 	}
 	return nil
 }
 
 // initCachedSession allows you create a pooled Session.
-func (sp *SessionPool) initCachedSession(id int) (*Session, error) {
+// Sessions spill over to the next connection in case a connection reached the channel max
+// that was negotiated with the broker.
+func (sp *SessionPool) initCachedSession(id int, channels map[*Connection]int) (*Session, error) {
 
+	saturated := 0
 	// retry until we get a channel
 	// or until shutdown
 	for {
@@ -147,6 +204,15 @@ func (sp *SessionPool) initCachedSession(id int) (*Session, error) {
 			return nil, err
 		}
 
+		if limit := conn.NegotiatedChannelMax(); limit > 0 && channels[conn] >= limit {
+			sp.pool.ReturnConnection(conn, nil)
+			saturated++
+			if saturated >= sp.pool.Capacity() {
+				return nil, fmt.Errorf("%w: %d sessions exceed the negotiated channel max of all connections", errInvalidPoolSize, sp.capacity)
+			}
+			continue
+		}
+
 		session, err := sp.deriveSession(sp.ctx, conn, id)
 		if err != nil {
 			sp.pool.ReturnConnection(conn, err)
@@ -154,6 +220,7 @@ func (sp *SessionPool) initCachedSession(id int) (*Session, error) {
 		}
 
 		sp.pool.ReturnConnection(conn, nil)
+		channels[conn]++
 		return session, nil
 	}
 }
@@ -169,8 +236,17 @@ func (sp *SessionPool) Capacity() int {
 }
 
 // GetSession gets a pooled session.
-// blocks until a session is acquired from the pool.
+// blocks until a session is acquired from the pool, ctx is done or the session pool is closed.
+// In case ctx is canceled or its deadline elapses, ctx.Err() is returned, which allows to bound the wait,
+// see TryGetSession in order not to wait at all.
 func (sp *SessionPool) GetSession(ctx context.Context) (s *Session, err error) {
+	start := time.Now()
+	done := sp.watchAcquisition()
+	defer func() {
+		done()
+		sp.observeAcquisition(AcquisitionPathCached, start, err)
+	}()
+
 	select {
 	case <-sp.catchShutdown():
 		return nil, sp.shutdownErr()
@@ -192,15 +268,82 @@ func (sp *SessionPool) GetSession(ctx context.Context) (s *Session, err error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to get session: %w", err)
 		}
+		session.borrow()
+		sp.lease(session)
 		return session, nil
 	}
 }
 
+// TryGetSession returns an idle cached session without blocking.
+// In case all sessions are in use, all idle sessions need to be recovered first or the session pool is closed,
+// nil and false are returned. Sessions that need to be recovered are left in the pool and recovered by GetSession.
+// The session must be returned with ReturnSession, see GetSession.
+// Like the acquisitions of GetSession, failed attempts are counted as acquisition errors, see Stats.
+func (sp *SessionPool) TryGetSession() (*Session, bool) {
+	start := time.Now()
+	select {
+	case <-sp.catchShutdown():
+		sp.observeAcquisition(AcquisitionPathCached, start, sp.shutdownErr())
+		return nil, false
+	default:
+	}
+
+	for i := 0; i < sp.capacity; i++ {
+		select {
+		case session := <-sp.sessions:
+			if !session.healthy() {
+				// recovering would block, the pool has room for the session as it was just taken out of it
+				sp.sessions <- session
+				continue
+			}
+			session.borrow()
+			sp.lease(session)
+			sp.observeAcquisition(AcquisitionPathCached, start, nil)
+			return session, true
+		default:
+			sp.observeAcquisition(AcquisitionPathCached, start, ErrPoolExhausted)
+			return nil, false
+		}
+	}
+	sp.observeAcquisition(AcquisitionPathCached, start, ErrPoolExhausted)
+	return nil, false
+}
+
 // GetTransientSession returns a transient session.
 // This method may return an error when the context ha sbeen closed before a session could be obtained.
 // A transient session creates a transient connection under the hood.
 func (sp *SessionPool) GetTransientSession(ctx context.Context) (s *Session, err error) {
-	conn, err := sp.pool.GetTransientConnection(ctx)
+	return sp.getTransientSession(ctx, "")
+}
+
+// GetTransientSessionForVHost returns a transient session whose transient connection is connected to the passed vhost
+// instead of the vhost of the connection pool, see ConnectionPool.GetTransientConnectionForVHost.
+func (sp *SessionPool) GetTransientSessionForVHost(ctx context.Context, vhost string) (s *Session, err error) {
+	if vhost == "" {
+		return nil, fmt.Errorf("%w: empty vhost", ErrInvalidConnectURL)
+	}
+	return sp.getTransientSession(ctx, vhost)
+}
+
+func (sp *SessionPool) getTransientSession(ctx context.Context, vhost string) (s *Session, err error) {
+	start := time.Now()
+	defer func() {
+		sp.observeAcquisition(AcquisitionPathTransient, start, err)
+	}()
+
+	parent := ctx
+	cancel := context.CancelFunc(func() {})
+	if sp.transientTTL > 0 {
+		// the session and its connection are derived from ctx, which closes both as soon as the ttl elapsed
+		ctx, cancel = context.WithTimeout(ctx, sp.transientTTL)
+	}
+	defer func() {
+		if err != nil {
+			cancel()
+		}
+	}()
+
+	conn, err := sp.pool.getTransientConnection(ctx, vhost)
 	if err != nil {
 		return nil, err
 	}
@@ -215,9 +358,73 @@ func (sp *SessionPool) GetTransientSession(ctx context.Context) (s *Session, err
 	if err != nil {
 		return nil, err
 	}
+
+	// closes the channel and the transient connection as soon as ctx is canceled
+	sp.lease(s)
+	go func() {
+		defer cancel()
+		closeOnCancel(ctx, s.ctx, func() error {
+			if parent.Err() == nil && ctx.Err() != nil {
+				sp.warn(ctx.Err(), fmt.Sprintf("closing transient session %s that was not closed within its ttl of %s", s.Name(), sp.transientTTL))
+			}
+			sp.unlease(s)
+			return s.Close()
+		})
+	}()
 	return s, nil
 }
 
+func (sp *SessionPool) observeAcquisition(path AcquisitionPath, start time.Time, err error) {
+	sp.acquisitions.observe(path, err)
+	if sp.metrics != nil {
+		sp.metrics.SessionAcquired(sp.pool.name, path, time.Since(start), err)
+	}
+}
+
+// watchAcquisition logs a warning in case a session could not be acquired within the slow acquisition threshold.
+// The returned function must be called as soon as the acquisition finished.
+func (sp *SessionPool) watchAcquisition() (done func()) {
+	return watchdog(sp.slowAcquisition, func(elapsed time.Duration) {
+		sp.log.WithFields(map[string]any{
+			"sessionPool": sp.pool.name,
+			"waited":      elapsed.String(),
+		}).Warn(fmt.Sprintf("waited %s for a session", elapsed.Round(time.Millisecond)))
+	})
+}
+
+// SessionPoolStats is a snapshot of the session pool statistics.
+type SessionPoolStats struct {
+	// Capacity is the number of cached sessions
+	Capacity int
+	// Size is the number of cached sessions that are currently not in use
+	Size int
+	// Acquisitions counts cached and transient session acquisitions separately
+	Acquisitions AcquisitionStats
+	// Confirms counts the acks and nacks that were received by cached and transient sessions
+	Confirms ConfirmStats
+	// Outstanding counts the acquired cached and transient sessions that were not returned, yet, by connection name
+	Outstanding map[string]int
+}
+
+// Stats returns a snapshot of the session pool statistics.
+func (sp *SessionPool) Stats() SessionPoolStats {
+	return SessionPoolStats{
+		Capacity:     sp.Capacity(),
+		Size:         sp.Size(),
+		Acquisitions: sp.acquisitions.stats(),
+		Confirms:     sp.confirms.stats(),
+		Outstanding:  sp.outstanding(),
+	}
+}
+
+// observeConfirm counts the confirmations of all sessions of the pool.
+func (sp *SessionPool) observeConfirm(ack bool) {
+	sp.confirms.observe(ack)
+	if collector, ok := sp.metrics.(ConfirmMetricsCollector); ok {
+		collector.PublishConfirmed(sp.pool.name, ack)
+	}
+}
+
 func (sp *SessionPool) deriveSession(ctx context.Context, conn *Connection, id int) (*Session, error) {
 
 	cached := conn.IsCached()
@@ -231,10 +438,15 @@ func (sp *SessionPool) deriveSession(ctx context.Context, conn *Connection, id i
 
 	return NewSession(conn, name,
 		SessionWithContext(ctx),
+		SessionWithLogger(sp.log),
 		SessionWithBufferCapacity(sp.bufferCapacity),
+		SessionWithMaxMessageSize(sp.maxMessageSize),
 		SessionWithCached(cached),
 		SessionWithConfirms(sp.confirmable),
+		SessionWithConfirmNoWait(sp.confirmNoWait),
 		SessionWithAutoCloseConnection(!cached), // only close transient connections
+		sessionWithConfirmObserver(sp.observeConfirm),
+		sessionWithPublishRateLimiter(sp.publishLimiter),
 		// reporting/alerting/metrics/etc. callbacks
 		SessionWithRecoverCallback(sp.RecoverCallback),
 		SessionWithPublishRetryCallback(sp.PublishRetryCallback),
@@ -257,9 +469,34 @@ func (sp *SessionPool) deriveSession(ctx context.Context, conn *Connection, id i
 	)
 }
 
+// Use gets a session from the pool, passes it to f and returns it to the pool afterwards.
+// The error returned by f is used to flag the session, see ReturnSession.
+// In case f panics, the session is returned as flagged before the panic is propagated,
+// as the state of the session's channel is unknown at that point.
+func (sp *SessionPool) Use(ctx context.Context, f func(*Session) error) (err error) {
+	session, err := sp.GetSession(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			sp.ReturnSession(session, fmt.Errorf("panic while using session: %v", r))
+			panic(r)
+		}
+		sp.ReturnSession(session, err)
+	}()
+
+	return f(session)
+}
+
 // ReturnSession returns a Session to the pool.
 // If Session is not a cached channel, it is simply closed here.
+// A returned session must not be used anymore, its operations fail with ErrSessionReturned until it is acquired again.
+// As the pool hands out the same session to the next caller, this cannot detect a usage after it was acquired again.
+// Returning a session twice is ignored.
+// Cached sessions of other pools are closed.
 func (sp *SessionPool) ReturnSession(session *Session, err error) {
+	sp.unlease(session)
 
 	// don't put non-managed sessions back into the channel
 	if !session.IsCached() {
@@ -267,6 +504,22 @@ func (sp *SessionPool) ReturnSession(session *Session, err error) {
 		return
 	}
 
+	if !sp.isCachedSession(session) {
+		sp.warn(fmt.Errorf("%w: session %s", ErrForeignSession, session.Name()), "closing session that does not belong to the pool")
+		_ = session.Close()
+		return
+	}
+
+	if sp.isPreWarmed(session) {
+		sp.error(ErrSessionReturned, "ignoring return of pre-warmed session ", session.Name())
+		return
+	}
+
+	if !session.release() {
+		sp.error(ErrSessionReturned, "ignoring repeated return of session ", session.Name())
+		return
+	}
+
 	session.Flag(err)
 
 	// flush confirms channel
@@ -277,8 +530,18 @@ func (sp *SessionPool) ReturnSession(session *Session, err error) {
 	select {
 	case sp.sessions <- session:
 	default:
-		panic("session buffer full: not supposed to happen")
+		sp.warn(fmt.Errorf("%w: session %s", ErrSessionReturned, session.Name()), "session was returned more than once")
+	}
+}
+
+// isCachedSession returns true in case the session is one of the cached sessions of this pool.
+func (sp *SessionPool) isCachedSession(session *Session) bool {
+	for _, s := range sp.cached {
+		if s == session {
+			return true
+		}
 	}
+	return false
 }
 
 func (sp *SessionPool) catchShutdown() <-chan struct{} {
@@ -290,7 +553,29 @@ func (sp *SessionPool) shutdownErr() error {
 }
 
 // Closes the session pool with all of its sessions
+// Closing an already closed session pool is a no-op.
 func (sp *SessionPool) Close() {
+	if !sp.close() {
+		return
+	}
+
+	if sp.autoCloseConnPool {
+		sp.pool.Close()
+	}
+}
+
+// close closes all sessions of the session pool without closing the connection pool.
+// returns false in case the session pool was already closed.
+func (sp *SessionPool) close() bool {
+	sp.closeMu.Lock()
+	if sp.closed {
+		sp.closeMu.Unlock()
+		return false
+	}
+	sp.closed = true
+	sp.closeMu.Unlock()
+
+	sp.pool.unregisterDependent(sp)
 
 	sp.info("closing session pool...")
 	defer sp.info("closed")
@@ -301,9 +586,9 @@ func (sp *SessionPool) Close() {
 
 	wg := &sync.WaitGroup{}
 
-	// close all sessions:
-	for i := 0; i < sp.capacity; i++ {
-		session := <-sp.sessions
+	// close all cached sessions, whether they are idle or in use, as waiting for sessions that are
+	// never returned would block forever. Returning a closed session is safe.
+	for _, session := range sp.cached {
 		wg.Add(1)
 		go func(s *Session) {
 			defer wg.Done()
@@ -311,16 +596,17 @@ func (sp *SessionPool) Close() {
 		}(session)
 	}
 	wg.Wait()
-
-	if sp.autoCloseConnPool {
-		sp.pool.Close()
-	}
+	return true
 }
 
 func (sp *SessionPool) info(a ...any) {
 	sp.log.WithField("sessionPool", sp.pool.name).Info(a...)
 }
 
+func (sp *SessionPool) warn(err error, a ...any) {
+	sp.log.WithField("sessionPool", sp.pool.name).WithField("error", err.Error()).Warn(a...)
+}
+
 func (sp *SessionPool) error(err error, a ...any) {
 	sp.log.WithField("sessionPool", sp.pool.name).WithField("error", err.Error()).Error(a...)
 }
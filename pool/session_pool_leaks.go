@@ -0,0 +1,64 @@
+package pool
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// sessionLease is an acquired session that was not returned to the pool, yet.
+type sessionLease struct {
+	conn string
+	stop func() // stops the leak watchdog
+}
+
+// lease tracks an acquired session until it is returned to the pool and starts its leak watchdog.
+func (sp *SessionPool) lease(s *Session) {
+	var stack []byte
+	if sp.leakStacks {
+		stack = debug.Stack()
+	}
+
+	conn := s.conn.Name()
+	stop := watchdog(sp.leakThreshold, func(elapsed time.Duration) {
+		fields := map[string]any{
+			"sessionPool": sp.pool.name,
+			"connection":  conn,
+			"session":     s.Name(),
+			"held":        elapsed.String(),
+		}
+		if stack != nil {
+			fields["stack"] = string(stack)
+		}
+		sp.log.WithFields(fields).Warn(fmt.Sprintf("session was not returned to the pool within %s, it may have leaked", elapsed.Round(time.Millisecond)))
+	})
+
+	sp.leasesMu.Lock()
+	defer sp.leasesMu.Unlock()
+	sp.leases[s] = sessionLease{conn: conn, stop: stop}
+}
+
+// unlease stops tracking a session that was returned to the pool or closed.
+func (sp *SessionPool) unlease(s *Session) {
+	sp.leasesMu.Lock()
+	defer sp.leasesMu.Unlock()
+
+	l, ok := sp.leases[s]
+	if !ok {
+		return
+	}
+	l.stop()
+	delete(sp.leases, s)
+}
+
+// outstanding returns the number of acquired sessions that were not returned, yet, by connection name.
+func (sp *SessionPool) outstanding() map[string]int {
+	sp.leasesMu.Lock()
+	defer sp.leasesMu.Unlock()
+
+	outstanding := make(map[string]int, len(sp.leases))
+	for _, l := range sp.leases {
+		outstanding[l.conn]++
+	}
+	return outstanding
+}
@@ -0,0 +1,42 @@
+package pool
+
+import (
+	"testing"
+
+	"github.com/jxsl13/amqpx/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionPoolOutstanding(t *testing.T) {
+	t.Parallel()
+
+	sp := &SessionPool{
+		pool:   &ConnectionPool{name: "pool"},
+		leases: make(map[*Session]sessionLease),
+		log:    logging.NewNoOpLogger(),
+	}
+
+	var (
+		c0 = &Connection{}
+		c1 = &Connection{}
+		s0 = &Session{name: "s0", conn: c0}
+		s1 = &Session{name: "s1", conn: c0}
+		s2 = &Session{name: "s2", conn: c1}
+	)
+
+	c0.SetName("pool-cached-connection-0")
+	c1.SetName("pool-cached-connection-1")
+
+	sp.lease(s0)
+	sp.lease(s1)
+	sp.lease(s2)
+	assert.Equal(t, map[string]int{c0.Name(): 2, c1.Name(): 1}, sp.Stats().Outstanding)
+
+	sp.unlease(s0)
+	sp.unlease(s0) // repeated returns are ignored
+	sp.unlease(s2)
+	assert.Equal(t, map[string]int{c0.Name(): 1}, sp.Stats().Outstanding)
+
+	sp.unlease(s1)
+	assert.Empty(t, sp.Stats().Outstanding)
+}
@@ -1,17 +1,34 @@
 package pool
 
 import (
+	"time"
+
 	"github.com/jxsl13/amqpx/logging"
 )
 
 type sessionPoolOption struct {
 	Capacity       int
 	Confirmable    bool // whether published messages require awaiting confirmations.
+	ConfirmNoWait  bool // whether confirm mode is enabled without awaiting the acknowledgement of the broker.
 	BufferCapacity int  // size of the session internal confirmation and error buffers.
+	MaxMessageSize int  // maximum body size of published messages, 0 is unlimited.
+
+	PublishRate            float64 // publishings per second of all sessions, 0 is unlimited.
+	PublishBurst           int     // number of publishings that may exceed the publish rate at once.
+	NonBlockingPublishRate bool    // whether exceeding the publish rate returns ErrRateLimited instead of blocking.
+
+	PreWarmedConsumers map[string]ConsumeOptions // queues that are consumed by the cached sessions upon creation.
+
+	SlowAcquisition time.Duration // threshold after which a blocking GetSession call is logged, 0 is disabled.
+	LeakThreshold   time.Duration // threshold after which a session that was not returned is logged, 0 is disabled.
+	LeakStacks      bool          // whether the stack of the acquisition of a leaked session is logged.
+	TransientTTL    time.Duration // lifetime after which transient sessions are closed, 0 is unlimited.
 
 	AutoClosePool bool // whether to close the internal connection pool automatically
 	Logger        logging.Logger
 
+	MetricsCollector MetricsCollector
+
 	RecoverCallback                     SessionRetryCallback
 	PublishRetryCallback                SessionRetryCallback
 	GetRetryCallback                    SessionRetryCallback
@@ -52,6 +69,82 @@ func SessionPoolWithBufferCapacity(capacity int) SessionPoolOption {
 	}
 }
 
+// SessionPoolWithMaxMessageSize limits the body size of messages that are published via sessions of the pool.
+// Publishing a bigger message returns ErrMessageTooLarge before it hits the broker, which would otherwise
+// close the channel. A size of 0 or less disables the limit, which is the default.
+func SessionPoolWithMaxMessageSize(bytes int) SessionPoolOption {
+	return func(po *sessionPoolOption) {
+		po.MaxMessageSize = bytes
+	}
+}
+
+// SessionPoolWithPublishRateLimit limits the publishings of all sessions of the pool, including transient sessions,
+// to perSecond messages per second with bursts of up to burst messages, e.g. in order to protect a shared broker
+// from a single runaway producer. Publishing blocks until the rate allows to publish or the context is done.
+// In contrast to the flow control of the broker, the limit is enforced by the client.
+// A rate of 0 or less disables the limit, which is the default. A burst of less than 1 is set to 1.
+func SessionPoolWithPublishRateLimit(perSecond float64, burst int) SessionPoolOption {
+	return func(po *sessionPoolOption) {
+		po.PublishRate = perSecond
+		po.PublishBurst = burst
+	}
+}
+
+// SessionPoolWithNonBlockingPublishRateLimit makes publishing return ErrRateLimited instead of blocking
+// in case the publish rate limit is exceeded, see SessionPoolWithPublishRateLimit.
+func SessionPoolWithNonBlockingPublishRateLimit(nonBlocking bool) SessionPoolOption {
+	return func(po *sessionPoolOption) {
+		po.NonBlockingPublishRate = nonBlocking
+	}
+}
+
+// SessionPoolWithTransientTTL closes transient sessions, see GetTransientSession, together with their transient
+// connections in case they are still open after the passed ttl, and logs a warning.
+// This is a safety net against transient sessions that are never closed, which would accumulate connections.
+// The ttl must be larger than the longest legitimate usage of a transient session, e.g. hours.
+// A ttl of 0 or less keeps transient sessions open until they are closed or their context is done (default).
+func SessionPoolWithTransientTTL(ttl time.Duration) SessionPoolOption {
+	return func(po *sessionPoolOption) {
+		po.TransientTTL = ttl
+	}
+}
+
+// SessionPoolWithLeakDetection logs a warning with the pool, connection and session name as soon as an acquired
+// session was not returned to the pool within the threshold, which helps to diagnose sessions that are never returned
+// and exhaust the pool or the channels of its connections.
+// In case captureStacks is true, the stack of the GetSession call is logged as well. As capturing the stack on every
+// acquisition is expensive, it should only be enabled for debugging.
+// A threshold of 0 or less disables the detection, which is the default. See SessionPoolStats.Outstanding.
+func SessionPoolWithLeakDetection(threshold time.Duration, captureStacks bool) SessionPoolOption {
+	return func(po *sessionPoolOption) {
+		po.LeakThreshold = threshold
+		po.LeakStacks = captureStacks
+	}
+}
+
+// SessionPoolWithPreWarmedConsumers registers one consumer per queue while the pool is created,
+// which avoids the consume setup latency of the first message, as messages start flowing as soon as the pool is ready.
+// Every consumer gets a dedicated cached session in addition to the sessions of the pool, which is not handed out
+// by GetSession. Consumers are restored whenever their session is recovered. Their deliveries are obtained via SessionPool.PreWarmedConsumer.
+// The queues must exist, see Topologer. An empty consumer tag defaults to the session name followed by the queue name.
+func SessionPoolWithPreWarmedConsumers(consumers map[string]ConsumeOptions) SessionPoolOption {
+	return func(po *sessionPoolOption) {
+		po.PreWarmedConsumers = make(map[string]ConsumeOptions, len(consumers))
+		for queue, o := range consumers {
+			po.PreWarmedConsumers[queue] = o
+		}
+	}
+}
+
+// SessionPoolWithSlowAcquisitionThreshold logs a warning with the pool name and the wait duration
+// as soon as GetSession blocks longer than the threshold, e.g. because all sessions are in use.
+// The threshold is derived from the connection pool by default. A threshold <= 0 disables the warning.
+func SessionPoolWithSlowAcquisitionThreshold(threshold time.Duration) SessionPoolOption {
+	return func(po *sessionPoolOption) {
+		po.SlowAcquisition = threshold
+	}
+}
+
 // SessionPoolWithConfirms requires all messages from sessions to be acked.
 func SessionPoolWithConfirms(requirePublishConfirms bool) SessionPoolOption {
 	return func(po *sessionPoolOption) {
@@ -59,6 +152,14 @@ func SessionPoolWithConfirms(requirePublishConfirms bool) SessionPoolOption {
 	}
 }
 
+// SessionPoolWithConfirmNoWait enables confirm mode of the sessions without awaiting the acknowledgement of the broker,
+// which reduces the latency of creating and recovering sessions, see SessionWithConfirmNoWait.
+func SessionPoolWithConfirmNoWait(noWait bool) SessionPoolOption {
+	return func(po *sessionPoolOption) {
+		po.ConfirmNoWait = noWait
+	}
+}
+
 // SessionPoolWithAutoCloseConnectionPool allows to close the internal connection pool automatically.
 // This is helpful in case you have a session pool that is the onl yuser of the connection pool.
 // You are basically passing ownership of the connection pool to the session pool with this.
@@ -218,3 +319,11 @@ func SessionPoolWithFlowRetryCallback(callback SessionRetryCallback) SessionPool
 		po.FlowRetryCallback = callback
 	}
 }
+
+// SessionPoolWithMetricsCollector allows to export session pool metrics, e.g. to Prometheus.
+// The collector is derived from the connection pool by default.
+func SessionPoolWithMetricsCollector(collector MetricsCollector) SessionPoolOption {
+	return func(po *sessionPoolOption) {
+		po.MetricsCollector = collector
+	}
+}
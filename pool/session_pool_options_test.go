@@ -0,0 +1,271 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jxsl13/amqpx/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionPoolBufferCapacity(t *testing.T) {
+	t.Parallel()
+
+	var option sessionPoolOption
+	SessionPoolWithBufferCapacity(7)(&option)
+
+	// the buffer capacity must not be confused with the number of pooled sessions
+	sp := &SessionPool{
+		capacity:       2,
+		bufferCapacity: option.BufferCapacity,
+		log:            logging.NewNoOpLogger(),
+	}
+
+	addr, _ := newFakeBroker(t, "PLAIN")
+	for _, cached := range []bool{true, false} {
+		conn, err := NewConnection(context.TODO(), fmt.Sprintf("amqp://admin:password@%s/", addr), "pool-connection",
+			ConnectionWithLogger(logging.NewNoOpLogger()),
+			ConnectionWithCached(cached),
+		)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		s, err := sp.deriveSession(context.TODO(), conn, 0)
+		require.NoError(t, err)
+		defer s.Close()
+
+		assert.Equal(t, 7, s.bufferCapacity, "cached: %v", cached)
+		assert.Equal(t, 7, cap(s.errors), "cached: %v", cached)
+	}
+}
+
+func TestSessionPoolTryGetSession(t *testing.T) {
+	t.Parallel()
+
+	addr, _ := newFakeBroker(t, "PLAIN")
+	cp, err := NewConnectionPool(context.TODO(), fmt.Sprintf("amqp://admin:password@%s/", addr), 1,
+		ConnectionPoolWithName(t.Name()),
+		ConnectionPoolWithLogger(logging.NewNoOpLogger()),
+	)
+	require.NoError(t, err)
+	defer cp.Close()
+
+	sp, err := NewSessionPool(cp, 2)
+	require.NoError(t, err)
+	defer sp.Close()
+
+	// drain the pool
+	s1, ok := sp.TryGetSession()
+	require.True(t, ok)
+	s2, ok := sp.TryGetSession()
+	require.True(t, ok)
+	assert.NotSame(t, s1, s2)
+
+	s, ok := sp.TryGetSession()
+	assert.False(t, ok)
+	assert.Nil(t, s)
+
+	sp.ReturnSession(s1, nil)
+	s, ok = sp.TryGetSession()
+	require.True(t, ok)
+	assert.Same(t, s1, s)
+	sp.ReturnSession(s, nil)
+
+	// flagged sessions are not recovered without blocking
+	sp.ReturnSession(s2, errors.New("channel died"))
+	s, ok = sp.TryGetSession()
+	require.True(t, ok)
+	assert.Same(t, s1, s)
+
+	_, ok = sp.TryGetSession()
+	assert.False(t, ok)
+	sp.ReturnSession(s, nil)
+
+	sp.Close()
+	_, ok = sp.TryGetSession()
+	assert.False(t, ok)
+
+	// failed attempts are counted like the failed acquisitions of GetSession
+	acquisitions := sp.Stats().Acquisitions
+	assert.Equal(t, uint64(4), acquisitions.CachedAcquired)
+	assert.Equal(t, uint64(3), acquisitions.CachedAcquireErrors)
+}
+
+func TestSessionPoolGetSessionContext(t *testing.T) {
+	t.Parallel()
+
+	addr, _ := newFakeBroker(t, "PLAIN")
+	cp, err := NewConnectionPool(context.TODO(), fmt.Sprintf("amqp://admin:password@%s/", addr), 1,
+		ConnectionPoolWithName(t.Name()),
+		ConnectionPoolWithLogger(logging.NewNoOpLogger()),
+	)
+	require.NoError(t, err)
+	defer cp.Close()
+
+	sp, err := NewSessionPool(cp, 1)
+	require.NoError(t, err)
+	defer sp.Close()
+
+	s, err := sp.GetSession(context.TODO())
+	require.NoError(t, err)
+	defer sp.ReturnSession(s, nil)
+
+	// the pool is exhausted
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = sp.GetSession(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestSessionPoolWithTransientTTL(t *testing.T) {
+	t.Parallel()
+
+	addr, _ := newFakeBroker(t, "PLAIN")
+	cp, err := NewConnectionPool(context.TODO(), fmt.Sprintf("amqp://admin:password@%s/", addr), 1,
+		ConnectionPoolWithName(t.Name()),
+		ConnectionPoolWithLogger(logging.NewNoOpLogger()),
+	)
+	require.NoError(t, err)
+	defer cp.Close()
+
+	sp, err := NewSessionPool(cp, 1, SessionPoolWithTransientTTL(300*time.Millisecond))
+	require.NoError(t, err)
+	defer sp.Close()
+
+	// a transient session that is closed within its ttl is not affected
+	s, err := sp.GetTransientSession(context.TODO())
+	require.NoError(t, err)
+	sp.ReturnSession(s, nil)
+
+	// a transient session that is never returned is reaped with its connection
+	leaked, err := sp.GetTransientSession(context.TODO())
+	require.NoError(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.False(t, leaked.conn.IsClosed(), "transient session must be kept open within its ttl")
+	assert.Equal(t, 1, cp.StatTransientActive())
+
+	assert.Eventually(t, func() bool {
+		return leaked.conn.IsClosed() && cp.StatTransientActive() == 0 && len(sp.Stats().Outstanding) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestSessionPoolCloseWhileInUse(t *testing.T) {
+	t.Parallel()
+
+	addr, _ := newFakeBroker(t, "PLAIN")
+	cp, err := NewConnectionPool(context.TODO(), fmt.Sprintf("amqp://admin:password@%s/", addr), 1,
+		ConnectionPoolWithName(t.Name()),
+		ConnectionPoolWithLogger(logging.NewNoOpLogger()),
+	)
+	require.NoError(t, err)
+
+	sp, err := NewSessionPool(cp, 2)
+	require.NoError(t, err)
+	cp.RegisterDependent(sp)
+
+	// a session that is never returned must not block closing the pools
+	leaked, err := sp.GetSession(context.TODO())
+	require.NoError(t, err)
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		cp.Close()
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("closing the connection pool blocked on a session that is in use")
+	}
+
+	leaked.mu.Lock()
+	assert.Nil(t, leaked.channel, "sessions in use must be closed")
+	leaked.mu.Unlock()
+
+	// returning a closed session is safe
+	sp.ReturnSession(leaked, nil)
+	sp.Close()
+}
+
+func TestConnectionPoolDependents(t *testing.T) {
+	t.Parallel()
+
+	addr, _ := newFakeBroker(t, "PLAIN")
+	cp, err := NewConnectionPool(context.TODO(), fmt.Sprintf("amqp://admin:password@%s/", addr), 1,
+		ConnectionPoolWithName(t.Name()),
+		ConnectionPoolWithLogger(logging.NewNoOpLogger()),
+	)
+	require.NoError(t, err)
+
+	sp, err := NewSessionPool(cp, 1)
+	require.NoError(t, err)
+	cp.RegisterDependent(sp)
+
+	// closed session pools unregister themselves
+	sp.Close()
+	cp.mu.Lock()
+	assert.Empty(t, cp.dependents)
+	cp.mu.Unlock()
+
+	late, err := NewSessionPool(cp, 1)
+	require.NoError(t, err)
+
+	// session pools that are registered after the connection pool was closed are closed right away
+	cp.Close()
+	cp.RegisterDependent(late)
+
+	late.closeMu.Lock()
+	assert.True(t, late.closed)
+	late.closeMu.Unlock()
+
+	cp.mu.Lock()
+	assert.Empty(t, cp.dependents)
+	cp.mu.Unlock()
+}
+
+func TestSessionPoolReturnForeignSession(t *testing.T) {
+	t.Parallel()
+
+	addr, _ := newFakeBroker(t, "PLAIN")
+	cp, err := NewConnectionPool(context.TODO(), fmt.Sprintf("amqp://admin:password@%s/", addr), 2,
+		ConnectionPoolWithName(t.Name()),
+		ConnectionPoolWithLogger(logging.NewNoOpLogger()),
+	)
+	require.NoError(t, err)
+	defer cp.Close()
+
+	sp, err := NewSessionPool(cp, 1)
+	require.NoError(t, err)
+	defer sp.Close()
+
+	other, err := NewSessionPool(cp, 1)
+	require.NoError(t, err)
+	defer other.Close()
+
+	// the buffer of the pool is full, the cached session of the other pool is closed
+	foreign, err := other.GetSession(context.TODO())
+	require.NoError(t, err)
+	assert.NotPanics(t, func() { sp.ReturnSession(foreign, nil) })
+
+	foreign.mu.Lock()
+	assert.Nil(t, foreign.channel, "foreign sessions must be closed")
+	foreign.mu.Unlock()
+
+	// the foreign session is not part of the pool
+	s, err := sp.GetSession(context.TODO())
+	require.NoError(t, err)
+	assert.NotSame(t, foreign, s)
+	sp.ReturnSession(s, nil)
+
+	// returning a closed session to its own pool is safe
+	other.ReturnSession(foreign, nil)
+}
@@ -0,0 +1,80 @@
+package pool
+
+import (
+	"fmt"
+	"sort"
+)
+
+// preWarmedConsumer is a consumer that was registered on a dedicated cached session upon pool creation.
+type preWarmedConsumer struct {
+	session    *Session
+	deliveries <-chan Delivery
+}
+
+// initPreWarmedConsumers subscribes the configured queues on dedicated cached sessions.
+// Every queue gets its own session, which is not part of the rotation of GetSession, as a session must only be used
+// by one goroutine at a time. Queues are sorted, which makes the assignment of session names deterministic.
+func (sp *SessionPool) initPreWarmedConsumers(consumers map[string]ConsumeOptions, channels map[*Connection]int) error {
+	if len(consumers) == 0 {
+		return nil
+	}
+
+	queues := make([]string, 0, len(consumers))
+	for queue := range consumers {
+		queues = append(queues, queue)
+	}
+	sort.Strings(queues)
+
+	sp.preWarmed = make(map[string]preWarmedConsumer, len(queues))
+	for i, queue := range queues {
+		// ids continue after the ids of the sessions that are handed out by GetSession
+		session, err := sp.initCachedSession(sp.capacity+i, channels)
+		if err != nil {
+			return fmt.Errorf("failed to pre-warm consumer for queue %s: %w", queue, err)
+		}
+		sp.cached = append(sp.cached, session)
+		// the session is never returned to the pool, which allows to (n)ack its deliveries at any time
+		session.borrow()
+
+		o := consumers[queue]
+		if o.ConsumerTag == "" {
+			o.ConsumerTag = withConsumerTagPrefix(o.ConsumerTagPrefix, fmt.Sprintf("%s-%s", session.Name(), queue))
+		}
+
+		// restored by the session upon recovery and canceled upon session pool closure
+		deliveries, err := session.Consume(queue, o)
+		if err != nil {
+			return fmt.Errorf("failed to pre-warm consumer for queue %s: %w", queue, err)
+		}
+		sp.preWarmed[queue] = preWarmedConsumer{
+			session:    session,
+			deliveries: deliveries,
+		}
+		sp.debug(fmt.Sprintf("pre-warmed consumer %s for queue %s on session %s", o.ConsumerTag, queue, session.Name()))
+	}
+	return nil
+}
+
+// isPreWarmed returns true in case the session is dedicated to a pre-warmed consumer.
+func (sp *SessionPool) isPreWarmed(session *Session) bool {
+	for _, c := range sp.preWarmed {
+		if c.session == session {
+			return true
+		}
+	}
+	return false
+}
+
+// PreWarmedConsumer returns the deliveries of the consumer that was registered on a dedicated cached session
+// for the passed queue upon pool creation, see SessionPoolWithPreWarmedConsumers.
+// The returned session must be used to ack, nack or reject the deliveries. It is not handed out by GetSession
+// and must not be returned to the pool, it is closed together with the pool.
+// The deliveries must be received, as unreceived deliveries block the session.
+// ErrNotFound is returned in case no consumer was pre-warmed for the queue.
+func (sp *SessionPool) PreWarmedConsumer(queue string) (*Session, <-chan Delivery, error) {
+	c, ok := sp.preWarmed[queue]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: no pre-warmed consumer for queue %s", ErrNotFound, queue)
+	}
+	return c.session, c.deliveries, nil
+}
@@ -2,6 +2,8 @@ package pool_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -10,6 +12,7 @@ import (
 	"github.com/jxsl13/amqpx/logging"
 	"github.com/jxsl13/amqpx/pool"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSingleSessionPool(t *testing.T) {
@@ -102,3 +105,535 @@ func TestNewSessionPool(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestConnectionPoolRegisterDependent(t *testing.T) {
+	t.Parallel()
+	var (
+		poolName    = testutils.FuncName()
+		ctx         = context.TODO()
+		connections = 1
+		sessions    = 5
+		log         = newCloseOrderLogger()
+	)
+	p, err := pool.NewConnectionPool(ctx,
+		testutils.HealthyConnectURL,
+		connections,
+		pool.ConnectionPoolWithName(poolName),
+		pool.ConnectionPoolWithLogger(log),
+	)
+	if err != nil {
+		assert.NoError(t, err)
+		return
+	}
+
+	sp, err := pool.NewSessionPool(p, sessions)
+	if err != nil {
+		assert.NoError(t, err)
+		return
+	}
+	p.RegisterDependent(sp)
+
+	s, err := sp.GetSession(ctx)
+	if err != nil {
+		assert.NoError(t, err)
+		return
+	}
+	sp.ReturnSession(s, nil)
+
+	p.Close()
+	// must not block nor close anything twice
+	sp.Close()
+
+	_, err = sp.GetSession(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	closed := log.Closed()
+	assert.Equal(t, sessions+connections, len(closed), closed)
+	for i, component := range closed {
+		if i < sessions {
+			assert.Equal(t, "session", component, "expected sessions to be closed before connections: %v", closed)
+		} else {
+			assert.Equal(t, "connection", component, "expected connections to be closed after sessions: %v", closed)
+		}
+	}
+}
+
+// closeOrderLogger records which components log that they have been closed.
+type closeOrderLogger struct {
+	*logging.NoOpLogger
+	fields logging.Fields
+	mu     *sync.Mutex
+	closed *[]string
+}
+
+func newCloseOrderLogger() *closeOrderLogger {
+	return &closeOrderLogger{
+		NoOpLogger: logging.NewNoOpLogger(),
+		fields:     logging.Fields{},
+		mu:         &sync.Mutex{},
+		closed:     &[]string{},
+	}
+}
+
+func (l *closeOrderLogger) Closed() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), *l.closed...)
+}
+
+func (l *closeOrderLogger) Info(args ...any) {
+	if fmt.Sprint(args...) != "closed" {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, component := range []string{"session", "connection"} {
+		if _, ok := l.fields[component]; ok {
+			*l.closed = append(*l.closed, component)
+			return
+		}
+	}
+}
+
+func (l *closeOrderLogger) WithField(key string, value any) logging.Logger {
+	return l.WithFields(logging.Fields{key: value})
+}
+
+func (l *closeOrderLogger) WithFields(fields logging.Fields) logging.Logger {
+	n := *l
+	n.fields = make(logging.Fields, len(l.fields)+len(fields))
+	for k, v := range l.fields {
+		n.fields[k] = v
+	}
+	for k, v := range fields {
+		n.fields[k] = v
+	}
+	return &n
+}
+
+func (l *closeOrderLogger) WithError(err error) logging.Logger {
+	return l.WithField("error", err)
+}
+
+func TestSessionPoolUse(t *testing.T) {
+	t.Parallel()
+	var (
+		poolName = testutils.FuncName()
+		ctx      = context.TODO()
+		errUse   = errors.New("use failed")
+		used     *pool.Session
+	)
+	cp, err := pool.NewConnectionPool(ctx,
+		testutils.HealthyConnectURL,
+		1,
+		pool.ConnectionPoolWithName(poolName),
+		pool.ConnectionPoolWithLogger(logging.NewTestLogger(t)),
+	)
+	require.NoError(t, err)
+
+	sp, err := pool.NewSessionPool(cp, 1, pool.SessionPoolWithAutoCloseConnectionPool(true))
+	require.NoError(t, err)
+	defer sp.Close()
+
+	err = sp.Use(ctx, func(s *pool.Session) error {
+		used = s
+		assert.Equal(t, 0, sp.Size())
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, sp.Size())
+	assert.False(t, used.IsFlagged())
+
+	// the error of the callback flags the session
+	err = sp.Use(ctx, func(s *pool.Session) error {
+		return errUse
+	})
+	assert.ErrorIs(t, err, errUse)
+	assert.Equal(t, 1, sp.Size())
+	assert.True(t, used.IsFlagged())
+
+	// the session is returned and flagged even if the callback panics
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = sp.Use(ctx, func(s *pool.Session) error {
+			assert.False(t, s.IsFlagged(), "expected session to be recovered")
+			panic("boom")
+		})
+	})
+	assert.Equal(t, 1, sp.Size())
+	assert.True(t, used.IsFlagged())
+
+	err = sp.Use(ctx, func(s *pool.Session) error {
+		assert.False(t, s.IsFlagged(), "expected session to be recovered")
+		return nil
+	})
+	assert.NoError(t, err)
+}
+
+func TestSessionPoolSlowAcquisition(t *testing.T) {
+	t.Parallel()
+	var (
+		poolName  = testutils.FuncName()
+		ctx       = context.TODO()
+		log       = newWarnLogger()
+		threshold = 100 * time.Millisecond
+	)
+	cp, err := pool.NewConnectionPool(ctx,
+		testutils.HealthyConnectURL,
+		1,
+		pool.ConnectionPoolWithName(poolName),
+		pool.ConnectionPoolWithLogger(log),
+		pool.ConnectionPoolWithSlowAcquisitionThreshold(threshold),
+	)
+	require.NoError(t, err)
+	defer cp.Close()
+
+	sp, err := pool.NewSessionPool(cp, 1)
+	require.NoError(t, err)
+	defer sp.Close()
+
+	// fast acquisitions are not logged
+	s, err := sp.GetSession(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, log.Warnings())
+
+	// saturated pool
+	timeoutCtx, cancel := context.WithTimeout(ctx, 3*threshold)
+	defer cancel()
+	_, err = sp.GetSession(timeoutCtx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	sp.ReturnSession(s, nil)
+
+	warnings := log.Warnings()
+	require.Len(t, warnings, 1)
+	assert.Equal(t, cp.Name(), warnings[0]["sessionPool"])
+	waited, err := time.ParseDuration(warnings[0]["waited"].(string))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, waited, threshold)
+}
+
+// warnLogger records the fields of all warnings.
+type warnLogger struct {
+	*logging.NoOpLogger
+	fields   logging.Fields
+	mu       *sync.Mutex
+	warnings *[]logging.Fields
+}
+
+func newWarnLogger() *warnLogger {
+	return &warnLogger{
+		NoOpLogger: logging.NewNoOpLogger(),
+		fields:     logging.Fields{},
+		mu:         &sync.Mutex{},
+		warnings:   &[]logging.Fields{},
+	}
+}
+
+func (l *warnLogger) Warnings() []logging.Fields {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]logging.Fields(nil), *l.warnings...)
+}
+
+func (l *warnLogger) Warn(args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	*l.warnings = append(*l.warnings, l.fields)
+}
+
+func (l *warnLogger) WithField(key string, value any) logging.Logger {
+	return l.WithFields(logging.Fields{key: value})
+}
+
+func (l *warnLogger) WithFields(fields logging.Fields) logging.Logger {
+	n := *l
+	n.fields = make(logging.Fields, len(l.fields)+len(fields))
+	for k, v := range l.fields {
+		n.fields[k] = v
+	}
+	for k, v := range fields {
+		n.fields[k] = v
+	}
+	return &n
+}
+
+func (l *warnLogger) WithError(err error) logging.Logger {
+	return l.WithField("error", err)
+}
+
+func TestSessionPoolTransientSessionContextCancel(t *testing.T) {
+	t.Parallel()
+	var (
+		poolName = testutils.FuncName()
+		ctx      = context.TODO()
+		log      = newCloseOrderLogger()
+	)
+	cp, err := pool.NewConnectionPool(ctx,
+		testutils.HealthyConnectURL,
+		1,
+		pool.ConnectionPoolWithName(poolName),
+		pool.ConnectionPoolWithLogger(log),
+	)
+	require.NoError(t, err)
+	defer cp.Close()
+
+	sp, err := pool.NewSessionPool(cp, 1)
+	require.NoError(t, err)
+	defer sp.Close()
+
+	sessionCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s, err := sp.GetTransientSession(sessionCtx)
+	require.NoError(t, err)
+	_, err = s.QueueDeclare(sessionCtx, testutils.QueueNameGenerator(s.Name())(), pool.QueueDeclareOptions{AutoDelete: true, Exclusive: true})
+	require.NoError(t, err)
+	assert.Empty(t, log.Closed())
+
+	// canceling the context closes the session as well as its transient connection
+	cancel()
+	assert.Eventually(t, func() bool {
+		return len(log.Closed()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.ElementsMatch(t, []string{"session", "connection"}, log.Closed())
+
+	// returning the already closed transient session must not block nor panic
+	sp.ReturnSession(s, nil)
+}
+
+func TestSessionPoolPreWarmedConsumers(t *testing.T) {
+	t.Parallel()
+	var (
+		poolName = testutils.FuncName()
+		ctx      = context.TODO()
+		nextName = testutils.QueueNameGenerator(poolName)
+		queues   = []string{nextName(), nextName(), nextName()}
+	)
+	cp, err := pool.NewConnectionPool(ctx,
+		testutils.HealthyConnectURL,
+		1,
+		pool.ConnectionPoolWithName(poolName),
+		pool.ConnectionPoolWithLogger(logging.NewTestLogger(t)),
+	)
+	require.NoError(t, err)
+	defer cp.Close()
+
+	setup, err := pool.NewSessionPool(cp, 1)
+	require.NoError(t, err)
+	defer setup.Close()
+
+	s, err := setup.GetSession(ctx)
+	require.NoError(t, err)
+	defer setup.ReturnSession(s, nil)
+
+	consumers := make(map[string]pool.ConsumeOptions, len(queues))
+	for _, queue := range queues {
+		_, err = s.QueueDeclare(ctx, queue)
+		require.NoError(t, err)
+		defer func(queue string) {
+			_, err := s.QueueDelete(ctx, queue)
+			assert.NoError(t, err)
+		}(queue)
+		consumers[queue] = pool.ConsumeOptions{}
+	}
+
+	// more queues than sessions, every consumer gets a dedicated session
+	sp, err := pool.NewSessionPool(cp, 2, pool.SessionPoolWithPreWarmedConsumers(consumers))
+	require.NoError(t, err)
+	defer sp.Close()
+	assert.Equal(t, 2, sp.Size())
+
+	// the sessions of the consumers are never handed out by GetSession
+	pooled := make([]*pool.Session, 0, sp.Capacity())
+	for i := 0; i < sp.Capacity(); i++ {
+		ps, err := sp.GetSession(ctx)
+		require.NoError(t, err)
+		pooled = append(pooled, ps)
+	}
+	for _, queue := range queues {
+		consumer, _, err := sp.PreWarmedConsumer(queue)
+		require.NoError(t, err)
+		assert.NotContains(t, pooled, consumer, queue)
+	}
+	for _, ps := range pooled {
+		sp.ReturnSession(ps, nil)
+	}
+
+	for _, queue := range queues {
+		// the consumers are active as soon as the pool is ready
+		q, err := s.QueueDeclarePassive(ctx, queue)
+		require.NoError(t, err)
+		assert.Equal(t, 1, q.Consumers, queue)
+
+		_, err = s.Publish(ctx, "", queue, pool.Publishing{
+			ContentType: "text/plain",
+			Body:        []byte(queue),
+		})
+		require.NoError(t, err)
+
+		consumer, deliveries, err := sp.PreWarmedConsumer(queue)
+		require.NoError(t, err)
+
+		select {
+		case msg := <-deliveries:
+			assert.Equal(t, queue, string(msg.Body))
+			assert.NoError(t, consumer.Ack(msg.DeliveryTag, false))
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "timed out waiting for message", queue)
+		}
+	}
+
+	_, _, err = sp.PreWarmedConsumer(nextName())
+	assert.ErrorIs(t, err, pool.ErrNotFound)
+
+	// closing the session pool cancels the consumers
+	sp.Close()
+	for _, queue := range queues {
+		assert.Eventually(t, func() bool {
+			q, err := s.QueueDeclarePassive(ctx, queue)
+			return err == nil && q.Consumers == 0
+		}, 5*time.Second, 50*time.Millisecond, queue)
+	}
+}
+
+func TestSessionPoolReturnedSession(t *testing.T) {
+	t.Parallel()
+	var (
+		poolName = testutils.FuncName()
+		ctx      = context.TODO()
+	)
+	p, err := pool.NewConnectionPool(ctx,
+		testutils.HealthyConnectURL,
+		1,
+		pool.ConnectionPoolWithName(poolName),
+		pool.ConnectionPoolWithLogger(logging.NewTestLogger(t)),
+	)
+	require.NoError(t, err)
+
+	sp, err := pool.NewSessionPool(
+		p,
+		1,
+		pool.SessionPoolWithAutoCloseConnectionPool(true),
+	)
+	require.NoError(t, err)
+	defer sp.Close()
+
+	s, err := sp.GetSession(ctx)
+	require.NoError(t, err)
+	sp.ReturnSession(s, nil)
+
+	// the stale reference must not publish on the pooled session
+	_, err = s.Publish(ctx, "", poolName, pool.Publishing{Body: []byte("stale")})
+	assert.ErrorIs(t, err, pool.ErrSessionReturned)
+
+	s, err = sp.GetSession(ctx)
+	require.NoError(t, err)
+	defer sp.ReturnSession(s, nil)
+
+	_, err = s.Publish(ctx, "", poolName, pool.Publishing{Body: []byte("borrowed")})
+	assert.NoError(t, err)
+}
+
+func TestSessionPoolPublishRateLimit(t *testing.T) {
+	t.Parallel()
+	var (
+		poolName  = testutils.FuncName()
+		ctx       = context.TODO()
+		perSecond = 50.0
+		burst     = 5
+		publishes = 30
+		sessions  = 3
+	)
+	p, err := pool.NewConnectionPool(ctx,
+		testutils.HealthyConnectURL,
+		1,
+		pool.ConnectionPoolWithName(poolName),
+		pool.ConnectionPoolWithLogger(logging.NewTestLogger(t)),
+	)
+	require.NoError(t, err)
+
+	sp, err := pool.NewSessionPool(
+		p,
+		sessions,
+		pool.SessionPoolWithAutoCloseConnectionPool(true),
+		pool.SessionPoolWithConfirms(true),
+		pool.SessionPoolWithPublishRateLimit(perSecond, burst),
+	)
+	require.NoError(t, err)
+	defer sp.Close()
+
+	// the limit is shared by all sessions of the pool
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < sessions; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := sp.Use(ctx, func(s *pool.Session) error {
+				for j := 0; j < publishes/sessions; j++ {
+					tag, err := s.Publish(ctx, "", poolName, pool.Publishing{Body: []byte("limited")})
+					if err != nil {
+						return err
+					}
+					err = s.AwaitConfirm(ctx, tag)
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	expected := time.Duration(float64(publishes-burst) / perSecond * float64(time.Second))
+	assert.GreaterOrEqual(t, time.Since(start), expected-10*time.Millisecond)
+}
+
+func TestSessionPoolLeakDetection(t *testing.T) {
+	t.Parallel()
+	var (
+		poolName  = testutils.FuncName()
+		ctx       = context.TODO()
+		log       = newWarnLogger()
+		threshold = 100 * time.Millisecond
+	)
+	cp, err := pool.NewConnectionPool(ctx,
+		testutils.HealthyConnectURL,
+		1,
+		pool.ConnectionPoolWithName(poolName),
+		pool.ConnectionPoolWithLogger(log),
+	)
+	require.NoError(t, err)
+	defer cp.Close()
+
+	sp, err := pool.NewSessionPool(cp, 2,
+		pool.SessionPoolWithLogger(log),
+		pool.SessionPoolWithLeakDetection(threshold, true),
+	)
+	require.NoError(t, err)
+	defer sp.Close()
+
+	// returned sessions are not logged
+	s, err := sp.GetSession(ctx)
+	require.NoError(t, err)
+	sp.ReturnSession(s, nil)
+
+	// long held sessions are logged
+	s, err = sp.GetSession(ctx)
+	require.NoError(t, err)
+	assert.Len(t, sp.Stats().Outstanding, 1)
+	time.Sleep(3 * threshold)
+	sp.ReturnSession(s, nil)
+	assert.Empty(t, sp.Stats().Outstanding)
+
+	warnings := log.Warnings()
+	require.Len(t, warnings, 1)
+	assert.Equal(t, cp.Name(), warnings[0]["sessionPool"])
+	assert.Equal(t, s.Name(), warnings[0]["session"])
+	assert.Contains(t, warnings[0]["stack"], poolName, "the stack of the acquisition must be logged")
+	held, err := time.ParseDuration(warnings[0]["held"].(string))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, held, threshold)
+}
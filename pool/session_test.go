@@ -2,6 +2,9 @@ package pool_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -11,6 +14,7 @@ import (
 	"github.com/jxsl13/amqpx/pool"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewSingleSessionPublishAndConsume(t *testing.T) {
@@ -1055,3 +1059,1266 @@ func TestNewSingleSessionCloseWithHealthyRabbitMQ(t *testing.T) {
 	err = s.Close()
 	assert.NoError(t, err)
 }
+
+func TestSessionQosGlobal(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		global        bool
+		prefetchCount int
+		expected      int
+	}{
+		// prefetch limit applies to each consumer individually
+		{name: "per consumer", global: false, prefetchCount: 2, expected: 4},
+		// prefetch limit is shared between both consumers
+		{name: "per channel", global: true, prefetchCount: 2, expected: 2},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			var (
+				ctx                     = context.TODO()
+				nextConnName            = testutils.ConnectionNameGenerator()
+				connName                = nextConnName()
+				nextQueueName           = testutils.QueueNameGenerator(connName)
+				queueName               = nextQueueName()
+				nextExchangeName        = testutils.ExchangeNameGenerator(connName)
+				exchangeName            = nextExchangeName()
+				nextConsumerName        = testutils.ConsumerNameGenerator(queueName)
+				publishMessageGenerator = testutils.MessageGenerator(queueName)
+				numMsgs                 = 10
+			)
+
+			s, closer := NewSession(t, ctx, testutils.HealthyConnectURL, connName)
+			defer closer()
+
+			cleanup := DeclareExchangeQueue(t, ctx, s, exchangeName, queueName)
+			defer cleanup()
+
+			err := s.Qos(ctx, test.prefetchCount, 0, pool.QosOptions{Global: test.global})
+			if err != nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			var consumers []<-chan pool.Delivery
+			for i := 0; i < 2; i++ {
+				c, err := s.Consume(queueName, pool.ConsumeOptions{ConsumerTag: nextConsumerName()})
+				if err != nil {
+					assert.NoError(t, err)
+					return
+				}
+				consumers = append(consumers, c)
+			}
+
+			PublishN(t, ctx, s, exchangeName, publishMessageGenerator, numMsgs)
+
+			// no message is acked, so the broker stops delivering once the prefetch limit is reached
+			received := 0
+			timeout := time.After(2 * time.Second)
+		loop:
+			for {
+				select {
+				case <-consumers[0]:
+					received++
+				case <-consumers[1]:
+					received++
+				case <-timeout:
+					break loop
+				}
+			}
+			assert.Equal(t, test.expected, received)
+
+			// requeue all unacked messages
+			assert.NoError(t, s.Nack(0, true, true))
+		})
+	}
+}
+
+func TestSessionConsumePrefetch(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		qos      *pool.QosOptions
+		prefetch []int // of both consumers
+		expected []int // number of unacked deliveries of both consumers, nil in case it is up to the broker
+		total    int
+	}{
+		// each consumer gets its own limit, the other consumer keeps the per consumer limit of the session
+		{name: "per consumer", qos: &pool.QosOptions{Global: false}, prefetch: []int{1, 0}, expected: []int{1, 2}, total: 3},
+		{name: "without session limit", prefetch: []int{3, 1}, expected: []int{3, 1}, total: 4},
+		// the limit that is shared by all consumers of the session still applies
+		{name: "per channel", qos: &pool.QosOptions{Global: true}, prefetch: []int{5, 5}, total: 2},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			var (
+				ctx                     = context.TODO()
+				nextConnName            = testutils.ConnectionNameGenerator()
+				connName                = nextConnName()
+				nextQueueName           = testutils.QueueNameGenerator(connName)
+				queueName               = nextQueueName()
+				nextExchangeName        = testutils.ExchangeNameGenerator(connName)
+				exchangeName            = nextExchangeName()
+				nextConsumerName        = testutils.ConsumerNameGenerator(queueName)
+				publishMessageGenerator = testutils.MessageGenerator(queueName)
+				numMsgs                 = 10
+			)
+
+			s, closer := NewSession(t, ctx, testutils.HealthyConnectURL, connName)
+			defer closer()
+
+			cleanup := DeclareExchangeQueue(t, ctx, s, exchangeName, queueName)
+			defer cleanup()
+
+			if test.qos != nil {
+				err := s.Qos(ctx, 2, 0, *test.qos)
+				if err != nil {
+					assert.NoError(t, err)
+					return
+				}
+			}
+
+			// two consumers share the same session (channel)
+			var consumers []<-chan pool.Delivery
+			for _, prefetch := range test.prefetch {
+				c, err := s.Consume(queueName, pool.ConsumeOptions{
+					ConsumerTag: nextConsumerName(),
+					Prefetch:    prefetch,
+				})
+				if err != nil {
+					assert.NoError(t, err)
+					return
+				}
+				consumers = append(consumers, c)
+			}
+
+			PublishN(t, ctx, s, exchangeName, publishMessageGenerator, numMsgs)
+
+			// no message is acked, so the broker stops delivering once the prefetch limits are reached
+			received := make([]int, len(consumers))
+			timeout := time.After(2 * time.Second)
+		loop:
+			for {
+				select {
+				case <-consumers[0]:
+					received[0]++
+				case <-consumers[1]:
+					received[1]++
+				case <-timeout:
+					break loop
+				}
+			}
+
+			assert.Equal(t, test.total, received[0]+received[1])
+			if test.expected != nil {
+				assert.Equal(t, test.expected, received)
+			}
+
+			// requeue all unacked messages
+			assert.NoError(t, s.Nack(0, true, true))
+		})
+	}
+}
+
+func TestSessionEnableConfirms(t *testing.T) {
+	t.Parallel()
+	var (
+		ctx              = context.TODO()
+		nextConnName     = testutils.ConnectionNameGenerator()
+		connName         = nextConnName()
+		nextSessionName  = testutils.SessionNameGenerator(connName)
+		sessionName      = nextSessionName()
+		nextQueueName    = testutils.QueueNameGenerator(sessionName)
+		queueName        = nextQueueName()
+		nextExchangeName = testutils.ExchangeNameGenerator(sessionName)
+		exchangeName     = nextExchangeName()
+	)
+
+	c, err := pool.NewConnection(
+		ctx,
+		testutils.HealthyConnectURL,
+		connName,
+		pool.ConnectionWithLogger(logging.NewTestLogger(t)),
+	)
+	if err != nil {
+		assert.NoError(t, err)
+		return
+	}
+	defer func() {
+		assert.NoError(t, c.Close())
+	}()
+
+	s, err := pool.NewSession(c, sessionName, pool.SessionWithConfirms(false))
+	if err != nil {
+		assert.NoError(t, err)
+		return
+	}
+	defer func() {
+		assert.NoError(t, s.Close())
+	}()
+
+	cleanup := DeclareExchangeQueue(t, ctx, s, exchangeName, queueName)
+	defer cleanup()
+
+	assert.False(t, s.IsConfirmable())
+	assert.ErrorIs(t, s.AwaitConfirm(ctx, 1), pool.ErrNoConfirms)
+
+	assert.NoError(t, s.EnableConfirms())
+	assert.True(t, s.IsConfirmable())
+
+	// no-op
+	assert.NoError(t, s.EnableConfirms())
+
+	tag, err := s.Publish(ctx, exchangeName, "", pool.Publishing{
+		ContentType: "text/plain",
+		Body:        []byte("confirmed message"),
+	})
+	if err != nil {
+		assert.NoError(t, err)
+		return
+	}
+	assert.Equal(t, uint64(1), tag)
+	assert.NoError(t, s.AwaitConfirm(ctx, tag))
+}
+
+func TestSessionPriorityQueue(t *testing.T) {
+	t.Parallel()
+	var (
+		ctx           = context.TODO()
+		nextConnName  = testutils.ConnectionNameGenerator()
+		connName      = nextConnName()
+		nextQueueName = testutils.QueueNameGenerator(connName)
+		queueName     = nextQueueName()
+	)
+
+	s, closer := NewSession(t, ctx, testutils.HealthyConnectURL, connName)
+	defer closer()
+
+	// priorities are only supported by classic queues
+	_, err := s.QueueDeclare(ctx, queueName, pool.QueueDeclareOptions{
+		Durable: true,
+		Args:    pool.QueueArgs(pool.QueueWithMaxPriority(5)),
+	})
+	if err != nil {
+		assert.NoError(t, err)
+		return
+	}
+	defer func() {
+		_, err := s.QueueDelete(ctx, queueName)
+		assert.NoError(t, err)
+	}()
+
+	for _, priority := range []uint8{3, 9} {
+		tag, err := s.Publish(ctx, "", queueName, pool.Publishing{
+			Priority: priority,
+			Body:     []byte("priority message"),
+		})
+		if err != nil {
+			assert.NoError(t, err)
+			return
+		}
+		assert.NoError(t, s.AwaitConfirm(ctx, tag))
+	}
+
+	// highest priority first, clamped to the maximum priority of the queue
+	for _, expected := range []uint8{5, 3} {
+		msg, ok, err := s.Get(ctx, queueName, true)
+		if err != nil {
+			assert.NoError(t, err)
+			return
+		}
+		assert.True(t, ok)
+		assert.Equal(t, expected, msg.Priority)
+	}
+}
+
+func TestSessionCancel(t *testing.T) {
+	t.Parallel()
+	var (
+		ctx                     = context.TODO()
+		nextConnName            = testutils.ConnectionNameGenerator()
+		connName                = nextConnName()
+		nextQueueName           = testutils.QueueNameGenerator(connName)
+		queueName               = nextQueueName()
+		nextExchangeName        = testutils.ExchangeNameGenerator(connName)
+		exchangeName            = nextExchangeName()
+		nextConsumerName        = testutils.ConsumerNameGenerator(queueName)
+		cancelledConsumer       = nextConsumerName()
+		activeConsumer          = nextConsumerName()
+		publishMessageGenerator = testutils.MessageGenerator(queueName)
+		numMsgs                 = 10
+	)
+
+	s, closer := NewSession(t, ctx, testutils.HealthyConnectURL, connName)
+	defer closer()
+
+	cleanup := DeclareExchangeQueue(t, ctx, s, exchangeName, queueName)
+	defer cleanup()
+
+	cancelled, err := s.Consume(queueName, pool.ConsumeOptions{ConsumerTag: cancelledConsumer, AutoAck: true})
+	if err != nil {
+		assert.NoError(t, err)
+		return
+	}
+	active, err := s.Consume(queueName, pool.ConsumeOptions{ConsumerTag: activeConsumer, AutoAck: true})
+	if err != nil {
+		assert.NoError(t, err)
+		return
+	}
+
+	assert.NoError(t, s.Cancel(cancelledConsumer, false))
+
+	// the delivery chan of the cancelled consumer is closed after all in-flight deliveries were received
+	timeout := time.After(5 * time.Second)
+drain:
+	for {
+		select {
+		case _, ok := <-cancelled:
+			if !ok {
+				break drain
+			}
+		case <-timeout:
+			assert.Fail(t, "expected delivery chan of cancelled consumer to be closed")
+			return
+		}
+	}
+
+	PublishN(t, ctx, s, exchangeName, publishMessageGenerator, numMsgs)
+
+	for i := 0; i < numMsgs; i++ {
+		select {
+		case msg, ok := <-active:
+			if !ok {
+				assert.Fail(t, "expected delivery chan of active consumer to be open")
+				return
+			}
+			assert.Equal(t, activeConsumer, msg.ConsumerTag)
+		case <-time.After(5 * time.Second):
+			assert.Failf(t, "timeout", "expected %d messages, got %d", numMsgs, i)
+			return
+		}
+	}
+}
+
+func TestSessionRecoverRestoresState(t *testing.T) {
+	t.Parallel()
+	var (
+		ctx                     = context.TODO()
+		nextConnName            = testutils.ConnectionNameGenerator()
+		connName                = nextConnName()
+		nextQueueName           = testutils.QueueNameGenerator(connName)
+		queueName               = nextQueueName()
+		nextExchangeName        = testutils.ExchangeNameGenerator(connName)
+		exchangeName            = nextExchangeName()
+		nextConsumerName        = testutils.ConsumerNameGenerator(queueName)
+		consumerName            = nextConsumerName()
+		publishMessageGenerator = testutils.MessageGenerator(queueName)
+		prefetchCount           = 2
+		numMsgs                 = 5
+	)
+
+	s, closer := NewSession(t, ctx, testutils.HealthyConnectURL, connName)
+	defer closer()
+
+	cleanup := DeclareExchangeQueue(t, ctx, s, exchangeName, queueName)
+	defer cleanup()
+
+	require.NoError(t, s.EnableConfirms())
+	require.NoError(t, s.Qos(ctx, prefetchCount, 0))
+
+	delivery, err := s.Consume(queueName, pool.ConsumeOptions{ConsumerTag: consumerName})
+	require.NoError(t, err)
+
+	// a passive declaration of a missing queue closes the channel
+	_, err = s.QueueDeclarePassive(ctx, nextQueueName())
+	require.ErrorIs(t, err, pool.ErrNotFound)
+	require.NoError(t, s.Recover(ctx))
+
+	// confirm mode is restored
+	assert.True(t, s.IsConfirmable())
+	PublishN(t, ctx, s, exchangeName, publishMessageGenerator, numMsgs)
+
+	// the consumer subscription is restored
+	q, err := s.QueueDeclarePassive(ctx, queueName)
+	require.NoError(t, err)
+	assert.Equal(t, 1, q.Consumers)
+
+	// the prefetch limit is restored, no message is acked, so the broker stops delivering
+	// once the prefetch limit is reached. The delivery chan stays open across the recovery.
+	var (
+		received []pool.Delivery
+		timeout  = time.After(2 * time.Second)
+	)
+loop:
+	for {
+		select {
+		case msg, ok := <-delivery:
+			require.True(t, ok, "expected delivery chan to stay open after recovery")
+			assert.Equal(t, consumerName, msg.ConsumerTag)
+			received = append(received, msg)
+		case <-timeout:
+			break loop
+		}
+	}
+	require.Len(t, received, prefetchCount)
+
+	// deliveries of the new channel can be acked
+	for _, msg := range received {
+		assert.NoError(t, msg.Ack(false))
+	}
+}
+
+func TestSessionAckBatch(t *testing.T) {
+	t.Parallel()
+	var (
+		ctx          = context.TODO()
+		nextConnName = testutils.ConnectionNameGenerator()
+		numMsgs      = 5
+	)
+
+	hs, hsclose := NewSession(t, ctx, testutils.HealthyConnectURL, nextConnName())
+	defer hsclose()
+
+	var (
+		sessions  = make([]*pool.Session, 2)
+		closers   = make([]func(), 0, 2)
+		queues    = make([]string, 2)
+		batch     []pool.Delivery
+		exchanges = testutils.ExchangeNameGenerator(hs.Name())
+		queueGen  = testutils.QueueNameGenerator(hs.Name())
+	)
+
+	closeSessions := func() {
+		for _, closer := range closers {
+			closer()
+		}
+		closers = nil
+	}
+	defer closeSessions()
+
+	for i := range sessions {
+		s, closer := NewSession(t, ctx, testutils.HealthyConnectURL, nextConnName())
+		closers = append(closers, closer)
+		sessions[i] = s
+
+		exchangeName, queueName := exchanges(), queueGen()
+		cleanup := DeclareExchangeQueue(t, ctx, hs, exchangeName, queueName)
+		defer cleanup()
+		queues[i] = queueName
+
+		PublishN(t, ctx, hs, exchangeName, testutils.MessageGenerator(queueName), numMsgs)
+
+		delivery, err := s.Consume(queueName, pool.ConsumeOptions{ConsumerTag: testutils.ConsumerNameGenerator(queueName)()})
+		require.NoError(t, err)
+
+		for j := 0; j < numMsgs; j++ {
+			select {
+			case msg := <-delivery:
+				batch = append(batch, msg)
+			case <-time.After(5 * time.Second):
+				require.FailNow(t, "timeout while waiting for message")
+			}
+		}
+	}
+
+	// both sessions use the same delivery tags, a cross session ack must not reach the broker
+	err := sessions[0].AckBatch(batch)
+	assert.ErrorIs(t, err, pool.ErrForeignDelivery)
+
+	// acks are routed to the session that received the message
+	require.NoError(t, pool.AckBatch(batch))
+
+	// unacked messages would be requeued when the sessions are closed
+	closeSessions()
+	for _, queueName := range queues {
+		q, err := hs.QueueDeclarePassive(ctx, queueName)
+		require.NoError(t, err)
+		assert.Equal(t, 0, q.Messages)
+	}
+}
+
+func TestSessionWithTx(t *testing.T) {
+	t.Parallel()
+	var (
+		ctx              = context.TODO()
+		nextConnName     = testutils.ConnectionNameGenerator()
+		connName         = nextConnName()
+		nextSessionName  = testutils.SessionNameGenerator(connName)
+		sessionName      = nextSessionName()
+		nextQueueName    = testutils.QueueNameGenerator(sessionName)
+		queueName        = nextQueueName()
+		nextExchangeName = testutils.ExchangeNameGenerator(sessionName)
+		exchangeName     = nextExchangeName()
+		errFail          = errors.New("failed")
+	)
+
+	c, err := pool.NewConnection(
+		ctx,
+		testutils.HealthyConnectURL,
+		connName,
+		pool.ConnectionWithLogger(logging.NewTestLogger(t)),
+	)
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, c.Close())
+	}()
+
+	s, err := pool.NewSession(c, sessionName, pool.SessionWithConfirms(false))
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, s.Close())
+	}()
+
+	cleanup := DeclareExchangeQueue(t, ctx, s, exchangeName, queueName)
+	defer cleanup()
+
+	publish := func(tx *pool.Tx) error {
+		return tx.Publish(ctx, exchangeName, "", pool.Publishing{
+			ContentType: "text/plain",
+			Body:        []byte("transactional message"),
+		})
+	}
+	messages := func() int {
+		q, err := s.QueueDeclarePassive(ctx, queueName)
+		require.NoError(t, err)
+		return q.Messages
+	}
+
+	// commit
+	err = s.WithTx(ctx, func(tx *pool.Tx) error {
+		require.NoError(t, publish(tx))
+		return publish(tx)
+	})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return messages() == 2 }, 5*time.Second, 100*time.Millisecond)
+
+	// rollback on error
+	err = s.WithTx(ctx, func(tx *pool.Tx) error {
+		require.NoError(t, publish(tx))
+		return errFail
+	})
+	assert.ErrorIs(t, err, errFail)
+
+	// rollback on context cancelation
+	cctx, cancel := context.WithCancel(ctx)
+	err = s.WithTx(cctx, func(tx *pool.Tx) error {
+		require.NoError(t, publish(tx))
+		cancel()
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// a channel closed during the transaction aborts the transaction
+	err = s.WithTx(ctx, func(tx *pool.Tx) error {
+		require.NoError(t, publish(tx))
+		_, err := s.QueueDeclarePassive(ctx, nextQueueName())
+		require.ErrorIs(t, err, pool.ErrNotFound)
+		return publish(tx)
+	})
+	assert.ErrorIs(t, err, pool.ErrTxAborted)
+
+	// the session is recovered in transaction mode, rolled back messages were never routed
+	require.NoError(t, s.Recover(ctx))
+	assert.Equal(t, 2, messages())
+	require.NoError(t, s.WithTx(ctx, publish))
+	assert.Eventually(t, func() bool { return messages() == 3 }, 5*time.Second, 100*time.Millisecond)
+
+	// transactions are not supported in confirm mode
+	require.NoError(t, s.EnableConfirms())
+	assert.ErrorIs(t, s.WithTx(ctx, publish), pool.ErrTxConfirmMode)
+}
+
+func TestSessionWaitConfirms(t *testing.T) {
+	t.Parallel()
+	var (
+		ctx                     = context.TODO()
+		nextConnName            = testutils.ConnectionNameGenerator()
+		connName                = nextConnName()
+		nextQueueName           = testutils.QueueNameGenerator(connName)
+		queueName               = nextQueueName()
+		nextExchangeName        = testutils.ExchangeNameGenerator(connName)
+		exchangeName            = nextExchangeName()
+		publishMessageGenerator = testutils.MessageGenerator(queueName)
+		numMsgs                 = 5
+	)
+
+	s, closer := NewSession(t, ctx, testutils.HealthyConnectURL, connName)
+	defer closer()
+
+	cleanup := DeclareExchangeQueue(t, ctx, s, exchangeName, queueName)
+	defer cleanup()
+
+	tags := make([]uint64, 0, numMsgs)
+	for i := 0; i < numMsgs; i++ {
+		tag, err := s.Publish(ctx, exchangeName, "", pool.Publishing{
+			ContentType: "text/plain",
+			Body:        []byte(publishMessageGenerator()),
+		})
+		require.NoError(t, err)
+		tags = append(tags, tag)
+	}
+	assert.LessOrEqual(t, s.OutstandingConfirms(), numMsgs)
+
+	wctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	require.NoError(t, s.WaitConfirms(wctx))
+	assert.Equal(t, 0, s.OutstandingConfirms())
+
+	// the confirmations can still be awaited
+	for _, tag := range tags {
+		assert.NoError(t, s.AwaitConfirm(ctx, tag))
+	}
+	assert.Equal(t, 0, s.OutstandingConfirms())
+}
+
+func TestSessionPublishWithCCAndBCC(t *testing.T) {
+	t.Parallel()
+	var (
+		ctx           = context.TODO()
+		nextConnName  = testutils.ConnectionNameGenerator()
+		connName      = nextConnName()
+		nextQueueName = testutils.QueueNameGenerator(connName)
+		queueName     = nextQueueName()
+		ccQueueName   = nextQueueName()
+		bccQueueName  = nextQueueName()
+	)
+
+	s, closer := NewSession(t, ctx, testutils.HealthyConnectURL, connName)
+	defer closer()
+
+	for _, name := range []string{queueName, ccQueueName, bccQueueName} {
+		_, err := s.QueueDeclare(ctx, name)
+		require.NoError(t, err)
+		defer func(name string) {
+			_, err := s.QueueDelete(ctx, name)
+			assert.NoError(t, err)
+		}(name)
+	}
+
+	// the default exchange routes messages to the queue with the name of the routing key
+	tag, err := s.Publish(ctx, "", queueName, pool.Publishing{
+		ContentType: "text/plain",
+		Body:        []byte("audited message"),
+		Headers: pool.PublishingHeaders(
+			pool.PublishingWithCC(ccQueueName),
+			pool.PublishingWithBCC(bccQueueName),
+		),
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.AwaitConfirm(ctx, tag))
+
+	for _, name := range []string{queueName, ccQueueName, bccQueueName} {
+		var (
+			msg pool.Delivery
+			ok  bool
+		)
+		require.Eventually(t, func() bool {
+			msg, ok, err = s.Get(ctx, name, true)
+			require.NoError(t, err)
+			return ok
+		}, 5*time.Second, 100*time.Millisecond, "expected message in queue %s", name)
+
+		assert.Equal(t, "audited message", string(msg.Body))
+		assert.Equal(t, []any{ccQueueName}, msg.Headers[pool.HeaderCC])
+		_, ok = msg.Headers[pool.HeaderBCC]
+		assert.False(t, ok, "BCC header must be removed by the broker")
+	}
+
+	// invalid headers are rejected before publishing
+	_, err = s.Publish(ctx, "", queueName, pool.Publishing{
+		Body:    []byte("invalid"),
+		Headers: pool.Table{pool.HeaderCC: ccQueueName},
+	})
+	assert.ErrorIs(t, err, pool.ErrInvalidHeader)
+}
+
+func TestSessionReset(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx           = context.TODO()
+		nextConnName  = testutils.ConnectionNameGenerator()
+		connName      = nextConnName()
+		nextQueueName = testutils.QueueNameGenerator(connName)
+		queueName     = nextQueueName()
+		prefetchCount = 3
+	)
+
+	s, closer := NewSession(t, ctx, testutils.HealthyConnectURL, connName)
+	defer closer()
+
+	_, err := s.QueueDeclare(ctx, queueName)
+	require.NoError(t, err)
+	defer func() {
+		_, err := s.QueueDelete(ctx, queueName)
+		assert.NoError(t, err)
+	}()
+	require.NoError(t, s.Qos(ctx, prefetchCount, 0))
+
+	_, err = s.Consume(queueName, pool.ConsumeOptions{ConsumerTag: testutils.ConsumerNameGenerator(queueName)()})
+	require.NoError(t, err)
+
+	// a passive declaration of a missing queue closes the channel, but not the connection
+	_, err = s.QueueDeclarePassive(ctx, nextQueueName())
+	require.ErrorIs(t, err, pool.ErrNotFound)
+	require.NoError(t, s.Reset(ctx))
+
+	// the session is usable again with its settings and consumers restored
+	assert.True(t, s.IsConfirmable())
+	tag, err := s.Publish(ctx, "", queueName, pool.Publishing{Body: []byte("reset")})
+	require.NoError(t, err)
+	require.NoError(t, s.AwaitConfirm(ctx, tag))
+
+	q, err := s.QueueDeclarePassive(ctx, queueName)
+	require.NoError(t, err)
+	assert.Equal(t, 1, q.Consumers)
+}
+
+func TestSessionGetOne(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx           = context.TODO()
+		nextConnName  = testutils.ConnectionNameGenerator()
+		connName      = nextConnName()
+		nextQueueName = testutils.QueueNameGenerator(connName)
+		queueName     = nextQueueName()
+	)
+
+	s, closer := NewSession(t, ctx, testutils.HealthyConnectURL, connName)
+	defer closer()
+
+	_, err := s.QueueDeclare(ctx, queueName)
+	require.NoError(t, err)
+	defer func() {
+		_, err := s.QueueDelete(ctx, queueName)
+		assert.NoError(t, err)
+	}()
+
+	// an empty queue is not an error
+	msg, err := s.GetOne(ctx, queueName, false)
+	require.NoError(t, err)
+	assert.Nil(t, msg)
+
+	for i := 0; i < 2; i++ {
+		tag, err := s.Publish(ctx, "", queueName, pool.Publishing{Body: []byte("get one")})
+		require.NoError(t, err)
+		require.NoError(t, s.AwaitConfirm(ctx, tag))
+	}
+
+	msg, err = s.GetOne(ctx, queueName, false)
+	require.NoError(t, err)
+	require.NotNil(t, msg)
+	assert.Equal(t, "get one", string(msg.Body))
+	assert.Equal(t, s.Epoch(), pool.DeliveryEpoch(msg.DeliveryTag))
+	assert.NoError(t, msg.Ack(false))
+
+	msg, err = s.GetOne(ctx, queueName, false)
+	require.NoError(t, err)
+	require.NotNil(t, msg)
+
+	// messages that were fetched before a recovery cannot be acked anymore
+	require.NoError(t, s.Reset(ctx))
+	assert.NotEqual(t, s.Epoch(), pool.DeliveryEpoch(msg.DeliveryTag))
+	assert.ErrorIs(t, msg.Ack(false), pool.ErrStaleDeliveryTag)
+}
+
+func TestSessionReject(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx            = context.TODO()
+		nextConnName   = testutils.ConnectionNameGenerator()
+		connName       = nextConnName()
+		nextQueueName  = testutils.QueueNameGenerator(connName)
+		queueName      = nextQueueName()
+		deadLetterName = nextQueueName()
+	)
+
+	s, closer := NewSession(t, ctx, testutils.HealthyConnectURL, connName)
+	defer closer()
+
+	_, err := s.QueueDeclare(ctx, deadLetterName)
+	require.NoError(t, err)
+	defer func() {
+		_, err := s.QueueDelete(ctx, deadLetterName)
+		assert.NoError(t, err)
+	}()
+
+	_, err = s.QueueDeclare(ctx, queueName, pool.QueueDeclareOptions{
+		Durable: true,
+		Args: pool.Table{
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": deadLetterName,
+		},
+	})
+	require.NoError(t, err)
+	defer func() {
+		_, err := s.QueueDelete(ctx, queueName)
+		assert.NoError(t, err)
+	}()
+
+	tag, err := s.Publish(ctx, "", queueName, pool.Publishing{Body: []byte("reject")})
+	require.NoError(t, err)
+	require.NoError(t, s.AwaitConfirm(ctx, tag))
+
+	// requeued messages are redelivered
+	msg, err := s.GetOne(ctx, queueName, false)
+	require.NoError(t, err)
+	require.NotNil(t, msg)
+	assert.False(t, msg.Redelivered)
+	require.NoError(t, s.Reject(msg.DeliveryTag, true))
+
+	msg, err = s.GetOne(ctx, queueName, false)
+	require.NoError(t, err)
+	require.NotNil(t, msg)
+	assert.True(t, msg.Redelivered)
+
+	// messages that are not requeued are dead-lettered
+	require.NoError(t, s.Reject(msg.DeliveryTag, false))
+
+	require.Eventually(t, func() bool {
+		q, err := s.QueueDeclarePassive(ctx, deadLetterName)
+		return err == nil && q.Messages == 1
+	}, 5*time.Second, 50*time.Millisecond)
+
+	msg, err = s.GetOne(ctx, queueName, false)
+	require.NoError(t, err)
+	assert.Nil(t, msg)
+
+	msg, err = s.GetOne(ctx, deadLetterName, true)
+	require.NoError(t, err)
+	require.NotNil(t, msg)
+	assert.Equal(t, "reject", string(msg.Body))
+
+	// stale deliveries cannot be rejected
+	require.NoError(t, s.Reset(ctx))
+	assert.ErrorIs(t, s.Reject(msg.DeliveryTag, true), pool.ErrStaleDeliveryTag)
+}
+
+func TestSessionConsumeN(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx           = context.TODO()
+		nextConnName  = testutils.ConnectionNameGenerator()
+		connName      = nextConnName()
+		nextQueueName = testutils.QueueNameGenerator(connName)
+		queueName     = nextQueueName()
+		published     = 5
+		consumed      = 3
+	)
+
+	s, closer := NewSession(t, ctx, testutils.HealthyConnectURL, connName)
+	defer closer()
+
+	_, err := s.QueueDeclare(ctx, queueName)
+	require.NoError(t, err)
+	defer func() {
+		_, err := s.QueueDelete(ctx, queueName)
+		assert.NoError(t, err)
+	}()
+
+	err = s.ConsumeN(ctx, queueName, 0, func(ctx context.Context, d pool.Delivery) error { return nil })
+	assert.Error(t, err)
+
+	for i := 0; i < published; i++ {
+		tag, err := s.Publish(ctx, "", queueName, pool.Publishing{Body: []byte(fmt.Sprintf("message %d", i))})
+		require.NoError(t, err)
+		require.NoError(t, s.AwaitConfirm(ctx, tag))
+	}
+
+	var (
+		handled = make([]string, 0, consumed)
+		failed  = false
+	)
+	err = s.ConsumeN(ctx, queueName, consumed, func(ctx context.Context, d pool.Delivery) error {
+		if !failed {
+			// failed messages are requeued and do not count
+			failed = true
+			return errors.New("failed once")
+		}
+		handled = append(handled, string(d.Body))
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Len(t, handled, consumed)
+
+	// all prefetched but unhandled messages were requeued
+	remaining := make([]string, 0, published-consumed)
+	for {
+		msg, err := s.GetOne(ctx, queueName, false)
+		require.NoError(t, err)
+		if msg == nil {
+			break
+		}
+		remaining = append(remaining, string(msg.Body))
+		require.NoError(t, msg.Ack(false))
+	}
+	assert.Len(t, remaining, published-consumed)
+	assert.ElementsMatch(t, []string{"message 0", "message 1", "message 2", "message 3", "message 4"}, append(handled, remaining...))
+
+	// the consumer was canceled, the session can consume again
+	timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	err = s.ConsumeN(timeoutCtx, queueName, 1, func(ctx context.Context, d pool.Delivery) error { return nil })
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestSessionConsumerTagPrefix(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx           = context.TODO()
+		nextConnName  = testutils.ConnectionNameGenerator()
+		connName      = nextConnName()
+		nextQueueName = testutils.QueueNameGenerator(connName)
+		queueName     = nextQueueName()
+		prefix        = "worker-pod-abc"
+	)
+
+	s, closer := NewSession(t, ctx, testutils.HealthyConnectURL, connName)
+	defer closer()
+
+	_, err := s.QueueDeclare(ctx, queueName)
+	require.NoError(t, err)
+	defer func() {
+		_, err := s.QueueDelete(ctx, queueName)
+		assert.NoError(t, err)
+	}()
+
+	delivery, err := s.Consume(queueName, pool.ConsumeOptions{ConsumerTagPrefix: prefix, AutoAck: true})
+	require.NoError(t, err)
+
+	receive := func(body string) pool.Delivery {
+		tag, err := s.Publish(ctx, "", queueName, pool.Publishing{Body: []byte(body)})
+		require.NoError(t, err)
+		require.NoError(t, s.AwaitConfirm(ctx, tag))
+
+		select {
+		case msg, ok := <-delivery:
+			require.True(t, ok)
+			assert.Equal(t, body, string(msg.Body))
+			return msg
+		case <-time.After(10 * time.Second):
+			require.Fail(t, "timed out waiting for message", body)
+			return pool.Delivery{}
+		}
+	}
+
+	msg := receive("before")
+	assert.Equal(t, prefix+"-"+s.Name(), msg.ConsumerTag)
+
+	// the restored consumer reuses its tag
+	_, err = s.QueueDeclarePassive(ctx, nextQueueName())
+	require.ErrorIs(t, err, pool.ErrNotFound)
+	require.NoError(t, s.Reset(ctx))
+
+	msg = receive("after")
+	assert.Equal(t, prefix+"-"+s.Name(), msg.ConsumerTag)
+
+	// too long prefixes are rejected
+	_, err = s.Consume(queueName, pool.ConsumeOptions{ConsumerTagPrefix: strings.Repeat("p", 256)})
+	assert.ErrorIs(t, err, pool.ErrInvalidConsumerTag)
+}
+
+func TestSessionSingleActiveConsumer(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx           = context.TODO()
+		nextConnName  = testutils.ConnectionNameGenerator()
+		connName      = nextConnName()
+		nextQueueName = testutils.QueueNameGenerator(connName)
+		queueName     = nextQueueName()
+	)
+
+	active, closeActive := NewSession(t, ctx, testutils.HealthyConnectURL, nextConnName())
+	defer closeActive()
+	standby, closeStandby := NewSession(t, ctx, testutils.HealthyConnectURL, nextConnName())
+	defer closeStandby()
+	publisher, closePublisher := NewSession(t, ctx, testutils.HealthyConnectURL, connName)
+	defer closePublisher()
+
+	_, err := publisher.QueueDeclare(ctx, queueName, pool.QueueDeclareOptions{
+		Durable: true,
+		Args:    pool.QueueArgs(pool.QueueWithSingleActiveConsumer(true)),
+	})
+	require.NoError(t, err)
+	defer func() {
+		_, err := publisher.QueueDelete(ctx, queueName)
+		assert.NoError(t, err)
+	}()
+
+	// the first consumer becomes the active one
+	activeDelivery, err := active.Consume(queueName, pool.ConsumeOptions{ConsumerTag: "active", AutoAck: true})
+	require.NoError(t, err)
+	standbyDelivery, err := standby.Consume(queueName, pool.ConsumeOptions{ConsumerTag: "standby", AutoAck: true})
+	require.NoError(t, err)
+
+	publish := func(n int) {
+		for i := 0; i < n; i++ {
+			tag, err := publisher.Publish(ctx, "", queueName, pool.Publishing{Body: []byte(fmt.Sprintf("message %d", i))})
+			require.NoError(t, err)
+			require.NoError(t, publisher.AwaitConfirm(ctx, tag))
+		}
+	}
+	receive := func(delivery <-chan pool.Delivery, n int) {
+		for i := 0; i < n; i++ {
+			select {
+			case _, ok := <-delivery:
+				require.True(t, ok)
+			case <-time.After(10 * time.Second):
+				require.Failf(t, "timed out waiting for message", "received %d of %d messages", i, n)
+			}
+		}
+	}
+	idle := func(delivery <-chan pool.Delivery) {
+		select {
+		case msg := <-delivery:
+			assert.Failf(t, "standby consumer received a message", "%s", string(msg.Body))
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+
+	publish(10)
+	receive(activeDelivery, 10)
+	idle(standbyDelivery)
+
+	// the standby consumer takes over as soon as the active consumer is canceled
+	require.NoError(t, active.Cancel("active", false))
+	publish(10)
+	receive(standbyDelivery, 10)
+}
+
+func TestSessionConfirmNoWait(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx           = context.TODO()
+		nextConnName  = testutils.ConnectionNameGenerator()
+		connName      = nextConnName()
+		nextQueueName = testutils.QueueNameGenerator(connName)
+		queueName     = nextQueueName()
+		log           = logging.NewTestLogger(t)
+	)
+
+	c, err := pool.NewConnection(ctx, testutils.HealthyConnectURL, connName, pool.ConnectionWithLogger(log))
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, c.Close())
+	}()
+
+	s, err := pool.NewSession(c, testutils.SessionNameGenerator(connName)(),
+		pool.SessionWithLogger(log),
+		pool.SessionWithConfirms(true),
+		pool.SessionWithConfirmNoWait(true),
+	)
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, s.Close())
+	}()
+	assert.True(t, s.IsConfirmable())
+
+	_, err = s.QueueDeclare(ctx, queueName)
+	require.NoError(t, err)
+	defer func() {
+		_, err := s.QueueDelete(ctx, queueName)
+		assert.NoError(t, err)
+	}()
+
+	publish := func(body string) {
+		tag, err := s.Publish(ctx, "", queueName, pool.Publishing{Body: []byte(body)})
+		require.NoError(t, err)
+		require.NoError(t, s.AwaitConfirm(ctx, tag))
+	}
+	publish("confirmed")
+
+	// confirm mode is re-enabled without waiting upon recovery
+	_, err = s.QueueDeclarePassive(ctx, nextQueueName())
+	require.ErrorIs(t, err, pool.ErrNotFound)
+	publish("recovered")
+
+	q, err := s.QueueDeclarePassive(ctx, queueName)
+	require.NoError(t, err)
+	assert.Equal(t, 2, q.Messages)
+}
+
+func TestConfirmGroup(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx           = context.TODO()
+		nextConnName  = testutils.ConnectionNameGenerator()
+		connName      = nextConnName()
+		nextSessName  = testutils.SessionNameGenerator(connName)
+		nextQueueName = testutils.QueueNameGenerator(connName)
+		queueName     = nextQueueName()
+		log           = logging.NewTestLogger(t)
+		numMsgs       = 50
+	)
+
+	c, err := pool.NewConnection(ctx, testutils.HealthyConnectURL, connName, pool.ConnectionWithLogger(log))
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, c.Close())
+	}()
+
+	sessions := make([]*pool.Session, 0, 2)
+	for i := 0; i < 2; i++ {
+		s, err := pool.NewSession(c, nextSessName(), pool.SessionWithLogger(log), pool.SessionWithConfirms(true))
+		require.NoError(t, err)
+		defer func() {
+			assert.NoError(t, s.Close())
+		}()
+		sessions = append(sessions, s)
+	}
+
+	_, err = sessions[0].QueueDeclare(ctx, queueName)
+	require.NoError(t, err)
+	defer func() {
+		_, err := sessions[0].QueueDelete(ctx, queueName)
+		assert.NoError(t, err)
+	}()
+
+	g := pool.NewConfirmGroup()
+	var wg sync.WaitGroup
+	for _, s := range sessions {
+		wg.Add(1)
+		go func(s *pool.Session) {
+			defer wg.Done()
+			for i := 0; i < numMsgs; i++ {
+				_, err := g.Publish(ctx, s, "", queueName, pool.Publishing{Body: []byte(fmt.Sprintf("%s-%d", s.Name(), i))})
+				assert.NoError(t, err)
+			}
+		}(s)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 2*numMsgs, g.Len())
+	require.NoError(t, g.Wait(ctx))
+	assert.Equal(t, 0, g.Len())
+
+	q, err := sessions[0].QueueDeclarePassive(ctx, queueName)
+	require.NoError(t, err)
+	assert.Equal(t, 2*numMsgs, q.Messages)
+
+	// a session that is recovered after publishing invalidates its pending confirmations
+	_, err = g.Publish(ctx, sessions[1], "", queueName, pool.Publishing{Body: []byte("lost")})
+	require.NoError(t, err)
+	_, err = sessions[1].QueueDeclarePassive(ctx, nextQueueName())
+	require.ErrorIs(t, err, pool.ErrNotFound)
+	assert.ErrorIs(t, g.Wait(ctx), pool.ErrConfirmLost)
+}
+
+func TestSessionInternalExchange(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx              = context.TODO()
+		nextConnName     = testutils.ConnectionNameGenerator()
+		connName         = nextConnName()
+		sessionName      = testutils.SessionNameGenerator(connName)()
+		nextExchangeName = testutils.ExchangeNameGenerator(sessionName)
+		exchangeName     = nextExchangeName()
+		log              = logging.NewTestLogger(t)
+	)
+
+	c, err := pool.NewConnection(ctx, testutils.HealthyConnectURL, connName, pool.ConnectionWithLogger(log))
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, c.Close())
+	}()
+
+	s, err := pool.NewSession(c, sessionName, pool.SessionWithLogger(log), pool.SessionWithConfirms(true))
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, s.Close())
+	}()
+
+	internal := pool.ExchangeOptions(pool.ExchangeWithInternal(true))
+	require.True(t, internal.Internal)
+
+	err = s.ExchangeDeclare(ctx, exchangeName, pool.ExchangeKindTopic, internal)
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, s.ExchangeDelete(ctx, exchangeName))
+	}()
+
+	// the broker verifies the internal flag of existing exchanges
+	assert.NoError(t, s.ExchangeDeclare(ctx, exchangeName, pool.ExchangeKindTopic, internal))
+	assert.Error(t, s.ExchangeDeclare(ctx, exchangeName, pool.ExchangeKindTopic, pool.ExchangeOptions()))
+
+	// internal exchanges do not accept publishings
+	tag, err := s.Publish(ctx, exchangeName, "key", pool.Publishing{Body: []byte("refused")})
+	if err == nil {
+		err = s.AwaitConfirm(ctx, tag)
+	}
+	assert.Error(t, err)
+}
+
+func TestSessionNotifyConfirm(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx           = context.TODO()
+		nextConnName  = testutils.ConnectionNameGenerator()
+		connName      = nextConnName()
+		nextSessName  = testutils.SessionNameGenerator(connName)
+		nextQueueName = testutils.QueueNameGenerator(connName)
+		queueName     = nextQueueName()
+		log           = logging.NewTestLogger(t)
+		numMsgs       = 20
+	)
+
+	c, err := pool.NewConnection(ctx, testutils.HealthyConnectURL, connName, pool.ConnectionWithLogger(log))
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, c.Close())
+	}()
+
+	s, err := pool.NewSession(c, nextSessName(), pool.SessionWithLogger(log), pool.SessionWithConfirms(true))
+	require.NoError(t, err)
+
+	_, err = s.QueueDeclare(ctx, queueName)
+	require.NoError(t, err)
+
+	notifications := s.NotifyConfirm()
+	for i := 0; i < numMsgs; i++ {
+		tag, err := s.Publish(ctx, "", queueName, pool.Publishing{Body: []byte(fmt.Sprintf("message-%d", i))})
+		require.NoError(t, err)
+
+		// awaiting the confirmation does not consume the notification
+		require.NoError(t, s.AwaitConfirm(ctx, tag))
+
+		select {
+		case n := <-notifications:
+			assert.Equal(t, tag, n.DeliveryTag)
+			assert.True(t, n.Ack)
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "confirmation was not notified", "delivery tag %d", tag)
+		}
+	}
+
+	// every publish produces exactly one notification
+	select {
+	case n := <-notifications:
+		assert.Failf(t, "unexpected notification", "delivery tag %d", n.DeliveryTag)
+	default:
+	}
+
+	_, err = s.QueueDelete(ctx, queueName)
+	assert.NoError(t, err)
+
+	require.NoError(t, s.Close())
+	_, ok := <-notifications
+	assert.False(t, ok, "notification channel must be closed with the session")
+}
@@ -0,0 +1,178 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Tx is a transaction of a session that is started by Session.WithTx.
+// A transaction is only valid within the callback that was passed to Session.WithTx.
+type Tx struct {
+	s          *Session
+	generation uint64
+}
+
+// WithTx puts the session into transaction mode, runs the callback and commits all publishings and acknowledgements
+// of the transaction in case the callback returns nil.
+// The transaction is rolled back in case the callback returns an error or the context is canceled.
+// In case the channel of the session is closed or recovered during the transaction, all uncommitted publishings and
+// acknowledgements are discarded by the broker and ErrTxAborted is returned, in which case the whole transaction
+// should be retried.
+//
+// Once a session has been put into transaction mode, it cannot be taken out of transaction mode, which includes recoveries.
+// Publishings and acknowledgements of the session outside of the callback are not transactional and thus
+// become part of the next transaction.
+// Sessions in confirm mode do not support transactions, ErrTxConfirmMode is returned.
+func (s *Session) WithTx(ctx context.Context, f func(tx *Tx) error) (err error) {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	err = f(tx)
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if verr := tx.valid(); verr != nil {
+		if err != nil {
+			return fmt.Errorf("%w: %w", verr, err)
+		}
+		return verr
+	}
+
+	if err != nil {
+		rerr := s.channel.TxRollback()
+		if rerr != nil {
+			return errors.Join(err, tx.abortedErr(fmt.Errorf("failed to roll back transaction: %w", rerr)))
+		}
+		return err
+	}
+
+	err = s.channel.TxCommit()
+	if err != nil {
+		return tx.abortedErr(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+	return nil
+}
+
+// beginTx puts the channel into transaction mode, in case it is not transactional yet.
+func (s *Session) beginTx(ctx context.Context) (*Tx, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.confirmable {
+		return nil, ErrTxConfirmMode
+	}
+
+	err := s.retry(ctx, nil, func() error {
+		if s.transactional {
+			// re-applied upon recovery
+			return nil
+		}
+		return s.channel.Tx()
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.transactional = true
+
+	return &Tx{
+		s:          s,
+		generation: s.generation(),
+	}, nil
+}
+
+// Publish sends a Publishing to an exchange as part of the transaction.
+// The message is routed as soon as the transaction is committed.
+// Publishings are not retried, as a channel recovery aborts the transaction.
+func (tx *Tx) Publish(ctx context.Context, exchange string, routingKey string, msg Publishing) error {
+	err := tx.s.publishLimiter.wait(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+
+	tx.s.mu.Lock()
+	defer tx.s.mu.Unlock()
+
+	err = tx.s.validatePublishing(msg)
+	if err != nil {
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+
+	err = tx.valid()
+	if err != nil {
+		return err
+	}
+
+	err = tx.s.channel.PublishWithContext(
+		ctx,
+		exchange,
+		routingKey,
+		msg.Mandatory,
+		msg.Immediate,
+		tx.s.publishing(exchange, routingKey, msg),
+	)
+	if err != nil {
+		return tx.abortedErr(fmt.Errorf("failed to publish message: %w", err))
+	}
+	return nil
+}
+
+// Ack acknowledges a delivery of the session as part of the transaction.
+func (tx *Tx) Ack(deliveryTag uint64, multiple bool) error {
+	tx.s.mu.Lock()
+	defer tx.s.mu.Unlock()
+
+	tag, err := tx.channelDeliveryTag(deliveryTag)
+	if err != nil {
+		return fmt.Errorf("failed to ack message: %w", err)
+	}
+	return tx.abortedErr(tx.s.channel.Ack(tag, multiple))
+}
+
+// Nack negatively acknowledges a delivery of the session as part of the transaction.
+func (tx *Tx) Nack(deliveryTag uint64, multiple bool, requeue bool) error {
+	tx.s.mu.Lock()
+	defer tx.s.mu.Unlock()
+
+	tag, err := tx.channelDeliveryTag(deliveryTag)
+	if err != nil {
+		return fmt.Errorf("failed to nack message: %w", err)
+	}
+	return tx.abortedErr(tx.s.channel.Nack(tag, multiple, requeue))
+}
+
+// not threadsafe
+func (tx *Tx) channelDeliveryTag(deliveryTag uint64) (uint64, error) {
+	err := tx.valid()
+	if err != nil {
+		return 0, err
+	}
+	return tx.s.channelDeliveryTag(deliveryTag)
+}
+
+// valid returns ErrTxAborted in case the channel of the transaction was closed or replaced.
+// not threadsafe
+func (tx *Tx) valid() error {
+	if tx.s.generation() != tx.generation || tx.s.channel == nil || tx.s.channel.IsClosed() {
+		return fmt.Errorf("transaction of session %s %w: channel closed", tx.s.name, ErrTxAborted)
+	}
+	return nil
+}
+
+// abortedErr wraps the error with ErrTxAborted in case the channel was closed.
+// not threadsafe
+func (tx *Tx) abortedErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	if verr := tx.valid(); verr != nil {
+		return fmt.Errorf("%w: %w", verr, err)
+	}
+	return err
+}
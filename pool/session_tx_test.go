@@ -0,0 +1,23 @@
+package pool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTxValid(t *testing.T) {
+	t.Parallel()
+
+	// the channel of the transaction was replaced
+	tx := &Tx{s: &Session{name: "tx", channels: 2}, generation: 0}
+	assert.ErrorIs(t, tx.valid(), ErrTxAborted)
+	assert.ErrorIs(t, tx.Publish(context.Background(), "", "queue", Publishing{}), ErrTxAborted)
+	assert.ErrorIs(t, tx.Ack(1, false), ErrTxAborted)
+	assert.ErrorIs(t, tx.Nack(1, false, true), ErrTxAborted)
+
+	s := &Session{name: "confirmable", confirmable: true}
+	err := s.WithTx(context.Background(), func(tx *Tx) error { return nil })
+	assert.ErrorIs(t, err, ErrTxConfirmMode)
+}
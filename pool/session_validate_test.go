@@ -0,0 +1,70 @@
+package pool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jxsl13/amqpx/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionPublishMaxMessageSize(t *testing.T) {
+	t.Parallel()
+
+	// no channel, oversized messages must be rejected before hitting the broker
+	s := &Session{maxMessageSize: 4}
+
+	_, err := s.Publish(context.Background(), "", "queue", Publishing{Body: []byte("too large")})
+	assert.ErrorIs(t, err, ErrMessageTooLarge)
+	assert.False(t, recoverable(err), "oversized messages must not be retried")
+
+	err = (&Tx{s: s}).Publish(context.Background(), "", "queue", Publishing{Body: []byte("too large")})
+	assert.ErrorIs(t, err, ErrMessageTooLarge)
+
+	assert.NoError(t, s.validatePublishing(Publishing{Body: []byte("fits")}))
+
+	// unlimited by default
+	s = &Session{}
+	assert.NoError(t, s.validatePublishing(Publishing{Body: make([]byte, 1024*1024)}))
+}
+
+func TestSessionReturned(t *testing.T) {
+	t.Parallel()
+
+	// no channel, returned sessions must fail before hitting the broker
+	s := &Session{name: "session", cached: true}
+	sp := &SessionPool{
+		pool:     &ConnectionPool{name: "pool"},
+		sessions: make(chan *Session, 1),
+		cached:   []*Session{s},
+		log:      logging.NewNoOpLogger(),
+	}
+
+	sp.ReturnSession(s, nil)
+	assert.Len(t, sp.sessions, 1)
+
+	_, err := s.Publish(context.Background(), "", "queue", Publishing{Body: []byte("hello")})
+	assert.ErrorIs(t, err, ErrSessionReturned)
+	assert.False(t, recoverable(err), "returned sessions must not be recovered")
+	assert.ErrorIs(t, s.Ack(1, false), ErrSessionReturned)
+	assert.ErrorIs(t, s.Nack(1, false, true), ErrSessionReturned)
+	assert.ErrorIs(t, s.Reject(1, true), ErrSessionReturned)
+	assert.ErrorIs(t, s.Cancel("consumer", false), ErrSessionReturned)
+
+	var d Delivery
+	d.DeliveryTag = 1
+	d.Acknowledger = sessionAcknowledger{s}
+	assert.ErrorIs(t, s.AckBatch([]Delivery{d}), ErrSessionReturned)
+	assert.ErrorIs(t, s.NackBatch([]Delivery{d}, true), ErrSessionReturned)
+	assert.ErrorIs(t, AckBatch([]Delivery{d}), ErrSessionReturned)
+	assert.ErrorIs(t, NackBatch([]Delivery{d}, true), ErrSessionReturned)
+
+	// returning a session twice must not put it into the pool twice
+	sp.ReturnSession(s, nil)
+	assert.Len(t, sp.sessions, 1)
+
+	s.borrow()
+	err = s.Cancel("consumer", false)
+	assert.ErrorIs(t, err, ErrClosed)
+	assert.NotErrorIs(t, err, ErrSessionReturned)
+}
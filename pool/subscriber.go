@@ -18,6 +18,10 @@ type Subscriber struct {
 	started       bool
 	handlers      []*Handler
 	batchHandlers []*BatchHandler
+	middlewares   []HandlerMiddleware
+
+	slowHandlerThreshold time.Duration
+	slowHandlerCB        SlowHandlerCallback
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -70,8 +74,13 @@ func NewSubscriber(p *Pool, options ...SubscriberOption) *Subscriber {
 	sub := &Subscriber{
 		pool:          p,
 		autoClosePool: option.AutoClosePool,
-		ctx:           ctx,
-		cancel:        cancel,
+		middlewares:   option.Middlewares,
+
+		slowHandlerThreshold: option.SlowHandlerThreshold,
+		slowHandlerCB:        option.SlowHandlerCallback,
+
+		ctx:    ctx,
+		cancel: cancel,
 
 		log: option.Logger,
 	}
@@ -80,9 +89,11 @@ func NewSubscriber(p *Pool, options ...SubscriberOption) *Subscriber {
 }
 
 // HandlerFunc is basically a handler for incoming messages/events.
+// The queue of the deliveries is attached to the context, see DeliveryQueue.
 type HandlerFunc func(context.Context, Delivery) error
 
-// BatchHandlerFunc is a handler for incoming batches of messages/events
+// BatchHandlerFunc is a handler for incoming batches of messages/events.
+// The queue of the deliveries is attached to the context, see DeliveryQueue.
 type BatchHandlerFunc func(context.Context, []Delivery) error
 
 // RegisterHandlerFunc registers a consumer function that starts a consumer upon subscriber startup.
@@ -279,12 +290,16 @@ func (s *Subscriber) consumer(h *Handler, wg *sync.WaitGroup) {
 		return
 	}
 
+	consume := reapplyOnCancel(
+		func() error { return s.applyTopology(h) },
+		func() error { return s.consume(h) },
+	)
 	s.retry("consumer", h, func() error {
 		select {
 		case <-s.catchShutdown():
 			return s.shutdownErr()
 		case <-h.resuming().Done():
-			return s.consume(h)
+			return consume()
 		}
 	})
 }
@@ -307,6 +322,15 @@ func (s *Subscriber) consume(h *Handler) (err error) {
 		s.returnSession(h, session, err)
 	}()
 
+	// prefetch limits must be applied before consuming
+	prefetch := newPrefetchController(opts.Backpressure)
+	defer prefetch.stop()
+	qos := s.prefetchQos(opts.ConsumerTag, session)
+	err = prefetch.update(qos)
+	if err != nil {
+		return err
+	}
+
 	// got a working session
 	delivery, err := session.ConsumeWithContext(
 		h.pausing(),
@@ -317,19 +341,35 @@ func (s *Subscriber) consume(h *Handler) (err error) {
 		return err
 	}
 
+	handle := queueHandler(chainMiddlewares(opts.HandlerFunc, s.middlewares), opts.Queue)
+
 	h.resumed()
 	s.infoConsumer(opts.ConsumerTag, "started")
+
+	var activation consumerActivation
 	for {
 		select {
 		case <-s.catchShutdown():
 			return s.shutdownErr()
+		case <-prefetch.C():
+			err = prefetch.update(qos)
+			if err != nil {
+				return err
+			}
 		case msg, ok := <-delivery:
 			if !ok {
 				return ErrDeliveryClosed
 			}
+			if activation.activated(msg) {
+				s.infoConsumer(opts.ConsumerTag, "active")
+			}
 
 			s.infoHandler(opts.ConsumerTag, msg.Exchange, msg.RoutingKey, opts.Queue, "received message")
-			err = opts.HandlerFunc(h.pausing(), msg)
+			done := s.watchHandler(opts.ConsumerTag, opts.Queue)
+			err = callSafe(s.log, "handler", ErrHandlerPanic, func() error {
+				return handle(h.pausing(), msg)
+			})
+			done()
 			if opts.AutoAck {
 				if err != nil {
 					// we cannot really do anything to recover from a processing error in this case
@@ -347,23 +387,44 @@ func (s *Subscriber) consume(h *Handler) (err error) {
 	}
 }
 
+// consumerActivation tracks whether a consumer is active on the current channel of its session.
+// Standby consumers of single active consumer queues do not receive messages until they are activated,
+// which happens again on every channel that the consumer is restored on after a recovery of its session.
+type consumerActivation struct {
+	active bool
+	epoch  uint64
+}
+
+// activated returns true for the first message that the consumer receives on a channel.
+func (a *consumerActivation) activated(msg Delivery) bool {
+	epoch := DeliveryEpoch(msg.DeliveryTag)
+	if a.active && a.epoch == epoch {
+		return false
+	}
+	a.active = true
+	a.epoch = epoch
+	return true
+}
+
 // (n)ack delivery and signal that message was processed by the service
 func (s *Subscriber) ackPostHandle(opts HandlerConfig, deliveryTag uint64, exchange, routingKey string, session *Session, handlerErr error) (err error) {
-	var ackErr error
+	var (
+		ackErr error
+		action NackAction
+	)
 	if handlerErr == nil {
 		ackErr = session.Ack(deliveryTag, false)
-	} else if errors.Is(handlerErr, ErrReject) || errors.Is(handlerErr, ErrRejectSingle) {
-		ackErr = session.Nack(deliveryTag, false, false)
 	} else {
+		action = nackAction(opts.NackPolicy, handlerErr)
 		// requeue message if possible
-		ackErr = session.Nack(deliveryTag, false, true)
+		ackErr = session.Nack(deliveryTag, false, action == NackActionRequeue)
 	}
 
 	if ackErr == nil {
 		// (n)acked or rejected successfully
 		if handlerErr == nil {
 			s.infoHandler(opts.ConsumerTag, exchange, routingKey, opts.Queue, "acked message")
-		} else if errors.Is(handlerErr, ErrReject) {
+		} else if action == NackActionDeadLetter {
 			s.infoHandler(opts.ConsumerTag, exchange, routingKey, opts.Queue, "rejected message")
 		} else {
 			s.infoHandler(opts.ConsumerTag, exchange, routingKey, opts.Queue, "nacked message")
@@ -398,12 +459,16 @@ func (s *Subscriber) batchConsumer(h *BatchHandler, wg *sync.WaitGroup) {
 		return
 	}
 
+	consume := reapplyOnCancel(
+		func() error { return s.applyTopology(h) },
+		func() error { return s.batchConsume(h) },
+	)
 	s.retry("batch consumer", h, func() error {
 		select {
 		case <-s.catchShutdown():
 			return s.shutdownErr()
 		case <-h.resuming().Done():
-			return s.batchConsume(h)
+			return consume()
 		}
 	})
 }
@@ -426,6 +491,15 @@ func (s *Subscriber) batchConsume(h *BatchHandler) (err error) {
 		s.returnSession(h, session, err)
 	}()
 
+	// prefetch limits must be applied before consuming
+	prefetch := newPrefetchController(opts.Backpressure)
+	defer prefetch.stop()
+	qos := s.prefetchQos(opts.ConsumerTag, session)
+	err = prefetch.update(qos)
+	if err != nil {
+		return err
+	}
+
 	// got a working session
 	delivery, err := session.ConsumeWithContext(
 		h.pausing(),
@@ -468,7 +542,12 @@ func (s *Subscriber) batchConsume(h *BatchHandler) (err error) {
 	)
 	defer closeTimer(timer, &drained)
 
-	var batchBytes = 0
+	var (
+		batchBytes = 0
+		// prefetch updates must not delay the flush of a batch
+		keepTimer  = false
+		activation consumerActivation
+	)
 	for {
 		// reset batch slice
 		// reuse memory
@@ -479,15 +558,27 @@ func (s *Subscriber) batchConsume(h *BatchHandler) (err error) {
 		for {
 
 			// reset the timer
-			resetTimer(timer, opts.FlushTimeout, &drained)
+			if !keepTimer {
+				resetTimer(timer, opts.FlushTimeout, &drained)
+			}
+			keepTimer = false
 
 			select {
 			case <-s.catchShutdown():
 				return s.shutdownErr()
+			case <-prefetch.C():
+				err = prefetch.update(qos)
+				if err != nil {
+					return err
+				}
+				keepTimer = true
 			case msg, ok := <-delivery:
 				if !ok {
 					return ErrDeliveryClosed
 				}
+				if activation.activated(msg) {
+					s.infoConsumer(opts.ConsumerTag, "active")
+				}
 
 				batchBytes += len(msg.Body)
 				batch = append(batch, msg)
@@ -520,13 +611,14 @@ func (s *Subscriber) batchConsume(h *BatchHandler) (err error) {
 		}
 
 		// at this point we have a batch to work with
-		var (
-			batchSize       = len(batch)
-			lastDeliveryTag = batch[len(batch)-1].DeliveryTag
-		)
+		batchSize := len(batch)
 
 		s.infoBatchHandler(opts.ConsumerTag, opts.Queue, batchSize, batchBytes, "received batch")
-		err = opts.HandlerFunc(h.pausing(), batch)
+		done := s.watchHandler(opts.ConsumerTag, opts.Queue)
+		err = callSafe(s.log, "batch handler", ErrHandlerPanic, func() error {
+			return opts.HandlerFunc(withDeliveryQueue(h.pausing(), opts.Queue), batch)
+		})
+		done()
 		// no acks required
 		if opts.AutoAck {
 			if err != nil {
@@ -547,7 +639,7 @@ func (s *Subscriber) batchConsume(h *BatchHandler) (err error) {
 				)
 			}
 		} else {
-			poolErr := s.ackBatchPostHandle(opts, lastDeliveryTag, batchSize, batchBytes, session, err)
+			poolErr := s.ackBatchPostHandle(opts, batch, batchBytes, session, err)
 			if poolErr != nil {
 				return poolErr
 			}
@@ -555,21 +647,31 @@ func (s *Subscriber) batchConsume(h *BatchHandler) (err error) {
 	}
 }
 
-func (s *Subscriber) ackBatchPostHandle(opts BatchHandlerConfig, lastDeliveryTag uint64, currentBatchSize, currentBatchBytes int, session *Session, handlerErr error) (err error) {
-	var ackErr error
+// ackBatchPostHandle (n)acks the batch on the session that received it.
+// Delivery tags are scoped to the channel of a session, which is why the batch is never (n)acked via another session.
+func (s *Subscriber) ackBatchPostHandle(opts BatchHandlerConfig, batch []Delivery, currentBatchBytes int, session *Session, handlerErr error) (err error) {
+	var (
+		ackErr           error
+		currentBatchSize = len(batch)
+		lastDeliveryTag  = batch[len(batch)-1].DeliveryTag
+		deadLetter       = handlerErr != nil && nackAction(opts.NackPolicy, handlerErr) == NackActionDeadLetter
+	)
 	// processing failed
 	if handlerErr == nil {
 		// ack last and all previous messages
-		ackErr = session.Ack(lastDeliveryTag, true)
+		ackErr = session.AckBatch(batch)
 	} else if errors.Is(handlerErr, ErrReject) {
 		// reject multiple
-		ackErr = session.Nack(lastDeliveryTag, true, false)
+		ackErr = session.NackBatch(batch, false)
 	} else if errors.Is(handlerErr, ErrRejectSingle) {
 		// reject single
 		ackErr = session.Nack(lastDeliveryTag, false, false)
+	} else if deadLetter {
+		// reject multiple according to policy
+		ackErr = session.NackBatch(batch, false)
 	} else {
 		// requeue message if possible & nack all previous messages
-		ackErr = session.Nack(lastDeliveryTag, true, true)
+		ackErr = session.NackBatch(batch, true)
 	}
 
 	if ackErr == nil {
@@ -581,7 +683,7 @@ func (s *Subscriber) ackBatchPostHandle(opts BatchHandlerConfig, lastDeliveryTag
 				currentBatchBytes,
 				"acked batch",
 			)
-		} else if errors.Is(handlerErr, ErrReject) {
+		} else if errors.Is(handlerErr, ErrReject) || (deadLetter && !errors.Is(handlerErr, ErrRejectSingle)) {
 			s.infoBatchHandler(
 				opts.ConsumerTag,
 				opts.Queue,
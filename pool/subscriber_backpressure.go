@@ -0,0 +1,111 @@
+package pool
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Backpressure adapts the prefetch count of a consumer to the load of its downstream dependencies, e.g. a database.
+// The higher the pressure, the fewer messages are prefetched, which prevents in-flight messages from piling up
+// while the downstream is slow. The prefetch count is restored as soon as the pressure decreases.
+//
+// The prefetch count is applied via basic.qos with QosOptions.Global, which is the only way to change the limit
+// of a running consumer. It is re-applied whenever the consumer is restarted or its session is recovered.
+// Be aware that quorum queues do not support global prefetch limits.
+type Backpressure struct {
+	// Pressure returns the current load of the downstream in the range [0, 1].
+	// Values outside of that range are clamped.
+	Pressure func() float64
+	// MinPrefetch is the prefetch count at a pressure of 1, must be at least 1.
+	MinPrefetch int
+	// MaxPrefetch is the prefetch count at a pressure of 0, must be at least MinPrefetch.
+	MaxPrefetch int
+	// Interval in which the pressure is evaluated, defaults to 1 second.
+	Interval time.Duration
+}
+
+// prefetch maps the passed pressure linearly to a prefetch count between MinPrefetch and MaxPrefetch.
+func (bp Backpressure) prefetch(pressure float64) int {
+	minPrefetch, maxPrefetch := bp.MinPrefetch, bp.MaxPrefetch
+	if minPrefetch < 1 {
+		minPrefetch = 1
+	}
+	if maxPrefetch < minPrefetch {
+		maxPrefetch = minPrefetch
+	}
+
+	switch {
+	case !(pressure > 0): // includes NaN
+		pressure = 0
+	case pressure > 1:
+		pressure = 1
+	}
+	return maxPrefetch - int(math.Round(pressure*float64(maxPrefetch-minPrefetch)))
+}
+
+// prefetchController periodically applies the prefetch count of a consumer with backpressure.
+// A nil controller does nothing, which is the case for consumers without backpressure.
+type prefetchController struct {
+	bp      Backpressure
+	ticker  *time.Ticker
+	current int // 0 as long as no prefetch count was applied
+}
+
+func newPrefetchController(bp *Backpressure) *prefetchController {
+	if bp == nil || bp.Pressure == nil {
+		return nil
+	}
+	interval := bp.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return &prefetchController{
+		bp:     *bp,
+		ticker: time.NewTicker(interval),
+	}
+}
+
+// C returns the channel that signals that the pressure should be evaluated again.
+func (pc *prefetchController) C() <-chan time.Time {
+	if pc == nil {
+		return nil
+	}
+	return pc.ticker.C
+}
+
+// update evaluates the pressure and applies the resulting prefetch count in case it changed.
+func (pc *prefetchController) update(qos func(prefetch int) error) error {
+	if pc == nil {
+		return nil
+	}
+	prefetch := pc.bp.prefetch(pc.bp.Pressure())
+	if prefetch == pc.current {
+		return nil
+	}
+	err := qos(prefetch)
+	if err != nil {
+		return err
+	}
+	pc.current = prefetch
+	return nil
+}
+
+func (pc *prefetchController) stop() {
+	if pc == nil {
+		return
+	}
+	pc.ticker.Stop()
+}
+
+// prefetchQos returns a function which applies the prefetch count of a consumer with backpressure to its session.
+func (s *Subscriber) prefetchQos(consumer string, session *Session) func(prefetch int) error {
+	return func(prefetch int) error {
+		err := session.Qos(s.ctx, prefetch, 0, QosOptions{Global: true})
+		if err != nil {
+			return fmt.Errorf("failed to apply prefetch count %d: %w", prefetch, err)
+		}
+		s.debugConsumer(consumer, fmt.Sprintf("applied prefetch count %d", prefetch))
+		return nil
+	}
+}
@@ -0,0 +1,89 @@
+package pool
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackpressurePrefetch(t *testing.T) {
+	t.Parallel()
+
+	bp := Backpressure{MinPrefetch: 10, MaxPrefetch: 110}
+
+	tests := []struct {
+		pressure float64
+		want     int
+	}{
+		{pressure: 0, want: 110},
+		{pressure: 0.25, want: 85},
+		{pressure: 0.5, want: 60},
+		{pressure: 1, want: 10},
+		// clamped
+		{pressure: -1, want: 110},
+		{pressure: 2, want: 10},
+		{pressure: math.NaN(), want: 110},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.want, bp.prefetch(test.pressure), test.pressure)
+	}
+
+	// invalid limits
+	assert.Equal(t, 1, Backpressure{}.prefetch(0))
+	assert.Equal(t, 5, Backpressure{MinPrefetch: 5, MaxPrefetch: 2}.prefetch(0))
+}
+
+func TestPrefetchController(t *testing.T) {
+	t.Parallel()
+
+	// disabled
+	disabled := newPrefetchController(nil)
+	assert.Nil(t, disabled)
+	assert.Nil(t, disabled.C())
+	assert.NoError(t, disabled.update(func(int) error { return errors.New("unexpected qos") }))
+	disabled.stop()
+
+	pressure := 0.0
+	pc := newPrefetchController(&Backpressure{
+		Pressure:    func() float64 { return pressure },
+		MinPrefetch: 1,
+		MaxPrefetch: 11,
+		Interval:    time.Millisecond,
+	})
+	require.NotNil(t, pc)
+	defer pc.stop()
+
+	var applied []int
+	qos := func(prefetch int) error {
+		applied = append(applied, prefetch)
+		return nil
+	}
+
+	// the initial prefetch count is always applied
+	require.NoError(t, pc.update(qos))
+	// unchanged pressure is not applied again
+	require.NoError(t, pc.update(qos))
+
+	// downstream slows down
+	pressure = 0.5
+	<-pc.C()
+	require.NoError(t, pc.update(qos))
+	pressure = 1
+	require.NoError(t, pc.update(qos))
+
+	// downstream recovers
+	pressure = 0
+	require.NoError(t, pc.update(qos))
+	assert.Equal(t, []int{11, 6, 1, 11}, applied)
+
+	// failed updates are retried
+	pressure = 0.5
+	errQos := errors.New("qos failed")
+	assert.ErrorIs(t, pc.update(func(int) error { return errQos }), errQos)
+	require.NoError(t, pc.update(qos))
+	assert.Equal(t, []int{11, 6, 1, 11, 6}, applied)
+}
@@ -0,0 +1,28 @@
+package pool
+
+import "context"
+
+// deliveryQueueKey is the context key of the queue that a delivery was consumed from, see DeliveryQueue.
+type deliveryQueueKey struct{}
+
+// DeliveryQueue returns the name of the queue that the deliveries passed to a handler of the Subscriber were
+// consumed from. The amqp delivery only carries the exchange and the routing key, so this allows handlers
+// that consume multiple queues to tell the deliveries of their queues apart.
+// ok is false in case the context was not passed to the handler by the Subscriber.
+func DeliveryQueue(ctx context.Context) (queue string, ok bool) {
+	queue, ok = ctx.Value(deliveryQueueKey{}).(string)
+	return queue, ok
+}
+
+// withDeliveryQueue attaches the queue of the deliveries to the context of a handler.
+func withDeliveryQueue(ctx context.Context, queue string) context.Context {
+	return context.WithValue(ctx, deliveryQueueKey{}, queue)
+}
+
+// queueHandler wraps the handler, so that the handler and its middlewares can look up the queue
+// of the deliveries, see DeliveryQueue.
+func queueHandler(hf HandlerFunc, queue string) HandlerFunc {
+	return func(ctx context.Context, msg Delivery) error {
+		return hf(withDeliveryQueue(ctx, queue), msg)
+	}
+}
@@ -0,0 +1,45 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+)
+
+// HandlerMiddleware wraps a handler function in order to add cross-cutting concerns,
+// e.g. tracing, metrics, logging or panic recovery.
+// A middleware sees every delivery before it is passed to the next handler and the error
+// that is returned by the next handler before the message is (n)acked.
+type HandlerMiddleware func(next HandlerFunc) HandlerFunc
+
+// chainMiddlewares wraps the handler with the middlewares, the first middleware is the outermost one.
+func chainMiddlewares(hf HandlerFunc, middlewares []HandlerMiddleware) HandlerFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		hf = middlewares[i](hf)
+	}
+	return hf
+}
+
+// RecoverMiddleware recovers from panics of the next handler and converts them into an error
+// that wraps ErrHandlerPanic, so that a single bad message does not crash the consumer.
+// The message is requeued or dead lettered depending on the passed action.
+// NackActionDeadLetter is recommended, as a requeued message most likely panics again.
+// Without the middleware, the Subscriber still recovers from handler panics, but (n)acks the message
+// according to the NackPolicy of the handler.
+func RecoverMiddleware(action NackAction) HandlerMiddleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, msg Delivery) (err error) {
+			defer func() {
+				r := recover()
+				if r == nil {
+					return
+				}
+				if action == NackActionDeadLetter {
+					err = fmt.Errorf("%w: %w: %v", ErrReject, ErrHandlerPanic, r)
+				} else {
+					err = fmt.Errorf("%w: %v", ErrHandlerPanic, r)
+				}
+			}()
+			return next(ctx, msg)
+		}
+	}
+}
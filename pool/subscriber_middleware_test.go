@@ -0,0 +1,106 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChainMiddlewares(t *testing.T) {
+	t.Parallel()
+
+	var (
+		calls   []string
+		handled = map[string]error{}
+		errFail = errors.New("failed")
+	)
+
+	metrics := func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, msg Delivery) error {
+			calls = append(calls, "metrics")
+			err := next(ctx, msg)
+			handled[msg.MessageId] = err
+			return err
+		}
+	}
+	tracing := func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, msg Delivery) error {
+			calls = append(calls, "tracing")
+			return next(ctx, msg)
+		}
+	}
+
+	handler := func(ctx context.Context, msg Delivery) error {
+		calls = append(calls, "handler")
+		switch msg.MessageId {
+		case "panic":
+			panic("bad message")
+		case "fail":
+			return errFail
+		default:
+			return nil
+		}
+	}
+
+	hf := chainMiddlewares(handler, []HandlerMiddleware{
+		metrics,
+		RecoverMiddleware(NackActionDeadLetter),
+		tracing,
+	})
+
+	// middlewares are composed in registration order
+	assert.NoError(t, hf(context.Background(), Delivery{MessageId: "ok"}))
+	assert.Equal(t, []string{"metrics", "tracing", "handler"}, calls)
+	assert.NoError(t, handled["ok"])
+
+	// middlewares see the error of the handler
+	assert.ErrorIs(t, hf(context.Background(), Delivery{MessageId: "fail"}), errFail)
+	assert.ErrorIs(t, handled["fail"], errFail)
+
+	// panics are converted into errors that dead letter the message
+	err := hf(context.Background(), Delivery{MessageId: "panic"})
+	assert.ErrorIs(t, err, ErrHandlerPanic)
+	assert.ErrorIs(t, handled["panic"], ErrHandlerPanic)
+	assert.Equal(t, NackActionDeadLetter, nackAction(nil, err))
+
+	// requeued upon panic
+	hf = chainMiddlewares(handler, []HandlerMiddleware{RecoverMiddleware(NackActionRequeue)})
+	err = hf(context.Background(), Delivery{MessageId: "panic"})
+	assert.ErrorIs(t, err, ErrHandlerPanic)
+	assert.Equal(t, NackActionRequeue, nackAction(nil, err))
+
+	// no middlewares
+	hf = chainMiddlewares(handler, nil)
+	assert.NoError(t, hf(context.Background(), Delivery{MessageId: "ok"}))
+}
+
+func TestQueueHandler(t *testing.T) {
+	var queues []string
+	handler := func(ctx context.Context, msg Delivery) error {
+		queue, ok := DeliveryQueue(ctx)
+		assert.True(t, ok)
+		queues = append(queues, queue)
+		return nil
+	}
+
+	// middlewares see the queue as well
+	middleware := func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, msg Delivery) error {
+			queue, _ := DeliveryQueue(ctx)
+			queues = append(queues, "middleware:"+queue)
+			return next(ctx, msg)
+		}
+	}
+
+	hf := queueHandler(chainMiddlewares(handler, []HandlerMiddleware{middleware}), "orders")
+	assert.NoError(t, hf(context.Background(), Delivery{}))
+	hf = queueHandler(handler, "payments")
+	assert.NoError(t, hf(context.Background(), Delivery{}))
+	assert.Equal(t, []string{"middleware:orders", "orders", "payments"}, queues)
+
+	// contexts that were not passed by the subscriber carry no queue
+	_, ok := DeliveryQueue(context.Background())
+	assert.False(t, ok)
+}
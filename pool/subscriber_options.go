@@ -2,6 +2,7 @@ package pool
 
 import (
 	"context"
+	"time"
 
 	"github.com/jxsl13/amqpx/logging"
 )
@@ -9,6 +10,10 @@ import (
 type subscriberOption struct {
 	Ctx           context.Context
 	AutoClosePool bool
+	Middlewares   []HandlerMiddleware
+
+	SlowHandlerThreshold time.Duration
+	SlowHandlerCallback  SlowHandlerCallback
 
 	Logger logging.Logger
 }
@@ -32,3 +37,26 @@ func SubscriberWithAutoClosePool(autoClose bool) SubscriberOption {
 		co.AutoClosePool = autoClose
 	}
 }
+
+// SubscriberWithMiddleware wraps the handler functions of all consumers of the subscriber with the passed middlewares,
+// e.g. for tracing, metrics, logging or panic recovery.
+// Middlewares are composed in registration order, the first middleware is the outermost one.
+// Batch handlers are not wrapped.
+func SubscriberWithMiddleware(middlewares ...HandlerMiddleware) SubscriberOption {
+	return func(co *subscriberOption) {
+		co.Middlewares = append(co.Middlewares, middlewares...)
+	}
+}
+
+// SubscriberWithHandlerWatchdog reports handlers that process a message or batch for longer than the threshold.
+// RabbitMQ closes the channel of consumers that do not acknowledge a delivery within the consumer timeout
+// (x-consumer-timeout), which requeues all of its unacknowledged messages. The threshold should be set to
+// a fraction of that timeout in order to detect risky handlers before the timeout is hit.
+// Slow handlers are logged as warnings and passed to the optional callback, e.g. in order to export metrics.
+// A threshold of 0 or less disables the watchdog, which is the default.
+func SubscriberWithHandlerWatchdog(threshold time.Duration, callback SlowHandlerCallback) SubscriberOption {
+	return func(co *subscriberOption) {
+		co.SlowHandlerThreshold = threshold
+		co.SlowHandlerCallback = callback
+	}
+}
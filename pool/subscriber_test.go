@@ -2,6 +2,7 @@ package pool_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"testing"
@@ -11,6 +12,7 @@ import (
 	"github.com/jxsl13/amqpx/logging"
 	"github.com/jxsl13/amqpx/pool"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewSingleSubscriber(t *testing.T) {
@@ -292,3 +294,410 @@ func testBatchSubscriberMaxBytes(t *testing.T, funcName string, maxBatchBytes in
 
 	wg.Wait()
 }
+
+func TestSubscriberNackPolicy(t *testing.T) {
+	t.Parallel()
+	var (
+		ctx          = context.TODO()
+		nextPoolName = testutils.PoolNameGenerator(testutils.FuncName())
+		poolName     = nextPoolName()
+		hp           = NewPool(t, ctx, testutils.HealthyConnectURL, poolName, 1, 2)
+	)
+	defer hp.Close()
+
+	var (
+		nextQueueName  = testutils.QueueNameGenerator(poolName)
+		queueName      = nextQueueName()
+		deadLetterName = nextQueueName()
+		errTemporary   = errors.New("temporary failure")
+		errPoison      = errors.New("poison message")
+	)
+
+	ts, err := hp.GetTransientSession(ctx)
+	require.NoError(t, err)
+	defer hp.ReturnSession(ts, nil)
+
+	_, err = ts.QueueDeclare(ctx, deadLetterName)
+	require.NoError(t, err)
+	defer func() {
+		_, err := ts.QueueDelete(ctx, deadLetterName)
+		assert.NoError(t, err)
+	}()
+
+	_, err = ts.QueueDeclare(ctx, queueName, pool.QueueDeclareOptions{
+		Durable: true,
+		Args: pool.Table{
+			"x-queue-type":              "quorum",
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": deadLetterName,
+		},
+	})
+	require.NoError(t, err)
+	defer func() {
+		_, err := ts.QueueDelete(ctx, queueName)
+		assert.NoError(t, err)
+	}()
+
+	var (
+		processed  = make(chan pool.Delivery, 3)
+		subscriber = pool.NewSubscriber(hp, pool.SubscriberWithLogger(logging.NewTestLogger(t)))
+	)
+	defer subscriber.Close()
+
+	subscriber.RegisterHandlerFunc(queueName, func(ctx context.Context, msg pool.Delivery) error {
+		processed <- msg
+		switch {
+		case string(msg.Body) == "poison":
+			return errPoison
+		case !msg.Redelivered:
+			// requeued and processed again
+			return errTemporary
+		default:
+			return nil
+		}
+	}, pool.ConsumeOptions{
+		ConsumerTag: testutils.ConsumerNameGenerator(queueName)(),
+		NackPolicy: func(err error) pool.NackAction {
+			if errors.Is(err, errPoison) {
+				return pool.NackActionDeadLetter
+			}
+			return pool.NackActionRequeue
+		},
+	})
+	require.NoError(t, subscriber.Start(ctx))
+
+	// requeue path
+	_, err = ts.Publish(ctx, "", queueName, pool.Publishing{Body: []byte("temporary")})
+	require.NoError(t, err)
+	for i, redelivered := range []bool{false, true} {
+		select {
+		case msg := <-processed:
+			assert.Equal(t, "temporary", string(msg.Body))
+			assert.Equalf(t, redelivered, msg.Redelivered, "delivery %d", i)
+		case <-time.After(10 * time.Second):
+			require.Fail(t, "expected requeued message to be processed again")
+		}
+	}
+
+	// dead letter path
+	_, err = ts.Publish(ctx, "", queueName, pool.Publishing{Body: []byte("poison")})
+	require.NoError(t, err)
+	select {
+	case msg := <-processed:
+		assert.Equal(t, "poison", string(msg.Body))
+	case <-time.After(10 * time.Second):
+		require.Fail(t, "expected poison message to be processed")
+	}
+
+	assert.Eventually(t, func() bool {
+		msg, ok, err := ts.Get(ctx, deadLetterName, true)
+		return err == nil && ok && string(msg.Body) == "poison"
+	}, 10*time.Second, 100*time.Millisecond)
+
+	// the poison message is not redelivered
+	select {
+	case msg := <-processed:
+		assert.Failf(t, "unexpected redelivery", "message: %s", string(msg.Body))
+	case <-time.After(time.Second):
+	}
+}
+
+func TestSubscriberHandlerWatchdog(t *testing.T) {
+	t.Parallel()
+	var (
+		ctx          = context.TODO()
+		nextPoolName = testutils.PoolNameGenerator(testutils.FuncName())
+		poolName     = nextPoolName()
+		hp           = NewPool(t, ctx, testutils.HealthyConnectURL, poolName, 1, 2)
+	)
+	defer hp.Close()
+
+	var (
+		queueName    = testutils.QueueNameGenerator(poolName)()
+		consumerName = testutils.ConsumerNameGenerator(queueName)()
+		threshold    = 100 * time.Millisecond
+	)
+
+	ts, err := hp.GetTransientSession(ctx)
+	require.NoError(t, err)
+	defer hp.ReturnSession(ts, nil)
+
+	_, err = ts.QueueDeclare(ctx, queueName)
+	require.NoError(t, err)
+	defer func() {
+		_, err := ts.QueueDelete(ctx, queueName)
+		assert.NoError(t, err)
+	}()
+
+	type slowHandler struct {
+		consumer string
+		queue    string
+		elapsed  time.Duration
+	}
+
+	var (
+		reported  = make(chan slowHandler, 2)
+		processed = make(chan string, 2)
+	)
+	subscriber := pool.NewSubscriber(hp,
+		pool.SubscriberWithLogger(logging.NewTestLogger(t)),
+		pool.SubscriberWithHandlerWatchdog(threshold, func(consumer, queue string, elapsed time.Duration) {
+			reported <- slowHandler{consumer: consumer, queue: queue, elapsed: elapsed}
+		}),
+	)
+	defer subscriber.Close()
+
+	subscriber.RegisterHandlerFunc(queueName, func(ctx context.Context, msg pool.Delivery) error {
+		if string(msg.Body) == "slow" {
+			time.Sleep(3 * threshold)
+		}
+		processed <- string(msg.Body)
+		return nil
+	}, pool.ConsumeOptions{ConsumerTag: consumerName})
+	require.NoError(t, subscriber.Start(ctx))
+
+	for _, body := range []string{"fast", "slow"} {
+		_, err = ts.Publish(ctx, "", queueName, pool.Publishing{Body: []byte(body)})
+		require.NoError(t, err)
+	}
+
+	for _, body := range []string{"fast", "slow"} {
+		select {
+		case got := <-processed:
+			assert.Equal(t, body, got)
+		case <-time.After(10 * time.Second):
+			require.Failf(t, "expected message to be processed", "message: %s", body)
+		}
+	}
+
+	// only the slow handler is reported, while it is still running
+	select {
+	case r := <-reported:
+		assert.Equal(t, consumerName, r.consumer)
+		assert.Equal(t, queueName, r.queue)
+		assert.GreaterOrEqual(t, r.elapsed, threshold)
+	default:
+		require.Fail(t, "expected slow handler to be reported")
+	}
+	select {
+	case r := <-reported:
+		assert.Failf(t, "unexpected report", "elapsed: %s", r.elapsed)
+	default:
+	}
+}
+
+func TestSubscriberReappliesTopologyAfterCancel(t *testing.T) {
+	t.Parallel()
+	var (
+		ctx          = context.TODO()
+		nextPoolName = testutils.PoolNameGenerator(testutils.FuncName())
+		poolName     = nextPoolName()
+		hp           = NewPool(t, ctx, testutils.HealthyConnectURL, poolName, 1, 2)
+	)
+	defer hp.Close()
+
+	var (
+		exchangeName = testutils.ExchangeNameGenerator(poolName)()
+		queueName    = testutils.QueueNameGenerator(poolName)()
+		applied      = make(chan struct{}, 1)
+		received     = make(chan pool.Delivery, 1)
+	)
+
+	ts, err := hp.GetTransientSession(ctx)
+	require.NoError(t, err)
+	defer hp.ReturnSession(ts, nil)
+
+	require.NoError(t, ts.ExchangeDeclare(ctx, exchangeName, pool.ExchangeKindTopic))
+	defer func() {
+		assert.NoError(t, ts.ExchangeDelete(ctx, exchangeName))
+	}()
+
+	topology := func(ctx context.Context, s *pool.Session) error {
+		_, err := s.QueueDeclare(ctx, queueName)
+		if err != nil {
+			return err
+		}
+		return s.QueueBind(ctx, queueName, "#", exchangeName)
+	}
+	require.NoError(t, topology(ctx, ts))
+	defer func() {
+		_, err := ts.QueueDelete(ctx, queueName)
+		assert.NoError(t, err)
+	}()
+
+	subscriber := pool.NewSubscriber(hp, pool.SubscriberWithLogger(logging.NewTestLogger(t)))
+	defer subscriber.Close()
+
+	subscriber.RegisterHandlerFunc(queueName, func(ctx context.Context, msg pool.Delivery) error {
+		received <- msg
+		return nil
+	}, pool.ConsumeOptions{
+		ConsumerTag: testutils.ConsumerNameGenerator(queueName)(),
+		Topology: func(ctx context.Context, s *pool.Session) error {
+			err := topology(ctx, s)
+			if err == nil {
+				applied <- struct{}{}
+			}
+			return err
+		},
+	})
+	require.NoError(t, subscriber.Start(ctx))
+
+	// deleting the queue cancels the consumer and loses the bindings of the queue, similar to a failover
+	// of a transient queue
+	require.Eventually(t, func() bool {
+		q, err := ts.QueueDeclarePassive(ctx, queueName)
+		return err == nil && q.Consumers == 1
+	}, 10*time.Second, 50*time.Millisecond)
+	_, err = ts.QueueDelete(ctx, queueName)
+	require.NoError(t, err)
+
+	select {
+	case <-applied:
+	case <-time.After(10 * time.Second):
+		require.Fail(t, "expected topology to be re-applied after the consumer was canceled")
+	}
+
+	// the restarted consumer receives messages via the re-established binding
+	require.Eventually(t, func() bool {
+		q, err := ts.QueueDeclarePassive(ctx, queueName)
+		return err == nil && q.Consumers == 1
+	}, 10*time.Second, 50*time.Millisecond)
+
+	tag, err := ts.Publish(ctx, exchangeName, "rebound", pool.Publishing{Body: []byte("rebound")})
+	require.NoError(t, err)
+	require.NoError(t, ts.AwaitConfirm(ctx, tag))
+
+	select {
+	case msg := <-received:
+		assert.Equal(t, "rebound", string(msg.Body))
+	case <-time.After(10 * time.Second):
+		require.Fail(t, "expected restarted consumer to receive the message")
+	}
+}
+
+func TestSubscriberHandlerPanic(t *testing.T) {
+	t.Parallel()
+	var (
+		ctx          = context.TODO()
+		nextPoolName = testutils.PoolNameGenerator(testutils.FuncName())
+		poolName     = nextPoolName()
+		hp           = NewPool(t, ctx, testutils.HealthyConnectURL, poolName, 1, 2)
+	)
+	defer hp.Close()
+
+	queueName := testutils.QueueNameGenerator(poolName)()
+
+	ts, err := hp.GetTransientSession(ctx)
+	require.NoError(t, err)
+	defer hp.ReturnSession(ts, nil)
+
+	_, err = ts.QueueDeclare(ctx, queueName)
+	require.NoError(t, err)
+	defer func() {
+		_, err := ts.QueueDelete(ctx, queueName)
+		assert.NoError(t, err)
+	}()
+
+	var (
+		processed  = make(chan pool.Delivery, 2)
+		subscriber = pool.NewSubscriber(hp, pool.SubscriberWithLogger(logging.NewTestLogger(t)))
+	)
+	defer subscriber.Close()
+
+	subscriber.RegisterHandlerFunc(queueName, func(ctx context.Context, msg pool.Delivery) error {
+		processed <- msg
+		if !msg.Redelivered {
+			panic("handler panicked")
+		}
+		return nil
+	}, pool.ConsumeOptions{
+		ConsumerTag: testutils.ConsumerNameGenerator(queueName)(),
+		NackPolicy: func(err error) pool.NackAction {
+			assert.ErrorIs(t, err, pool.ErrHandlerPanic)
+			return pool.NackActionRequeue
+		},
+	})
+	require.NoError(t, subscriber.Start(ctx))
+
+	_, err = ts.Publish(ctx, "", queueName, pool.Publishing{Body: []byte("panic")})
+	require.NoError(t, err)
+
+	// the panic is contained and the message is requeued according to the nack policy
+	for i, redelivered := range []bool{false, true} {
+		select {
+		case msg := <-processed:
+			assert.Equal(t, "panic", string(msg.Body))
+			assert.Equalf(t, redelivered, msg.Redelivered, "delivery %d", i)
+		case <-time.After(10 * time.Second):
+			require.Fail(t, "expected message to be processed again after the handler panicked")
+		}
+	}
+
+	// the message was acked after it was processed successfully
+	assert.Eventually(t, func() bool {
+		q, err := ts.QueueDeclarePassive(ctx, queueName)
+		return err == nil && q.Messages == 0 && q.Consumers == 1
+	}, 10*time.Second, 100*time.Millisecond)
+}
+
+func TestSubscriberDeliveryQueue(t *testing.T) {
+	t.Parallel()
+	var (
+		ctx           = context.TODO()
+		nextPoolName  = testutils.PoolNameGenerator(testutils.FuncName())
+		poolName      = nextPoolName()
+		hp            = NewPool(t, ctx, testutils.HealthyConnectURL, poolName, 1, 3)
+		nextQueueName = testutils.QueueNameGenerator(poolName)
+		queues        = []string{nextQueueName(), nextQueueName()}
+	)
+	defer hp.Close()
+
+	ts, err := hp.GetTransientSession(ctx)
+	require.NoError(t, err)
+	defer hp.ReturnSession(ts, nil)
+
+	var (
+		processed  = make(chan pool.Delivery, len(queues))
+		subscriber = pool.NewSubscriber(hp, pool.SubscriberWithLogger(logging.NewTestLogger(t)))
+	)
+	defer subscriber.Close()
+
+	// a single handler consumes multiple queues
+	handle := func(ctx context.Context, msg pool.Delivery) error {
+		queue, ok := pool.DeliveryQueue(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, string(msg.Body), queue)
+		processed <- msg
+		return nil
+	}
+
+	for _, queueName := range queues {
+		queueName := queueName
+		_, err = ts.QueueDeclare(ctx, queueName)
+		require.NoError(t, err)
+		defer func() {
+			_, err := ts.QueueDelete(ctx, queueName)
+			assert.NoError(t, err)
+		}()
+
+		subscriber.RegisterHandlerFunc(queueName, handle, pool.ConsumeOptions{
+			ConsumerTag: testutils.ConsumerNameGenerator(queueName)(),
+		})
+	}
+	require.NoError(t, subscriber.Start(ctx))
+
+	for _, queueName := range queues {
+		_, err = ts.Publish(ctx, "", queueName, pool.Publishing{Body: []byte(queueName)})
+		require.NoError(t, err)
+	}
+
+	// every delivery carries the queue it was consumed from
+	for range queues {
+		select {
+		case <-processed:
+		case <-time.After(10 * time.Second):
+			require.Fail(t, "expected messages of all queues to be processed")
+		}
+	}
+}
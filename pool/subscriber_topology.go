@@ -0,0 +1,51 @@
+package pool
+
+import (
+	"errors"
+	"fmt"
+)
+
+// reapplyOnCancel returns a consume function that applies the topology before restarting a consumer
+// whose deliveries were closed, e.g. because the broker canceled the consumer during a failover of its queue.
+// The topology is applied again until it succeeded once.
+func reapplyOnCancel(apply func() error, consume func() error) func() error {
+	reapply := false
+	return func() error {
+		if reapply {
+			err := apply()
+			if err != nil {
+				return err
+			}
+			reapply = false
+		}
+
+		err := consume()
+		reapply = errors.Is(err, ErrDeliveryClosed)
+		return err
+	}
+}
+
+// applyTopology applies the topology of the queue of the handler, if any.
+func (s *Subscriber) applyTopology(h handler) (err error) {
+	opts := h.QueueConfig()
+	if opts.Topology == nil {
+		return nil
+	}
+
+	session, err := s.pool.GetSession(s.ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		s.pool.ReturnSession(session, err)
+	}()
+
+	err = callSafe(s.log, "topology", ErrCallbackPanic, func() error {
+		return opts.Topology(s.ctx, session)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to re-apply topology of queue %s: %w", opts.Queue, err)
+	}
+	s.infoConsumer(opts.ConsumerTag, "re-applied topology of queue ", opts.Queue)
+	return nil
+}
@@ -0,0 +1,44 @@
+package pool
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReapplyOnCancel(t *testing.T) {
+	t.Parallel()
+
+	var (
+		errApply   = errors.New("apply failed")
+		applied    = 0
+		applyErrs  = []error{errApply, nil}
+		consumeErr error
+	)
+	consume := reapplyOnCancel(
+		func() error {
+			applied++
+			err := applyErrs[0]
+			applyErrs = applyErrs[1:]
+			return err
+		},
+		func() error { return consumeErr },
+	)
+
+	// the topology is not applied upon the initial start
+	consumeErr = ErrDeliveryClosed
+	assert.ErrorIs(t, consume(), ErrDeliveryClosed)
+	assert.Equal(t, 0, applied)
+
+	// canceled consumers re-apply their topology until it succeeded
+	consumeErr = errors.New("connection lost")
+	assert.ErrorIs(t, consume(), errApply)
+	assert.Equal(t, 1, applied)
+	assert.EqualError(t, consume(), "connection lost")
+	assert.Equal(t, 2, applied)
+
+	// other errors do not re-apply the topology
+	assert.EqualError(t, consume(), "connection lost")
+	assert.Equal(t, 2, applied)
+}
@@ -0,0 +1,41 @@
+package pool
+
+import (
+	"time"
+)
+
+// watchHandler starts the handler watchdog which reports the handler as slow in case it does not return
+// within the configured threshold. The returned function must be called as soon as the handler returned.
+func (s *Subscriber) watchHandler(consumer, queue string) (done func()) {
+	return watchdog(s.slowHandlerThreshold, func(elapsed time.Duration) {
+		s.log.WithFields(withConsumerIfSet(consumer, map[string]any{
+			"subscriber": s.pool.Name(),
+			"queue":      queue,
+			"elapsed":    elapsed.String(),
+		})).Warn("handler exceeds the watchdog threshold, risking the consumer acknowledgement timeout")
+
+		if s.slowHandlerCB != nil {
+			// called from the timer goroutine, which must not crash the process
+			_ = callSafe(s.log, "slow handler callback", ErrCallbackPanic, func() error {
+				s.slowHandlerCB(consumer, queue, elapsed)
+				return nil
+			})
+		}
+	})
+}
+
+// watchdog calls slow once the threshold elapsed, unless the returned function is called before.
+// A threshold of 0 or less disables the watchdog.
+func watchdog(threshold time.Duration, slow func(elapsed time.Duration)) (stop func()) {
+	if threshold <= 0 {
+		return func() {}
+	}
+
+	start := time.Now()
+	timer := time.AfterFunc(threshold, func() {
+		slow(time.Since(start))
+	})
+	return func() {
+		timer.Stop()
+	}
+}
@@ -0,0 +1,42 @@
+package pool
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchdog(t *testing.T) {
+	t.Parallel()
+
+	var (
+		threshold = 20 * time.Millisecond
+		slow      atomic.Int64
+		elapsed   atomic.Int64
+	)
+	report := func(d time.Duration) {
+		slow.Add(1)
+		elapsed.Store(int64(d))
+	}
+
+	// slow handler
+	stop := watchdog(threshold, report)
+	time.Sleep(5 * threshold)
+	stop()
+	assert.Equal(t, int64(1), slow.Load(), "slow handler must be reported exactly once")
+	assert.GreaterOrEqual(t, time.Duration(elapsed.Load()), threshold)
+
+	// fast handler
+	stop = watchdog(threshold, report)
+	stop()
+	time.Sleep(5 * threshold)
+	assert.Equal(t, int64(1), slow.Load(), "fast handler must not be reported")
+
+	// disabled
+	stop = watchdog(0, report)
+	time.Sleep(2 * threshold)
+	stop()
+	assert.Equal(t, int64(1), slow.Load(), "disabled watchdog must not report")
+}
@@ -36,3 +36,19 @@ Use a type assertion when reading values from a table for type conversion.
 RabbitMQ expects int32 for integer values.
 */
 type Table = amqp091.Table
+
+// copyTable returns a deep copy of the passed table, nested tables are copied as well.
+// A nil table is returned as nil.
+func copyTable(t Table) Table {
+	if t == nil {
+		return nil
+	}
+	c := make(Table, len(t)+1)
+	for k, v := range t {
+		if nested, ok := v.(Table); ok {
+			v = copyTable(nested)
+		}
+		c[k] = v
+	}
+	return c
+}
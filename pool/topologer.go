@@ -2,6 +2,8 @@ package pool
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"github.com/jxsl13/amqpx/logging"
 )
@@ -71,6 +73,14 @@ func (t *Topologer) ExchangeDeclare(ctx context.Context, name string, kind Excha
 	return s.ExchangeDeclare(ctx, name, kind, option...)
 }
 
+// DelayedExchangeDeclare declares a delayed message exchange which routes messages like an exchange of the passed kind
+// once their delay elapsed (see PublishingWithDelay).
+// The exchange kind is ExchangeKindDelayedMessage, which requires the rabbitmq-delayed-message-exchange plugin.
+// The passed options are handled like the options of ExchangeDeclare.
+func (t *Topologer) DelayedExchangeDeclare(ctx context.Context, name string, kind ExchangeKind, option ...ExchangeDeclareOptions) error {
+	return t.ExchangeDeclare(ctx, name, ExchangeKindDelayedMessage, delayedExchangeDeclareOptions(kind, option...))
+}
+
 // ExchangeDeclarePassive is functionally and parametrically equivalent to
 // ExchangeDeclare, except that it sets the "passive" attribute to true. A passive
 // exchange is assumed by RabbitMQ to already exist, and attempting to connect to a
@@ -87,6 +97,87 @@ func (t *Topologer) ExchangeDeclarePassive(ctx context.Context, name string, kin
 	return s.ExchangeDeclarePassive(ctx, name, kind, option...)
 }
 
+// Verify checks that all exchanges and queues of the topology exist with the expected settings without creating them,
+// e.g. in case the topology is owned by another team. Every resource is declared passively in order to check its existence
+// and then declared with its expected settings, which does not modify existing resources with equivalent settings.
+// This requires the configure permission on all resources of the topology. Only the existence of reserved exchanges,
+// whose names start with "amq.", is verified. Bindings are not verified, as they cannot be declared passively.
+//
+// In case any resource is missing or incompatible, a *TopologyError is returned that lists every such resource.
+// The broker closes the channel of the session upon every rejected declaration, which is reset before the next check.
+// Any other error, e.g. a connection failure, aborts the verification and is returned as is.
+func (t *Topologer) Verify(ctx context.Context, topology Topology) error {
+	s, err := t.getSession(ctx)
+	if err != nil {
+		return err
+	}
+
+	mismatches, err := verifyTopology(ctx, s, topology)
+	// the session was reset after every mismatch, only other errors flag it
+	t.pool.ReturnSession(s, err)
+	if err != nil {
+		return err
+	}
+
+	if len(mismatches) > 0 {
+		return &TopologyError{Mismatches: mismatches}
+	}
+	return nil
+}
+
+// verifyTopology returns the resources of the topology that are missing or incompatible.
+func verifyTopology(ctx context.Context, s *Session, topology Topology) (mismatches []TopologyMismatch, err error) {
+	check := func(resource, name string, declare func() error) error {
+		err := declare()
+		if err == nil {
+			return nil
+		}
+		m, ok := newTopologyMismatch(resource, name, err)
+		if !ok {
+			return fmt.Errorf("failed to verify %s %s: %w", resource, name, err)
+		}
+		mismatches = append(mismatches, m)
+		return s.Reset(ctx)
+	}
+
+	for _, e := range topology.Exchanges {
+		var options []ExchangeDeclareOptions
+		if e.Options != nil {
+			options = append(options, *e.Options)
+		}
+		err = check("exchange", e.Name, func() error {
+			err := s.ExchangeDeclarePassive(ctx, e.Name, e.Kind, options...)
+			if err != nil || strings.HasPrefix(e.Name, "amq.") {
+				// reserved exchanges cannot be declared
+				return err
+			}
+			return s.ExchangeDeclare(ctx, e.Name, e.Kind, options...)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, q := range topology.Queues {
+		var options []QueueDeclareOptions
+		if q.Options != nil {
+			options = append(options, *q.Options)
+		}
+		err = check("queue", q.Name, func() error {
+			_, err := s.QueueDeclarePassive(ctx, q.Name, options...)
+			if err != nil {
+				return err
+			}
+			_, err = s.QueueDeclare(ctx, q.Name, options...)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return mismatches, nil
+}
+
 // ExchangeDelete removes the named exchange from the server. When an exchange is
 // deleted all queue bindings on the exchange are also deleted.  If this exchange
 // does not exist, the channel will be closed with an error.
@@ -0,0 +1,87 @@
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// Topology describes the exchanges and queues a service depends on, see Topologer.Verify.
+type Topology struct {
+	Exchanges []TopologyExchange
+	Queues    []TopologyQueue
+}
+
+// TopologyExchange describes an exchange of a Topology.
+type TopologyExchange struct {
+	Name string
+	Kind ExchangeKind
+	// Options are the expected settings of the exchange.
+	// nil expects the defaults of ExchangeDeclare.
+	Options *ExchangeDeclareOptions
+}
+
+// TopologyQueue describes a queue of a Topology.
+type TopologyQueue struct {
+	Name string
+	// Options are the expected settings of the queue.
+	// nil expects the defaults of QueueDeclare.
+	Options *QueueDeclareOptions
+}
+
+// TopologyMismatch is a resource of a Topology that does not exist or whose settings differ from the expected ones.
+type TopologyMismatch struct {
+	// Resource is either "exchange" or "queue".
+	Resource string
+	Name     string
+	// Missing is true in case the resource does not exist and false in case its settings differ.
+	Missing bool
+	// Err is the error of the broker.
+	Err error
+}
+
+// TopologyError is returned by Topologer.Verify and lists every resource of the topology
+// that is missing or incompatible with the expected settings.
+type TopologyError struct {
+	Mismatches []TopologyMismatch
+}
+
+func (e *TopologyError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "topology verification failed for %d resources", len(e.Mismatches))
+	for _, m := range e.Mismatches {
+		reason := "incompatible"
+		if m.Missing {
+			reason = "missing"
+		}
+		fmt.Fprintf(&sb, "; %s %q: %s: %v", m.Resource, m.Name, reason, m.Err)
+	}
+	return sb.String()
+}
+
+// Unwrap returns the errors of all mismatched resources.
+func (e *TopologyError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Mismatches))
+	for _, m := range e.Mismatches {
+		errs = append(errs, m.Err)
+	}
+	return errs
+}
+
+// newTopologyMismatch returns the mismatch of a resource in case the broker rejected its declaration.
+// Any other error, e.g. a connection failure, is not a mismatch and returns false.
+func newTopologyMismatch(resource, name string, err error) (TopologyMismatch, bool) {
+	ae := &amqp091.Error{}
+	if !errors.As(err, &ae) || !ae.Recover {
+		// only soft errors that close the channel are caused by the declaration
+		return TopologyMismatch{}, false
+	}
+	return TopologyMismatch{
+		Resource: resource,
+		Name:     name,
+		Missing:  ae.Code == amqp091.NotFound,
+		Err:      err,
+	}, true
+}
@@ -0,0 +1,39 @@
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopologyError(t *testing.T) {
+	t.Parallel()
+
+	notFound := &amqp091.Error{Code: amqp091.NotFound, Reason: "NOT_FOUND - no queue 'orders'", Recover: true}
+	precondition := &amqp091.Error{Code: amqp091.PreconditionFailed, Reason: "PRECONDITION_FAILED - inequivalent arg 'durable'", Recover: true}
+
+	missing, ok := newTopologyMismatch("queue", "orders", fmt.Errorf("queue %w: %w", ErrNotFound, notFound))
+	require.True(t, ok)
+	assert.True(t, missing.Missing)
+
+	incompatible, ok := newTopologyMismatch("exchange", "events", precondition)
+	require.True(t, ok)
+	assert.False(t, incompatible.Missing)
+
+	// errors that are not caused by the declaration abort the verification
+	_, ok = newTopologyMismatch("queue", "orders", &amqp091.Error{Code: amqp091.ConnectionForced, Reason: "CONNECTION_FORCED"})
+	assert.False(t, ok)
+	_, ok = newTopologyMismatch("queue", "orders", errors.New("connection reset by peer"))
+	assert.False(t, ok)
+
+	err := error(&TopologyError{Mismatches: []TopologyMismatch{missing, incompatible}})
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, err, precondition)
+	assert.Contains(t, err.Error(), "failed for 2 resources")
+	assert.Contains(t, err.Error(), `queue "orders": missing`)
+	assert.Contains(t, err.Error(), `exchange "events": incompatible`)
+}
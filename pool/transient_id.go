@@ -0,0 +1,50 @@
+package pool
+
+// TransientIDStrategy defines how the ids of transient connections are generated.
+// The id is part of the name of a transient connection, e.g. "name-transient-connection-3".
+type TransientIDStrategy int
+
+const (
+	// TransientIDSequential assigns an ever increasing id to every transient connection (default).
+	TransientIDSequential TransientIDStrategy = iota
+	// TransientIDRecycle reuses the smallest id of the transient connections that were closed,
+	// which keeps the ids (and connection names) bounded by the maximum number of concurrent transient connections.
+	TransientIDRecycle
+)
+
+// transientIDs keeps track of the ids of the active transient connections.
+// not threadsafe, must be guarded by the lock of the connection pool.
+type transientIDs struct {
+	strategy TransientIDStrategy
+	last     int64
+	// ids of closed transient connections that may be reused
+	free   []int64
+	active int
+}
+
+// acquire returns the id of a new transient connection.
+func (t *transientIDs) acquire() int64 {
+	t.active++
+	if t.strategy == TransientIDRecycle && len(t.free) > 0 {
+		idx := 0
+		for i, id := range t.free {
+			if id < t.free[idx] {
+				idx = i
+			}
+		}
+		id := t.free[idx]
+		t.free[idx] = t.free[len(t.free)-1]
+		t.free = t.free[:len(t.free)-1]
+		return id
+	}
+	t.last++
+	return t.last
+}
+
+// release must be called exactly once for every acquired id when its transient connection is closed.
+func (t *transientIDs) release(id int64) {
+	t.active--
+	if t.strategy == TransientIDRecycle {
+		t.free = append(t.free, id)
+	}
+}
@@ -0,0 +1,74 @@
+package pool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransientIDs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		strategy TransientIDStrategy
+		// id of the transient connection that is acquired after 2 and 1 were released
+		want []int64
+	}{
+		{strategy: TransientIDSequential, want: []int64{4, 5, 6}},
+		{strategy: TransientIDRecycle, want: []int64{1, 2, 4}},
+	}
+
+	for _, test := range tests {
+		ids := transientIDs{strategy: test.strategy}
+		assert.Equal(t, int64(1), ids.acquire())
+		assert.Equal(t, int64(2), ids.acquire())
+		assert.Equal(t, int64(3), ids.acquire())
+		assert.Equal(t, 3, ids.active)
+
+		ids.release(2)
+		ids.release(1)
+		assert.Equal(t, 1, ids.active)
+
+		got := []int64{ids.acquire(), ids.acquire(), ids.acquire()}
+		assert.Equal(t, test.want, got, test.strategy)
+		assert.Equal(t, 4, ids.active)
+	}
+}
+
+func TestConnectionPoolRecyclesTransientIDs(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.TODO()
+	cp := &ConnectionPool{
+		transients: transientIDs{strategy: TransientIDRecycle},
+	}
+
+	newTransient := func() (*Connection, int64) {
+		id := cp.acquireTransientID()
+		conn, err := newConnection(ctx, testConnectURL, "transient",
+			connectionWithCloseCallback(func() { cp.releaseTransientID(id) }),
+		)
+		require.NoError(t, err)
+		return conn, id
+	}
+
+	c1, id1 := newTransient()
+	c2, id2 := newTransient()
+	assert.Equal(t, 2, cp.StatTransientActive())
+
+	// closing a connection multiple times releases its id only once
+	require.NoError(t, c1.Close())
+	require.NoError(t, c1.Close())
+	assert.Equal(t, 1, cp.StatTransientActive())
+
+	c3, id3 := newTransient()
+	assert.Equal(t, id1, id3)
+	assert.NotEqual(t, id2, id3)
+	assert.Equal(t, 2, cp.StatTransientActive())
+
+	require.NoError(t, c2.Close())
+	require.NoError(t, c3.Close())
+	assert.Equal(t, 0, cp.StatTransientActive())
+}
@@ -0,0 +1,175 @@
+package pool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+
+	"github.com/jxsl13/amqpx/logging"
+)
+
+// Decoder decodes the body of a delivery into a value of type T.
+type Decoder[T any] func(msg Delivery) (T, error)
+
+// TypedHandlerFunc handles a decoded message.
+type TypedHandlerFunc[T any] func(ctx context.Context, v T, msg Delivery) error
+
+// DecodeFailureAction defines what happens to a message that could not be decoded by a TypedConsumer.
+type DecodeFailureAction int
+
+const (
+	// DecodeFailureDeadLetter rejects the message without requeuing it.
+	// The broker routes the message to the dead letter exchange of the queue or drops it in case
+	// the queue has no dead letter exchange.
+	DecodeFailureDeadLetter DecodeFailureAction = iota
+	// DecodeFailureRequeue puts the message back into its queue.
+	// Be aware that a malformed message most likely fails to be decoded again, which leads to an infinite redelivery loop.
+	DecodeFailureRequeue
+	// DecodeFailureDrop acknowledges and thereby drops the message.
+	DecodeFailureDrop
+)
+
+func (a DecodeFailureAction) String() string {
+	switch a {
+	case DecodeFailureDeadLetter:
+		return "dead letter"
+	case DecodeFailureRequeue:
+		return "requeue"
+	case DecodeFailureDrop:
+		return "drop"
+	default:
+		return "unknown"
+	}
+}
+
+type typedConsumerOption struct {
+	DecodeFailureAction DecodeFailureAction
+	Logger              logging.Logger
+}
+
+type TypedConsumerOption func(*typedConsumerOption)
+
+// TypedConsumerWithDecodeFailureAction defines what happens to messages that cannot be decoded.
+// By default such messages are dead lettered.
+func TypedConsumerWithDecodeFailureAction(action DecodeFailureAction) TypedConsumerOption {
+	return func(o *typedConsumerOption) {
+		o.DecodeFailureAction = action
+	}
+}
+
+// TypedConsumerWithLogger allows to set a logger which logs messages that cannot be decoded.
+// By default the logger of SetDefaultLogger is used.
+func TypedConsumerWithLogger(logger logging.Logger) TypedConsumerOption {
+	return func(o *typedConsumerOption) {
+		o.Logger = logger
+	}
+}
+
+// TypedConsumer decodes the body of every delivery before passing the decoded value to its handler,
+// which removes repetitive unmarshaling from handlers and handles malformed messages uniformly.
+// Pass TypedConsumer.Handle to Subscriber.RegisterHandlerFunc in order to consume messages.
+type TypedConsumer[T any] struct {
+	decode Decoder[T]
+	handle TypedHandlerFunc[T]
+
+	decodeFailureAction DecodeFailureAction
+
+	log logging.Logger
+}
+
+// NewTypedConsumer creates a consumer which decodes messages with the passed decoder before they are handled.
+func NewTypedConsumer[T any](decode Decoder[T], handle TypedHandlerFunc[T], options ...TypedConsumerOption) *TypedConsumer[T] {
+	if decode == nil {
+		panic("nil decoder passed")
+	}
+	if handle == nil {
+		panic("nil handler passed")
+	}
+
+	option := typedConsumerOption{
+		DecodeFailureAction: DecodeFailureDeadLetter,
+		Logger:              defaultLogger(),
+	}
+
+	for _, o := range options {
+		o(&option)
+	}
+
+	return &TypedConsumer[T]{
+		decode:              decode,
+		handle:              handle,
+		decodeFailureAction: option.DecodeFailureAction,
+		log:                 option.Logger,
+	}
+}
+
+// Handle decodes the delivery and passes the decoded value to the handler.
+// Decoding errors wrap ErrDecode. Depending on the decode failure action, they are returned as rejections (ErrReject),
+// returned as is, which lets the NackPolicy of the consumer decide (requeue by default), or dropped.
+func (tc *TypedConsumer[T]) Handle(ctx context.Context, msg Delivery) error {
+	v, err := tc.decode(msg)
+	if err != nil {
+		return tc.decodeFailed(msg, err)
+	}
+	return tc.handle(ctx, v, msg)
+}
+
+func (tc *TypedConsumer[T]) decodeFailed(msg Delivery, err error) error {
+	tc.log.WithFields(map[string]any{
+		"consumer":    msg.ConsumerTag,
+		"exchange":    msg.Exchange,
+		"routingKey":  msg.RoutingKey,
+		"contentType": msg.ContentType,
+		"action":      tc.decodeFailureAction.String(),
+		"error":       err.Error(),
+	}).Warn("failed to decode message")
+
+	switch tc.decodeFailureAction {
+	case DecodeFailureDrop:
+		return nil
+	case DecodeFailureRequeue:
+		return fmt.Errorf("%w: %w", ErrDecode, err)
+	default:
+		return fmt.Errorf("%w: %w: %w", ErrReject, ErrDecode, err)
+	}
+}
+
+// RouteByType returns a handler which passes every delivery to the handler that is registered for its Type,
+// e.g. the Handle method of a TypedConsumer per message type.
+// Deliveries with an unknown type are passed to the fallback handler. In case the fallback handler is nil,
+// such deliveries are rejected (ErrReject) and thereby dead lettered.
+func RouteByType(handlers map[string]HandlerFunc, fallback HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, msg Delivery) error {
+		if handle, ok := handlers[msg.Type]; ok {
+			return handle(ctx, msg)
+		}
+		if fallback != nil {
+			return fallback(ctx, msg)
+		}
+		return fmt.Errorf("%w: no handler for message type %q", ErrReject, msg.Type)
+	}
+}
+
+// JSONDecoder returns a decoder which unmarshals JSON message bodies into values of type T.
+// Messages with a content type other than application/json are not decoded. Messages without content type are decoded.
+func JSONDecoder[T any]() Decoder[T] {
+	return func(msg Delivery) (T, error) {
+		var v T
+		if msg.ContentType != "" {
+			mediaType, _, err := mime.ParseMediaType(msg.ContentType)
+			if err != nil {
+				return v, fmt.Errorf("invalid content type %q: %w", msg.ContentType, err)
+			}
+			if mediaType != "application/json" {
+				return v, fmt.Errorf("unexpected content type %q: expected application/json", msg.ContentType)
+			}
+		}
+
+		err := json.Unmarshal(msg.Body, &v)
+		if err != nil {
+			return v, err
+		}
+		return v, nil
+	}
+}
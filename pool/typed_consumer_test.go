@@ -0,0 +1,98 @@
+package pool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type typedEvent struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestTypedConsumer(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx     = context.Background()
+		handled []typedEvent
+	)
+	handle := func(ctx context.Context, v typedEvent, msg Delivery) error {
+		handled = append(handled, v)
+		return nil
+	}
+
+	tc := NewTypedConsumer(JSONDecoder[typedEvent](), handle)
+
+	// successful decode
+	err := tc.Handle(ctx, Delivery{ContentType: "application/json; charset=utf-8", Body: []byte(`{"id":1,"name":"created"}`)})
+	assert.NoError(t, err)
+	err = tc.Handle(ctx, Delivery{Body: []byte(`{"id":2,"name":"deleted"}`)})
+	assert.NoError(t, err)
+	assert.Equal(t, []typedEvent{{ID: 1, Name: "created"}, {ID: 2, Name: "deleted"}}, handled)
+
+	malformed := []Delivery{
+		{ContentType: "application/json", Body: []byte(`{"id":`)},
+		{ContentType: "text/plain", Body: []byte(`{"id":3}`)},
+	}
+
+	// malformed messages are dead lettered by default
+	for _, msg := range malformed {
+		err = tc.Handle(ctx, msg)
+		assert.ErrorIs(t, err, ErrDecode)
+		assert.Equal(t, NackActionDeadLetter, nackAction(NackPolicyAlways(NackActionRequeue), err))
+	}
+
+	tc = NewTypedConsumer(JSONDecoder[typedEvent](), handle, TypedConsumerWithDecodeFailureAction(DecodeFailureRequeue))
+	for _, msg := range malformed {
+		err = tc.Handle(ctx, msg)
+		assert.ErrorIs(t, err, ErrDecode)
+		assert.Equal(t, NackActionRequeue, nackAction(nil, err))
+	}
+
+	tc = NewTypedConsumer(JSONDecoder[typedEvent](), handle, TypedConsumerWithDecodeFailureAction(DecodeFailureDrop))
+	for _, msg := range malformed {
+		// acked
+		assert.NoError(t, tc.Handle(ctx, msg))
+	}
+
+	// the handler is never called for malformed messages
+	assert.Len(t, handled, 2)
+}
+
+func TestRouteByType(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx     = context.Background()
+		created []typedEvent
+		other   []string
+	)
+
+	handle := RouteByType(map[string]HandlerFunc{
+		"event.created": NewTypedConsumer(JSONDecoder[typedEvent](), func(ctx context.Context, v typedEvent, msg Delivery) error {
+			created = append(created, v)
+			return nil
+		}).Handle,
+	}, nil)
+
+	err := handle(ctx, Delivery{Type: "event.created", Body: []byte(`{"id":1,"name":"created"}`)})
+	assert.NoError(t, err)
+	assert.Equal(t, []typedEvent{{ID: 1, Name: "created"}}, created)
+
+	// unknown types are rejected without fallback
+	err = handle(ctx, Delivery{Type: "event.unknown", Body: []byte(`{}`)})
+	assert.ErrorIs(t, err, ErrReject)
+	err = handle(ctx, Delivery{Body: []byte(`{}`)})
+	assert.ErrorIs(t, err, ErrReject)
+
+	handle = RouteByType(nil, func(ctx context.Context, msg Delivery) error {
+		other = append(other, msg.Type)
+		return nil
+	})
+	err = handle(ctx, Delivery{Type: "event.unknown"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"event.unknown"}, other)
+}
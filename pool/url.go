@@ -0,0 +1,105 @@
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// urlQueryParams are the query parameters of the connect url that are known to the amqp091 client,
+// see https://www.rabbitmq.com/uri-query-parameters.html
+var urlQueryParams = map[string]struct{}{
+	"certfile":               {},
+	"keyfile":                {},
+	"cacertfile":             {},
+	"server_name_indication": {},
+	"auth_mechanism":         {},
+	"heartbeat":              {},
+	"connection_timeout":     {},
+	"channel_max":            {},
+}
+
+// parseURL parses and validates the connect url that is used by the connection pool as well as by connections.
+// Only the schemes amqp and amqps are supported. In case tls is enabled, the scheme is changed to amqps.
+// The vhost is the url encoded path of the url, e.g. %2F for the vhost "/".
+// Query parameters are passed on to the amqp091 client, unknown parameters are rejected.
+// Returned errors never contain the passed url in order not to leak any credentials into logs.
+func parseURL(connectUrl string, tlsEnabled bool) (*url.URL, error) {
+	u, err := url.Parse(connectUrl)
+	if err != nil {
+		ue := &url.Error{}
+		if errors.As(err, &ue) {
+			// url.Error contains the whole url including the password
+			err = ue.Err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConnectURL, err)
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	switch scheme {
+	case "amqp", "amqps":
+	default:
+		return nil, fmt.Errorf("%w: unsupported scheme %q", ErrInvalidConnectURL, u.Scheme)
+	}
+
+	if u.Opaque != "" || u.Hostname() == "" {
+		return nil, fmt.Errorf("%w: missing host", ErrInvalidConnectURL)
+	}
+
+	if p := u.Port(); p != "" {
+		port, err := strconv.Atoi(p)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("%w: invalid port %q", ErrInvalidConnectURL, p)
+		}
+	}
+
+	// the vhost is the first and only path segment, e.g. amqp://localhost/vhost
+	// a vhost containing slashes must be url encoded.
+	if u.Path != "" && u.Path != "/" && strings.Contains(strings.TrimPrefix(u.EscapedPath(), "/"), "/") {
+		return nil, fmt.Errorf("%w: vhost must be a single url encoded path segment", ErrInvalidConnectURL)
+	}
+
+	if u.Fragment != "" {
+		return nil, fmt.Errorf("%w: fragments are not supported", ErrInvalidConnectURL)
+	}
+
+	query, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid query", ErrInvalidConnectURL)
+	}
+	for param := range query {
+		if _, ok := urlQueryParams[param]; !ok {
+			return nil, fmt.Errorf("%w: unsupported query parameter %q", ErrInvalidConnectURL, param)
+		}
+	}
+
+	u.Scheme = scheme
+	if tlsEnabled {
+		u.Scheme = "amqps"
+	}
+	return u, nil
+}
+
+// setVHost returns the connect url with the passed vhost.
+func setVHost(u url.URL, vhost string) string {
+	u.Path = "/" + vhost
+	// vhosts may contain slashes, e.g. the default vhost "/"
+	u.RawPath = "/" + url.PathEscape(vhost)
+	return u.String()
+}
+
+// withVHost replaces the vhost of all passed connect urls.
+func withVHost(vhost string, connectURLs ...string) ([]string, error) {
+	result := make([]string, 0, len(connectURLs))
+	for _, connectURL := range connectURLs {
+		u, err := url.Parse(connectURL)
+		if err != nil {
+			// never leak credentials
+			return nil, fmt.Errorf("%w: failed to set vhost %q", ErrInvalidConnectURL, vhost)
+		}
+		result = append(result, setVHost(*u, vhost))
+	}
+	return result, nil
+}
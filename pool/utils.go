@@ -2,6 +2,8 @@ package pool
 
 import (
 	"context"
+	"crypto/rand"
+	"fmt"
 	"time"
 )
 
@@ -34,6 +36,28 @@ func resetTimer(timer *time.Timer, duration time.Duration, drained *bool) {
 	*drained = false
 }
 
+// newMessageID returns a random (version 4) UUID.
+func newMessageID() string {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		// crypto/rand does not fail on supported platforms
+		panic(fmt.Sprintf("failed to generate message id: %v", err))
+	}
+	u[6] = (u[6] & 0x0f) | 0x40 // version 4
+	u[8] = (u[8] & 0x3f) | 0x80 // variant 10
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// closeOnCancel binds the lifecycle of a resource to the parent context by calling closeFn as soon as the parent is done.
+// The child context must be derived from the parent and must be canceled when the resource is closed,
+// which stops waiting without calling closeFn again.
+func closeOnCancel(parent, child context.Context, closeFn func() error) {
+	<-child.Done()
+	if parent.Err() != nil {
+		_ = closeFn()
+	}
+}
+
 func toCancelFunc(err error, ccf context.CancelCauseFunc) context.CancelFunc {
 	return func() {
 		ccf(err)
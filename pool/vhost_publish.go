@@ -0,0 +1,114 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// VHostPublishing is a message that is published to an exchange of a specific vhost.
+type VHostPublishing struct {
+	Exchange   string
+	RoutingKey string
+	Publishing
+}
+
+// VHostPublishError is returned by PublishToVHosts in case the message of at least one vhost
+// could not be published. It contains the result of every vhost, which allows to compensate
+// the messages that were published successfully.
+type VHostPublishError struct {
+	// Failed contains the error of every vhost whose message was not confirmed by the broker.
+	// Such messages may or may not have been routed.
+	Failed map[string]error
+	// Published contains the sorted vhosts whose message was confirmed by the broker.
+	Published []string
+}
+
+func (e *VHostPublishError) Error() string {
+	vhosts := make([]string, 0, len(e.Failed))
+	for vhost := range e.Failed {
+		vhosts = append(vhosts, vhost)
+	}
+	sort.Strings(vhosts)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "failed to publish to %d of %d vhosts", len(e.Failed), len(e.Failed)+len(e.Published))
+	for _, vhost := range vhosts {
+		fmt.Fprintf(&sb, "; vhost %q: %v", vhost, e.Failed[vhost])
+	}
+	return sb.String()
+}
+
+// Unwrap returns the errors of all failed vhosts.
+func (e *VHostPublishError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failed))
+	for _, err := range e.Failed {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// PublishToVHosts publishes one message per vhost, e.g. one event per participating service of a saga.
+// Every message is published concurrently via a transient session of its vhost and awaits its publisher confirmation,
+// independent of whether the session pool requires confirms.
+//
+// Publishing is best effort and not atomic, as AMQP does not support transactions across vhosts (or channels).
+// In case any message fails to be published, a *VHostPublishError is returned which reports the result of every vhost,
+// so that the caller can compensate the messages that were published successfully.
+func (sp *SessionPool) PublishToVHosts(ctx context.Context, publishings map[string]VHostPublishing) error {
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		failed    = make(map[string]error)
+		published = make([]string, 0, len(publishings))
+	)
+
+	wg.Add(len(publishings))
+	for vhost, msg := range publishings {
+		go func(vhost string, msg VHostPublishing) {
+			defer wg.Done()
+			err := sp.publishToVHost(ctx, vhost, msg)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed[vhost] = err
+			} else {
+				published = append(published, vhost)
+			}
+		}(vhost, msg)
+	}
+	wg.Wait()
+
+	if len(failed) == 0 {
+		return nil
+	}
+	sort.Strings(published)
+	return &VHostPublishError{
+		Failed:    failed,
+		Published: published,
+	}
+}
+
+func (sp *SessionPool) publishToVHost(ctx context.Context, vhost string, msg VHostPublishing) (err error) {
+	s, err := sp.GetTransientSessionForVHost(ctx, vhost)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		sp.ReturnSession(s, err)
+	}()
+
+	err = s.EnableConfirms()
+	if err != nil {
+		return err
+	}
+
+	tag, err := s.Publish(ctx, msg.Exchange, msg.RoutingKey, msg.Publishing)
+	if err != nil {
+		return err
+	}
+	return s.AwaitConfirm(ctx, tag)
+}
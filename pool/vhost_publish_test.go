@@ -0,0 +1,32 @@
+package pool
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVHostPublishError(t *testing.T) {
+	t.Parallel()
+
+	var (
+		errPayments = errors.New("payments failed")
+		errShipping = errors.New("shipping failed")
+	)
+
+	err := error(&VHostPublishError{
+		Failed: map[string]error{
+			"shipping": errShipping,
+			"payments": errPayments,
+		},
+		Published: []string{"orders"},
+	})
+	assert.EqualError(t, err, `failed to publish to 2 of 3 vhosts; vhost "payments": payments failed; vhost "shipping": shipping failed`)
+	assert.ErrorIs(t, err, errPayments)
+	assert.ErrorIs(t, err, errShipping)
+
+	var vpe *VHostPublishError
+	assert.ErrorAs(t, err, &vpe)
+	assert.Equal(t, []string{"orders"}, vpe.Published)
+}